@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/spf13/cobra"
+	tmClient "github.com/tendermint/tendermint/rpc/client/http"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var BlobCmd = &cobra.Command{
+	Use:   "blob",
+	Short: "Submit and get blobs",
+}
+
+var BlobGetCmd = &cobra.Command{
+	Use:   "get [hash]",
+	Short: "Download a blob, an object or the blobs of a namespace, verified against their block",
+	Long: `Download a blob, or with --namespace the blobs of a namespace in a height range. Every blob is
+verified against its tx hash and the data hash of its block header, then decompressed with its codec. The
+header is read from Tendermint at --td-rpc and verified against the validators of the --genesis file, so
+neither node has to be trusted for the content of a blob. A namespace export can not prove that the node
+listed every blob of the namespace.
+
+With --object the hash is the manifest of an object submitted with blob submit --split. The node reassembles
+the object from its parts, which is verified against the size and sha256 of the manifest as served by the node.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: getBlob,
+}
+
+var BlobSubmitCmd = &cobra.Command{
+	Use:   "submit [file]",
+	Short: "Compress, sign and submit a file as blob, then wait for its inclusion",
+	Long: `Compress a file, sign it with the key of --from or the remote signer of --signer-url and submit it as
+blob once a dry run CheckTx on the node estimated its fee. The command waits until the blob is included and
+prints its hash, height and fee.
+
+Files larger than --chunk-size are rejected unless --split is set, which submits them as several blobs followed
+by a manifest listing the parts in order. Every part after the first links to the hash of the previous part, the
+hash of the manifest identifies the object, see blob get --object.`,
+	Args: cobra.ExactArgs(1),
+	RunE: submitBlob,
+}
+
+func init() {
+	addSigningFlags(BlobSubmitCmd)
+	BlobSubmitCmd.Flags().StringVar(&BlobNamespace, "namespace", "", "Namespace of the blob")
+	BlobSubmitCmd.Flags().StringVar(&BlobCodec, "codec", types.CodecAuto, "Codec to compress with: none, gzip, zstd, snappy or auto")
+	BlobSubmitCmd.Flags().BoolVar(&BlobSplit, "split", false, "Split files larger than --chunk-size into linked blobs and a manifest")
+	BlobSubmitCmd.Flags().IntVar(&BlobChunkSize, "chunk-size", types.DefaultMaxBlobBytes, "Max bytes of a blob before compression")
+	BlobSubmitCmd.Flags().DurationVar(&BlobTimeout, "timeout", types.DefaultWaitCommitTimeout, "How long to wait for the inclusion of every blob")
+	BlobSubmitCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+
+	BlobGetCmd.Flags().StringVarP(&BlobOutput, "output", "o", "", "Output file, or directory with --namespace")
+	BlobGetCmd.Flags().StringVar(&BlobNamespace, "namespace", "", "Export the blobs of a namespace")
+	BlobGetCmd.Flags().Int64Var(&BlobFromHeight, "from", 0, "Lowest height to export")
+	BlobGetCmd.Flags().Int64Var(&BlobToHeight, "to", 0, "Highest height to export, the latest if 0")
+	BlobGetCmd.Flags().BoolVar(&BlobObject, "object", false, "The hash is the manifest of an object")
+	BlobGetCmd.Flags().DurationVar(&BlobTimeout, "timeout", time.Hour, "How long the download of an object may take")
+	BlobGetCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+	BlobGetCmd.Flags().StringVarP(&MintTdRpc, "td-rpc", "r", DefaultMintTdRpc, "Tendermint RPC address of the signed block headers")
+	BlobGetCmd.Flags().StringVar(&BlobGenesis, "genesis", DefaultGenesisPath, "Genesis file of the validators signing the block headers")
+	_ = BlobGetCmd.MarkFlagRequired("output")
+
+	BlobCmd.AddCommand(BlobSubmitCmd, BlobGetCmd)
+}
+
+func submitBlob(cmd *cobra.Command, args []string) error {
+	if len(BlobCodec) == 0 || !types.ValidCodec(BlobCodec) {
+		return fmt.Errorf("unknown codec %q", BlobCodec)
+	}
+	if BlobChunkSize <= 0 {
+		return fmt.Errorf("invalid --chunk-size %d", BlobChunkSize)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return errors.New("empty file")
+	}
+	if len(data) > BlobChunkSize && !BlobSplit {
+		return fmt.Errorf("file of %d bytes is larger than %d bytes, use --split to submit it as several blobs", len(data), BlobChunkSize)
+	}
+
+	signer, err := newSigner()
+	if err != nil {
+		return err
+	}
+
+	c := newClient(MintNodeRpc)
+	ctx := context.Background()
+
+	nonce, err := c.Nonce(ctx, signer.Address())
+	if err != nil {
+		return err
+	}
+
+	parts := (len(data) + BlobChunkSize - 1) / BlobChunkSize
+	total := new(big.Int)
+	hashes := make([]string, 0, parts)
+	prev := ""
+	for i := 0; i < parts; i++ {
+		chunk := data[i*BlobChunkSize : min((i+1)*BlobChunkSize, len(data))]
+
+		// every part is signed with the next nonce, so it is only checked once the previous part is included
+		tx, err := client.NewBlobTxWith(ctx, signer, nonce+uint64(i), chunk, BlobNamespace, BlobCodec, prev)
+		if err != nil {
+			return err
+		}
+
+		label := fmt.Sprintf("part %d/%d", i+1, parts)
+		hash, err := submitTx(ctx, c, tx, label, total)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+		prev = hash
+	}
+
+	if parts == 1 {
+		logger.Info("blob submitted", "hash", prev, "bytes", len(data), "fee", total.String())
+		return nil
+	}
+
+	// the manifest makes the parts one object, the chain checks that every part is included
+	digest := sha256.Sum256(data)
+	tx, err := client.NewManifestTxWith(ctx, signer, nonce+uint64(parts), BlobNamespace, hashes, int64(len(data)), digest[:])
+	if err != nil {
+		return err
+	}
+	id, err := submitTx(ctx, c, tx, "manifest", total)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("object submitted", "id", id, "parts", parts, "bytes", len(data), "fee", total.String())
+	return nil
+}
+
+// submitTx broadcasts a tx once a dry run estimated its fee, waits for its inclusion and adds the fee to total.
+func submitTx(ctx context.Context, c *client.Client, tx *types.Tx, label string, total *big.Int) (string, error) {
+	estimate, err := c.DryRunTx(ctx, tx)
+	if err != nil && estimate.Code != 0 {
+		return "", fmt.Errorf("%s rejected with a fee of %s wei: %w", label, weiString(estimate.Fee), err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", label, err)
+	}
+	logger.Info("submit "+tx.Ty.String(), "tx", label, "fee", weiString(estimate.Fee))
+
+	result, err := c.BroadcastTx(ctx, tx, BlobTimeout)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", label, err)
+	}
+	receipt := result.Receipt
+	if receipt == nil || receipt.Status != types.TxStatusIncluded {
+		return "", fmt.Errorf("%s %s not included: %s", label, result.Hash, receiptLog(receipt))
+	}
+
+	if fee, err := hexutil.DecodeBig(receipt.Fee); err == nil {
+		total.Add(total, fee)
+	}
+	logger.Info(tx.Ty.String()+" included", "tx", label, "hash", result.Hash, "height", receipt.Height, "fee", weiString(receipt.Fee))
+	return result.Hash, nil
+}
+
+// weiString formats a hex encoded amount in wei as decimal.
+func weiString(amount string) string {
+	if value, err := hexutil.DecodeBig(amount); err == nil {
+		return value.String()
+	}
+	return "0"
+}
+
+func receiptLog(receipt *client.Receipt) string {
+	if receipt == nil {
+		return "no receipt"
+	}
+	return fmt.Sprintf("%s %s", receipt.Status, receipt.Log)
+}
+
+func getBlob(cmd *cobra.Command, args []string) error {
+	c := newClient(MintNodeRpc)
+	ctx := context.Background()
+
+	if len(args) == 1 && BlobObject {
+		ctx, cancel := context.WithTimeout(ctx, BlobTimeout)
+		defer cancel()
+		if err := writeVerified(BlobOutput, func(w io.Writer) error { return c.Object(ctx, args[0], w) }); err != nil {
+			return err
+		}
+		logger.Info("object verified", "id", args[0], "file", BlobOutput)
+		return nil
+	}
+
+	trust, err := newTrust()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		height, err := downloadBlob(ctx, c, trust, args[0], BlobOutput)
+		if err != nil {
+			return err
+		}
+		logger.Info("blob verified", "hash", args[0], "height", height, "file", BlobOutput)
+		return nil
+	}
+
+	if !cmd.Flags().Changed("namespace") {
+		return errors.New("a blob hash or --namespace is required")
+	}
+	if BlobToHeight > 0 && BlobToHeight < BlobFromHeight {
+		return fmt.Errorf("--to %d is below --from %d", BlobToHeight, BlobFromHeight)
+	}
+	if err := os.MkdirAll(BlobOutput, 0755); err != nil {
+		return err
+	}
+
+	count := 0
+	query := client.BlobsQuery{FromHeight: BlobFromHeight, ToHeight: BlobToHeight, Page: 1, Limit: types.MaxPageLimit}
+	for {
+		result, err := c.Blobs(ctx, BlobNamespace, query)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range result.Txs {
+			// failed blobs are in the block but not part of the namespace history
+			if record.Code != 0 {
+				logger.Info("skip failed blob", "hash", record.Hash, "height", record.Height, "log", record.Log)
+				continue
+			}
+
+			// files sort in chain order
+			file := filepath.Join(BlobOutput, fmt.Sprintf("%010d-%05d-%s.bin", record.Height, record.Index, record.Hash))
+			if _, err := downloadBlob(ctx, c, trust, record.Hash, file); err != nil {
+				return err
+			}
+			count++
+		}
+
+		if query.Page*query.Limit >= result.Total {
+			break
+		}
+		query.Page++
+	}
+
+	logger.Info("blobs exported", "namespace", BlobNamespace, "count", count, "dir", BlobOutput)
+	return nil
+}
+
+// newTrust verifies block headers from --td-rpc against the validators of --genesis.
+func newTrust() (*client.Trust, error) {
+	genesis, err := tmTypes.GenesisDocFromFile(BlobGenesis)
+	if err != nil {
+		return nil, fmt.Errorf("read --genesis: %w", err)
+	}
+	tdClient, err := tmClient.New(MintTdRpc, "/websocket")
+	if err != nil {
+		return nil, err
+	}
+	return client.NewTrust(genesis, tdClient)
+}
+
+// downloadBlob writes the verified data of a blob to file, which is only created once the blob is verified.
+func downloadBlob(ctx context.Context, c *client.Client, trust *client.Trust, hash, file string) (int64, error) {
+	blob, err := c.ProvenBlob(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	err = writeVerified(file, func(w io.Writer) error {
+		if err := trust.VerifyBlob(ctx, blob, hash, w); err != nil {
+			return fmt.Errorf("blob %s: %w", hash, err)
+		}
+		return nil
+	})
+	return blob.Height, err
+}
+
+// writeVerified writes to a temporary file renamed to file once write verified the data.
+func writeVerified(file string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -34,7 +35,66 @@ var (
 	MintPrivateKeyPath        string
 	DefaultMintPrivateKeyPath = ""
 
+	MintAmount string
+	MintTo     string
+	MintCount  int
+	MintWait   bool
+
 	QueryAddress string
+	QueryOutput  string
+	QueryHeight  int64
+
+	RemoteSigner     bool
+	SignerDir        string
+	DefaultSignerDir = os.Getenv("HOME") + "/.side-chain/0-signer"
+
+	KeysDir        string
+	DefaultKeysDir = os.Getenv("HOME") + "/.side-chain/keys"
+	KeysExportHex  bool
+	KeyFrom        string
+	PassphraseEnv  = "SC_PASSPHRASE"
+
+	SignerUrl        string
+	SignerProtocol   string
+	SignerAddress    string
+	SignerIdentifier string
+
+	BlobOutput     string
+	BlobNamespace  string
+	BlobFromHeight int64
+	BlobToHeight   int64
+	BlobCodec      string
+	BlobSplit      bool
+	BlobChunkSize  int
+	BlobTimeout    time.Duration
+	BlobObject     bool
+	BlobGenesis    string
+
+	DefaultGenesisPath = os.Getenv("HOME") + "/.side-chain/0/config/genesis.json"
+
+	MultisigThreshold int
+	MultisigSigners   string
+	MultisigAccount   string
+	MultisigOutput    string
+	MultisigBroadcast bool
+
+	TxAddress    string
+	TxMultisig   string
+	TxNonce      int64
+	TxAmount     string
+	TxTo         string
+	TxFile       string
+	TxNamespace  string
+	TxCodec      string
+	TxPrev       string
+	TxParts      string
+	TxOutput     string
+	TxWait       time.Duration
+	TxDryRun     bool
+	TxFull       bool
+	TxEthChainId uint64
+
+	ApiKeyEnv = "SC_API_KEY"
 
 	PortSpacingFactor = 100
 
@@ -51,10 +111,12 @@ var (
 	DefaultTdP2pPort   = 26656
 	DefaultTdRpcPort   = 26657
 	DefaultTdProxyPort = 26658
+	// DefaultTdPrivValPort is the priv_validator_laddr port of nodes initialized with --remote-signer
+	DefaultTdPrivValPort = 26659
 
 	DefaultAccountAddress    = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
 	DefaultAccountPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
-	DefaultAmount            = "0xde0b6b3a7640000" // 1ether
+	DefaultAmount            = "1eth"
 )
 
 var (
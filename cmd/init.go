@@ -43,6 +43,9 @@ func init() {
 
 	InitFilesCmd.Flags().IntVar(&TimeoutPropose, "time-propose", DefaultTimeoutPropose, "Timeout Propose")
 	InitFilesCmd.Flags().IntVar(&CreateEmptyBlocksInterval, "cebi", DefaultCreateEmptyBlocksInterval, "Create Empty Blocks Interval")
+
+	InitFilesCmd.Flags().BoolVar(&RemoteSigner, "remote-signer", false,
+		"Generate the validator keys into separate signer dirs like $HOME/.side-chain/0-signer, run by signer start")
 }
 
 func initFiles(cmd *cobra.Command, args []string) error {
@@ -54,6 +57,9 @@ type tempConfig struct {
 	nodeConfigPath    string
 	nodeId            string
 	actualGenesisPath string
+	// signerPath is the signer dir of the validator with --remote-signer
+	signerPath string
+	signerAddr string
 }
 
 // initFilesWithConfig initializes the necessary files and configurations for a side chain setup based on provided or default settings.
@@ -160,11 +166,35 @@ func initFilesWithConfig() error {
 		// set genesis path, use partial paths. RootDir+Genesis will be used when creating td
 		config.Genesis = "config/genesis.json"
 
+		// with a remote signer the key is only written to the signer dir, the node listens for the signer
+		var signerPath, signerAddr string
+		if RemoteSigner {
+			signerPath = filepath.Join(sideChainPath, fmt.Sprintf("%d-signer", idx))
+			for _, dir := range []string{"config", "data"} {
+				if err := tmos.EnsureDir(filepath.Join(signerPath, dir), 0700); err != nil {
+					logger.Error("ensure signer dir fail", "err", err)
+					return err
+				}
+			}
+
+			if len(hostList) == 0 {
+				config.PrivValidatorListenAddr = fmt.Sprintf("tcp://127.0.0.1:%d", DefaultTdPrivValPort+idx*PortSpacingFactor)
+				signerAddr = fmt.Sprintf("tcp://127.0.0.1:%d", DefaultTdPrivValPort+idx*PortSpacingFactor)
+			} else {
+				config.PrivValidatorListenAddr = fmt.Sprintf("tcp://0.0.0.0:%d", DefaultTdPrivValPort)
+				signerAddr = fmt.Sprintf("tcp://%s:%d", hostList[idx], DefaultTdPrivValPort)
+			}
+		}
+
 		// create file
 		{
 			actualP2PAddrBook := filepath.Join(nodeConfigPath, "addrbook.json")
 			actualPrivValidatorKey := filepath.Join(nodeConfigPath, "priv_validator_key.json")
 			actualPrivValidatorState := filepath.Join(nodeDataPath, "priv_validator_state.json")
+			if RemoteSigner {
+				actualPrivValidatorKey = filepath.Join(signerPath, config.PrivValidatorKey)
+				actualPrivValidatorState = filepath.Join(signerPath, config.PrivValidatorState)
+			}
 			var pv *privval.FilePV
 
 			if tmos.FileExists(actualP2PAddrBook) {
@@ -200,6 +230,8 @@ func initFilesWithConfig() error {
 					nodeConfigPath:    nodeConfigPath,
 					nodeId:            string(nodekey.ID()),
 					actualGenesisPath: filepath.Join(nodeConfigPath, "genesis.json"),
+					signerPath:        signerPath,
+					signerAddr:        signerAddr,
 				})
 
 				logger.Info("Generated node key", "path", config.NodeKey)
@@ -294,11 +326,38 @@ func initFilesWithConfig() error {
 			return err
 		}
 
+		if len(tempConfig.signerPath) != 0 {
+			if err := writeSignerConfig(tempConfig, genDoc.ChainID, config); err != nil {
+				logger.Error("write signer config fail", "err", err)
+				return err
+			}
+			if err := compressWithTar(tempConfig.signerPath, fmt.Sprintf("%s/%d-signer.tar.gz", compressPath, idx)); err != nil {
+				logger.Error("compress fail", "err", err)
+				return err
+			}
+		}
+
 	}
 
 	return nil
 }
 
+// writeSignerConfig writes the signer.toml of a signer dir, the signer dials the priv_validator_laddr of the node.
+func writeSignerConfig(tempConfig tempConfig, chainId string, config *cfg.Config) error {
+	signerConfig := coreCfg.SignerConfig{
+		ChainId:            chainId,
+		NodeAddr:           tempConfig.signerAddr,
+		PrivValidatorKey:   config.PrivValidatorKey,
+		PrivValidatorState: config.PrivValidatorState,
+	}
+
+	content, err := toml.Marshal(signerConfig)
+	if err != nil {
+		return err
+	}
+	return tmos.WriteFile(filepath.Join(tempConfig.signerPath, "config", "signer.toml"), content, 0644)
+}
+
 // genSeedsString generates a comma-separated string of key-value pairs from a map,
 // excluding the entry with a key matching the provided filter.
 // Each pair is formatted as "key@value". The resulting string is stripped of trailing commas.
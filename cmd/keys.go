@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/utils"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+)
+
+var KeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the encrypted signing keys",
+	Long: `Manage named secp256k1 keys, stored in --keyring-dir as scrypt encrypted keystore json like geth stores
+its keys. Signing commands use a key with --from <name>. The passphrase is prompted for, or read from ` + PassphraseEnv + `
+if set, or from stdin if it is not a terminal.`,
+}
+
+var KeysAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Generate a key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  addKey,
+}
+
+var KeysImportCmd = &cobra.Command{
+	Use:   "import [name] [file]",
+	Short: "Import a hex private key or a keystore json",
+	Long: `Import a key from a file holding a hex private key, or a keystore json e.g. of geth. A keystore json is
+decrypted with the passphrase and stored encrypted with it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: importKey,
+}
+
+var KeysExportCmd = &cobra.Command{
+	Use:   "export [name]",
+	Short: "Print the keystore json of a key, or its hex private key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  exportKey,
+}
+
+var KeysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys and their addresses",
+	Args:  cobra.NoArgs,
+	RunE:  listKeys,
+}
+
+var KeysDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a key, which has to be decrypted first",
+	Args:  cobra.ExactArgs(1),
+	RunE:  deleteKey,
+}
+
+func init() {
+	KeysCmd.PersistentFlags().StringVar(&KeysDir, "keyring-dir", DefaultKeysDir, "Directory of the keys")
+	KeysExportCmd.Flags().BoolVar(&KeysExportHex, "unarmored-hex", false, "Print the decrypted hex private key")
+
+	KeysCmd.AddCommand(KeysAddCmd, KeysImportCmd, KeysExportCmd, KeysListCmd, KeysDeleteCmd)
+}
+
+func newKeystore() *client.Keystore {
+	return client.NewKeystore(KeysDir, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
+func addKey(cmd *cobra.Command, args []string) error {
+	passphrase, err := utils.ReadPassphrase("Passphrase", PassphraseEnv, true)
+	if err != nil {
+		return err
+	}
+
+	address, err := newKeystore().Add(args[0], passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\t%s\n", args[0], address)
+	return nil
+}
+
+func importKey(cmd *cobra.Command, args []string) error {
+	b, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	b = bytes.TrimSpace(b)
+
+	var address common.Address
+	if bytes.HasPrefix(b, []byte("{")) {
+		passphrase, err := utils.ReadPassphrase("Passphrase of the keystore json", PassphraseEnv, false)
+		if err != nil {
+			return err
+		}
+		address, err = newKeystore().ImportJson(args[0], b, passphrase)
+		if err != nil {
+			return err
+		}
+	} else {
+		privateKey, err := crypto.HexToECDSA(utils.RemoveHexPrefix(string(b)))
+		if err != nil {
+			return err
+		}
+		passphrase, err := utils.ReadPassphrase("Passphrase", PassphraseEnv, true)
+		if err != nil {
+			return err
+		}
+		address, err = newKeystore().Import(args[0], privateKey, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%s\t%s\n", args[0], address)
+	return nil
+}
+
+func exportKey(cmd *cobra.Command, args []string) error {
+	ks := newKeystore()
+	if !KeysExportHex {
+		j, err := ks.Json(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	passphrase, err := utils.ReadPassphrase("Passphrase", PassphraseEnv, false)
+	if err != nil {
+		return err
+	}
+	privateKey, err := ks.Key(args[0], passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Println(common.Bytes2Hex(crypto.FromECDSA(privateKey)))
+	return nil
+}
+
+func listKeys(cmd *cobra.Command, args []string) error {
+	keys, err := newKeystore().List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", key.Name, key.Address)
+	}
+	return w.Flush()
+}
+
+func deleteKey(cmd *cobra.Command, args []string) error {
+	passphrase, err := utils.ReadPassphrase("Passphrase", PassphraseEnv, false)
+	if err != nil {
+		return err
+	}
+	if err := newKeystore().Delete(args[0], passphrase); err != nil {
+		return err
+	}
+	fmt.Printf("deleted %s\n", args[0])
+	return nil
+}
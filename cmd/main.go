@@ -1,6 +1,12 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/utils"
 	"github.com/spf13/cobra"
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/libs/log"
@@ -23,7 +29,65 @@ func main() {
 		StartCmd,
 		MintCmd,
 		QueryCmd,
+		BlobCmd,
+		KeysCmd,
+		SignerCmd,
+		MultisigCmd,
+		TxCmd,
 	)
 
 	rootCmd.Execute()
 }
+
+// newSigner returns the remote signer at --signer-url, or the key named by --from, decrypted with the passphrase,
+// or the key at the deprecated --privatekey-path, or the key of the default account if none is set.
+func newSigner() (client.Signer, error) {
+	if len(SignerUrl) != 0 {
+		if !common.IsHexAddress(SignerAddress) {
+			return nil, fmt.Errorf("--signer-address %q is not an address", SignerAddress)
+		}
+		var opts []client.SignerOption
+		if len(SignerIdentifier) != 0 {
+			opts = append(opts, client.WithSignerIdentifier(SignerIdentifier))
+		}
+		return client.NewRemoteSigner(SignerProtocol, SignerUrl, common.HexToAddress(SignerAddress), opts...)
+	}
+	if len(KeyFrom) != 0 {
+		passphrase, err := utils.ReadPassphrase("Passphrase of "+KeyFrom, PassphraseEnv, false)
+		if err != nil {
+			return nil, err
+		}
+		return client.NewKeystoreSigner(newKeystore(), KeyFrom, passphrase)
+	}
+
+	var privateKey *ecdsa.PrivateKey
+	var err error
+	if len(MintPrivateKeyPath) != 0 {
+		privateKey, err = client.LoadPrivateKey(MintPrivateKeyPath)
+	} else {
+		privateKey, err = crypto.HexToECDSA(DefaultAccountPrivateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.NewLocalSigner(privateKey), nil
+}
+
+// addSigningFlags adds the flags choosing the key or remote signer signing the txs of cmd.
+func addSigningFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&KeyFrom, "from", "", "Name of the signing key, see keys. The default account if empty")
+	cmd.Flags().StringVar(&KeysDir, "keyring-dir", DefaultKeysDir, "Directory of the keys")
+	cmd.Flags().StringVar(&SignerUrl, "signer-url", "", "URL of a remote signer holding the key instead of --from")
+	cmd.Flags().StringVar(&SignerProtocol, "signer", client.SignerWeb3Signer, "Protocol of the remote signer: web3signer or clef")
+	cmd.Flags().StringVar(&SignerAddress, "signer-address", "", "Address the remote signer signs for")
+	cmd.Flags().StringVar(&SignerIdentifier, "signer-identifier", "", "Web3Signer key identifier, the address if empty")
+	cmd.Flags().StringVarP(&MintPrivateKeyPath, "privatekey-path", "k", DefaultMintPrivateKeyPath, "Private key path")
+	cmd.MarkFlagsMutuallyExclusive("signer-url", "from")
+	cmd.MarkFlagsRequiredTogether("signer-url", "signer-address")
+	_ = cmd.Flags().MarkDeprecated("privatekey-path", "store the key with keys import and use --from")
+}
+
+// newClient returns a client of the node RPC, authenticated with the api key or jwt in SC_API_KEY if set.
+func newClient(nodeRpc string) *client.Client {
+	return client.NewClient(nodeRpc, client.WithApiKey(os.Getenv(ApiKeyEnv)))
+}
@@ -1,135 +1,121 @@
 package main
 
 import (
-	"bytes"
-	"crypto/ecdsa"
-	"encoding/base64"
-	"encoding/gob"
-	"encoding/json"
+	"context"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
 	"github.com/nbnet/side-chain/core/types"
 	"github.com/spf13/cobra"
-	"github.com/tendermint/tendermint/libs/os"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"math/big"
+	"time"
 )
 
 var MintCmd = &cobra.Command{
 	Use:   "mint",
 	Short: "Mint Token",
-	RunE:  mint,
+	Long: `Mint --amount to the signing account, --count times. With --to the minted amount is transferred on to another
+account by a transfer following every mint.
+
+The txs are sent with consecutive nonces. The node checks a nonce against the committed state, so every tx but the
+last is waited for before the next is sent, with --wait the last one too. A tx waited for has to succeed in
+DeliverTx.`,
+	Args: cobra.NoArgs,
+	RunE: mint,
 }
 
 func init() {
 	MintCmd.Flags().StringVarP(&MintTdRpc, "td-rpc", "r", DefaultMintTdRpc, "RPC server address")
 	MintCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
-	MintCmd.Flags().StringVarP(&MintPrivateKeyPath, "privatekey-path", "k", DefaultMintPrivateKeyPath, "Mint private key path")
+	MintCmd.Flags().StringVar(&MintAmount, "amount", DefaultAmount, "Amount of every mint in wei, or with a unit: 250eth, 1.5gwei")
+	MintCmd.Flags().StringVar(&MintTo, "to", "", "Account receiving the minted amount, the signing account if empty")
+	MintCmd.Flags().IntVar(&MintCount, "count", 1, "Number of mints")
+	MintCmd.Flags().BoolVar(&MintWait, "wait", false, "Wait for the last mint to be included too")
+	addSigningFlags(MintCmd)
+	_ = MintCmd.Flags().MarkDeprecated("td-rpc", "txs are broadcast through --node-rpc")
 }
 
 func mint(cmd *cobra.Command, args []string) error {
 
-	var privateKey *ecdsa.PrivateKey
-	var address common.Address
-	var err error
-
-	if len(MintPrivateKeyPath) == 0 {
-		privateKey, err = crypto.HexToECDSA(DefaultAccountPrivateKey)
-		if err != nil {
-			logger.Error("load private key error", "err", err)
-			return err
-		}
-		address = crypto.PubkeyToAddress(privateKey.PublicKey)
-	} else {
-		b, err := os.ReadFile(MintPrivateKeyPath)
-		if err != nil {
-			logger.Error("read private key error", "err", err)
-			return err
-		}
-		privateKey, err = crypto.HexToECDSA(strings.TrimSpace(string(b)))
-		if err != nil {
-			logger.Error("load private key error", "err", err)
-			return err
-		}
-		address = crypto.PubkeyToAddress(privateKey.PublicKey)
-	}
-
-	nonce, err := getNonce(address.String(), MintNodeRpc)
-
-	body := types.MintBody{
-		Nonce:   uint64(nonce),
-		Amount:  DefaultAmount,
-		Address: address.String(),
-	}
-
-	mintTx := types.Tx{
-		Ty:   types.Mint,
-		Body: body,
-	}
-
-	var b bytes.Buffer
-	encoder := gob.NewEncoder(&b)
-	err = encoder.Encode(mintTx.Body)
+	amount, err := parseAmount(MintAmount)
 	if err != nil {
-		logger.Error("RLP encode error", err)
 		return err
 	}
+	if MintCount < 1 {
+		return fmt.Errorf("invalid --count %d", MintCount)
+	}
 
-	digestHash, err := body.DigestHash()
+	signer, err := newSigner()
 	if err != nil {
-		logger.Error("Digest hash error", err)
+		logger.Error("load signer error", "err", err)
 		return err
 	}
+	address := signer.Address()
 
-	signature, err := crypto.Sign(digestHash, privateKey)
-	if err != nil {
-		logger.Error("Sign error", err)
-		return err
+	to := address
+	if len(MintTo) != 0 {
+		if !common.IsHexAddress(MintTo) {
+			return fmt.Errorf("invalid --to %q", MintTo)
+		}
+		to = common.HexToAddress(MintTo)
 	}
 
-	mintTx.Signature = common.Bytes2Hex(signature)
+	c := newClient(MintNodeRpc)
+	ctx := context.Background()
 
-	j, err := json.Marshal(&mintTx)
+	nonce, err := c.Nonce(ctx, address)
 	if err != nil {
-		logger.Error("JSON marshal error", err)
+		logger.Error("get account nonce error", "err", err)
 		return err
 	}
-	tx := base64.StdEncoding.EncodeToString(j)
-	requestBody := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "broadcast_tx_sync",
-		"params": map[string]interface{}{
-			"tx": tx,
-		},
-		"id": 1,
+
+	txs := MintCount
+	if to != address {
+		txs *= 2
 	}
+	for i := 0; i < txs; i++ {
+		var tx *types.Tx
+		if to != address && i%2 == 1 {
+			tx, err = client.NewTransferTxWith(ctx, signer, nonce, to, amount)
+		} else {
+			tx, err = client.NewMintTxWith(ctx, signer, nonce, amount)
+		}
+		if err != nil {
+			logger.Error("sign tx error", "err", err)
+			return err
+		}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		logger.Error("Error marshalling JSON:", err)
-		return err
+		wait := time.Duration(0)
+		if i < txs-1 || MintWait {
+			wait = types.DefaultWaitCommitTimeout
+		}
+		if err := sendMintTx(c, tx, wait); err != nil {
+			return err
+		}
+		nonce++
 	}
 
-	//url := fmt.Sprintf("http://%s:%d", DefaultHostList, DefaultTdRpcPort)
+	total := new(big.Int).Mul(amount, big.NewInt(int64(MintCount)))
+	logger.Info("minted", "to", to, "amount", total, "mints", MintCount, "confirmed", MintWait)
+	return nil
+}
 
-	response, err := http.Post(MintTdRpc, "application/json", bytes.NewBuffer(jsonData))
+// sendMintTx broadcasts tx and, if wait is set, checks that it succeeded in DeliverTx.
+func sendMintTx(c *client.Client, tx *types.Tx, wait time.Duration) error {
+	result, err := c.BroadcastTx(context.Background(), tx, wait)
 	if err != nil {
-		logger.Error("Error sending POST request:", err)
+		logger.Error("broadcast tx error", "err", err)
 		return err
 	}
-	defer response.Body.Close()
-
-	// 读取响应内容
-	context, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		logger.Error("Error reading response body:", err)
-		return err
+	if wait == 0 {
+		logger.Info(tx.Ty.String()+" broadcast", "hash", result.Hash)
+		return nil
 	}
 
-	logger.Info("Response", "Response Status:", response.Status)
-	logger.Info("Response", "Response Body:", string(context))
-	logger.Info("Mint Account", "Address", address, "Amount", "1ether")
-
+	receipt := result.Receipt
+	if receipt == nil || receipt.Status != types.TxStatusIncluded {
+		return fmt.Errorf("%s %s not included: %s", tx.Ty, result.Hash, receiptLog(receipt))
+	}
+	logger.Info(tx.Ty.String()+" included", "hash", result.Hash, "height", receipt.Height)
 	return nil
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	amounts := map[string]string{
+		"1000":         "1000",
+		"0x3e8":        "1000",
+		"1000wei":      "1000",
+		"1.5gwei":      "1500000000",
+		"250eth":       "250000000000000000000",
+		"0.25 ether":   "250000000000000000",
+		"1ETH":         "1000000000000000000",
+		"2.000000gwei": "2000000000",
+	}
+	for s, expected := range amounts {
+		amount, err := parseAmount(s)
+		if err != nil || amount.String() != expected {
+			t.Fatalf("%s: expected %s, got %v %v", s, expected, amount, err)
+		}
+	}
+
+	for _, s := range []string{"", "eth", "1.5wei", "-1eth", "1.2.3eth", "1btc", ".5eth"} {
+		if amount, err := parseAmount(s); err == nil {
+			t.Fatalf("%s: expected error, got %s", s, amount)
+		}
+	}
+}
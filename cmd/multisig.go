@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var MultisigCmd = &cobra.Command{
+	Use:   "multisig",
+	Short: "Create multisig accounts and sign their txs offline",
+	Long: `A multisig account is the address of a threshold and a set of signer addresses, every tx it sends carries
+the signatures of at least threshold signers. The txs are signed offline:
+
+  sc multisig create --threshold 2 --signers 0x..,0x..,0x.. -o treasury.json
+  sc multisig build mint --account treasury.json --amount 1000000000000000000 -o tx.json
+  sc multisig sign tx.json --from alice -o alice.json     # every signer on their own machine
+  sc multisig combine alice.json bob.json --broadcast`,
+}
+
+var MultisigCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Print the address of a multisig account and write its account file",
+	Args:  cobra.NoArgs,
+	RunE:  createMultisig,
+}
+
+var MultisigBuildCmd = &cobra.Command{
+	Use:   "build [mint|transfer]",
+	Short: "Write an unsigned mint or transfer of a multisig account",
+	Long: `Write an unsigned mint or transfer of the multisig account of --account, to be signed with multisig sign.
+The nonce of the account is read from the node unless --nonce is set. Same as tx build --multisig.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"mint", "transfer"},
+	RunE:      buildMultisigTx,
+}
+
+var MultisigSignCmd = &cobra.Command{
+	Use:   "sign [tx file]",
+	Short: "Add the signature of --from to a multisig tx",
+	Args:  cobra.ExactArgs(1),
+	RunE:  signMultisigTx,
+}
+
+var MultisigCombineCmd = &cobra.Command{
+	Use:   "combine [tx files...]",
+	Short: "Combine the signatures of copies of a multisig tx",
+	Long: `Combine the signatures of copies of a multisig tx signed by different signers. The combined tx is written
+once it carries enough signatures, with --broadcast it is submitted and waited for.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: combineMultisigTxs,
+}
+
+func init() {
+	MultisigCreateCmd.Flags().IntVar(&MultisigThreshold, "threshold", 0, "Signatures a tx needs")
+	MultisigCreateCmd.Flags().StringVar(&MultisigSigners, "signers", "", "Signer addresses, separated by commas")
+	MultisigCreateCmd.Flags().StringVarP(&MultisigOutput, "output", "o", "", "Account file, stdout if empty")
+	_ = MultisigCreateCmd.MarkFlagRequired("threshold")
+	_ = MultisigCreateCmd.MarkFlagRequired("signers")
+
+	MultisigBuildCmd.Flags().StringVar(&MultisigAccount, "account", "", "Account file of multisig create")
+	MultisigBuildCmd.Flags().StringVar(&TxAmount, "amount", "", "Amount in wei, or with a unit: 250eth, 1.5gwei")
+	MultisigBuildCmd.Flags().StringVar(&TxTo, "to", "", "Recipient of a transfer")
+	MultisigBuildCmd.Flags().Int64Var(&TxNonce, "nonce", -1, "Nonce of the account, read from the node if negative")
+	MultisigBuildCmd.Flags().StringVarP(&TxOutput, "output", "o", "", "Tx file, stdout if empty")
+	MultisigBuildCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+	_ = MultisigBuildCmd.MarkFlagRequired("account")
+	_ = MultisigBuildCmd.MarkFlagRequired("amount")
+
+	addSigningFlags(MultisigSignCmd)
+	MultisigSignCmd.Flags().StringVarP(&MultisigOutput, "output", "o", "", "Signed tx file, stdout if empty")
+
+	MultisigCombineCmd.Flags().StringVarP(&MultisigOutput, "output", "o", "", "Combined tx file, stdout if empty")
+	MultisigCombineCmd.Flags().BoolVar(&MultisigBroadcast, "broadcast", false, "Submit the combined tx and wait for its inclusion")
+	MultisigCombineCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+
+	MultisigCmd.AddCommand(MultisigCreateCmd, MultisigBuildCmd, MultisigSignCmd, MultisigCombineCmd)
+}
+
+// multisigAccount is the account file of multisig create.
+type multisigAccount struct {
+	Address   common.Address `json:"address"`
+	Threshold int            `json:"threshold"`
+	Signers   []string       `json:"signers"`
+}
+
+func createMultisig(cmd *cobra.Command, args []string) error {
+	var signers []common.Address
+	for _, signer := range strings.Split(MultisigSigners, ",") {
+		signer = strings.TrimSpace(signer)
+		if !common.IsHexAddress(signer) {
+			return fmt.Errorf("invalid signer %q", signer)
+		}
+		signers = append(signers, common.HexToAddress(signer))
+	}
+
+	multisig, err := types.NewMultisig(MultisigThreshold, signers)
+	if err != nil {
+		return err
+	}
+	address, _ := multisig.Address()
+
+	account := multisigAccount{Address: address, Threshold: multisig.Threshold, Signers: multisig.Signers}
+	if err := writeJson(MultisigOutput, account); err != nil {
+		return err
+	}
+	if len(MultisigOutput) != 0 {
+		logger.Info("multisig account", "address", address, "threshold", multisig.Threshold, "signers", len(signers))
+	}
+	return nil
+}
+
+func buildMultisigTx(cmd *cobra.Command, args []string) error {
+	TxMultisig = MultisigAccount
+	return buildTx(cmd, args)
+}
+
+// loadMultisigAccount reads the account file of multisig create, the multisig has no signatures yet.
+func loadMultisigAccount(file string) (*types.Multisig, common.Address, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	var account multisigAccount
+	if err := json.Unmarshal(b, &account); err != nil {
+		return nil, common.Address{}, fmt.Errorf("account file: %w", err)
+	}
+
+	multisig := &types.Multisig{Threshold: account.Threshold, Signers: account.Signers, Signatures: make([]string, len(account.Signers))}
+	address, err := multisig.Address()
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	if address != account.Address {
+		return nil, common.Address{}, fmt.Errorf("account file of %s derives %s", account.Address, address)
+	}
+	return multisig, address, nil
+}
+
+func signMultisigTx(cmd *cobra.Command, args []string) error {
+	tx, err := readTx(args[0])
+	if err != nil {
+		return err
+	}
+	signer, err := newSigner()
+	if err != nil {
+		return err
+	}
+
+	if err := client.SignMultisigTx(context.Background(), tx, signer); err != nil {
+		return err
+	}
+	if err := writeTx(MultisigOutput, tx); err != nil {
+		return err
+	}
+	if len(MultisigOutput) != 0 {
+		logger.Info("multisig tx signed", "signer", signer.Address(), "signatures", countSignatures(tx.Multisig), "threshold", tx.Multisig.Threshold)
+	}
+	return nil
+}
+
+func combineMultisigTxs(cmd *cobra.Command, args []string) error {
+	txs := make([]*types.Tx, 0, len(args))
+	for _, file := range args {
+		tx, err := readTx(file)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+	}
+
+	tx, err := client.CombineMultisigTxs(txs...)
+	if err != nil {
+		return err
+	}
+	if err := client.VerifyTx(tx); err != nil {
+		return fmt.Errorf("combined tx with %d of %d signatures: %w", countSignatures(tx.Multisig), tx.Multisig.Threshold, err)
+	}
+	if len(MultisigOutput) != 0 || !MultisigBroadcast {
+		if err := writeTx(MultisigOutput, tx); err != nil {
+			return err
+		}
+	}
+	if !MultisigBroadcast {
+		return nil
+	}
+
+	result, err := newClient(MintNodeRpc).BroadcastTx(context.Background(), tx, types.DefaultWaitCommitTimeout)
+	if err != nil {
+		return err
+	}
+	if result.Receipt == nil || result.Receipt.Status != types.TxStatusIncluded {
+		return fmt.Errorf("tx %s not included: %s", result.Hash, receiptLog(result.Receipt))
+	}
+	logger.Info("multisig tx included", "hash", result.Hash, "height", result.Receipt.Height)
+	return nil
+}
+
+func countSignatures(multisig *types.Multisig) int {
+	count := 0
+	for _, signature := range multisig.Signatures {
+		if len(signature) != 0 {
+			count++
+		}
+	}
+	return count
+}
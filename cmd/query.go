@@ -1,116 +1,387 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
 	"github.com/spf13/cobra"
-	"io/ioutil"
-	"net/http"
+	tmClient "github.com/tendermint/tendermint/rpc/client/http"
+	"io"
+	"math/big"
+	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 var QueryCmd = &cobra.Command{
-	Use:   "query [balance] [nonce]",
-	Short: "Query balance/nonce",
-	Args:  cobra.ExactArgs(1),
-	RunE:  query,
+	Use:   "query [account|tx|block|blob|params|validators] [address|hash]",
+	Short: "Query accounts, txs, blocks, blobs, consensus params and validators",
+	Long: `Query the state of an account, the receipt of a tx or a blob from the node at --node-rpc, or a block, the
+consensus params or the validators from Tendermint at --td-rpc.
+
+  sc query account 0x..        # balance in wei and ether, and nonce, --address if no address is given
+  sc query tx <hash>
+  sc query blob <hash>
+  sc query block               # the latest block, --height for another one
+  sc query params
+  sc query validators
+
+--height reads the state of an account, a block, the params or the validators at a past height. balance and nonce
+query a single field of account.`,
+	Args:      cobra.RangeArgs(1, 2),
+	ValidArgs: []string{"account", "tx", "block", "blob", "params", "validators", "balance", "nonce"},
+	RunE:      query,
 }
 
 func init() {
 	QueryCmd.Flags().StringVarP(&QueryAddress, "address", "a", DefaultAccountAddress.String(), "Query by address")
 	QueryCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+	QueryCmd.Flags().StringVarP(&MintTdRpc, "td-rpc", "r", DefaultMintTdRpc, "Tendermint RPC address of block, params and validators")
+	QueryCmd.Flags().StringVarP(&QueryOutput, "output", "o", "text", "Output format: json, table or text")
+	QueryCmd.Flags().Int64Var(&QueryHeight, "height", 0, "Height to read at, the latest if 0")
 }
 
 func query(cmd *cobra.Command, args []string) error {
-	arg := args[0]
-	baseUrl := fmt.Sprintf("http://%s:%d", DefaultHostList, DefaultNodePort)
-	if len(MintNodeRpc) != 0 || MintNodeRpc != DefaultMintNodeRpc {
-		baseUrl = MintNodeRpc
+	switch QueryOutput {
+	case "json", "table", "text":
+	default:
+		return fmt.Errorf("unknown output format %q", QueryOutput)
+	}
+	if QueryHeight < 0 {
+		return fmt.Errorf("invalid --height %d", QueryHeight)
 	}
 
-	switch strings.ToLower(arg) {
-	case "balance":
-		balance, err := getBalance(QueryAddress, baseUrl)
-		if err != nil {
-			logger.Error("query balance", "err", err)
+	arg := ""
+	if len(args) == 2 {
+		arg = args[1]
+	}
+	ctx := context.Background()
+
+	var result *queryResult
+	var err error
+	switch kind := strings.ToLower(args[0]); kind {
+	case "account", "balance", "nonce":
+		result, err = queryAccount(ctx, arg, kind)
+	case "tx", "blob":
+		if len(arg) == 0 {
+			return fmt.Errorf("query %s needs a hash", kind)
 		}
-		logger.Info("account balance", "balance", balance)
-	case "nonce":
-		nonce, err := getNonce(QueryAddress, baseUrl)
-		if err != nil {
-			logger.Error("query balance", "err", err)
+		if QueryHeight != 0 {
+			return fmt.Errorf("--height does not apply to query %s", kind)
+		}
+		if kind == "tx" {
+			result, err = queryTx(ctx, arg)
+		} else {
+			result, err = queryBlob(ctx, arg)
 		}
-		logger.Info("account nonce", "nonce", nonce)
+	case "block", "params", "validators":
+		if len(arg) != 0 {
+			return fmt.Errorf("query %s takes no argument, see --height", kind)
+		}
+		result, err = queryTendermint(ctx, kind)
 	default:
-		logger.Error("invalid query type")
-		return nil
+		return fmt.Errorf("unknown query %q", args[0])
 	}
-	return nil
+	if err != nil {
+		return err
+	}
+
+	return result.print(os.Stdout, QueryOutput)
 }
 
-func getBalance(address, baseUrl string) (string, error) {
-	url := fmt.Sprintf("%s/balance/%s", baseUrl, address)
-	response, err := http.Get(url)
+func queryAccount(ctx context.Context, addressStr, kind string) (*queryResult, error) {
+	if len(addressStr) == 0 {
+		addressStr = QueryAddress
+	}
+	if !common.IsHexAddress(addressStr) {
+		return nil, fmt.Errorf("invalid address %q", addressStr)
+	}
+
+	account, err := newClient(MintNodeRpc).Account(ctx, common.HexToAddress(addressStr), QueryHeight)
 	if err != nil {
-		logger.Error("get account balance error", "err", err)
-		return "", err
+		return nil, err
 	}
-	defer response.Body.Close()
-	body, err := ioutil.ReadAll(response.Body)
+
+	result := new(queryResult)
+	switch kind {
+	case "balance":
+		result.add("balance", account.Balance.String()).add("balance_ether", formatEther(account.Balance))
+	case "nonce":
+		result.add("nonce", account.Nonce)
+	default:
+		result.add("address", account.Address.String()).
+			add("balance", account.Balance.String()).
+			add("balance_ether", formatEther(account.Balance)).
+			add("nonce", account.Nonce)
+	}
+	return result.add("height", account.Height), nil
+}
+
+func queryTx(ctx context.Context, hash string) (*queryResult, error) {
+	receipt, err := newClient(MintNodeRpc).Tx(ctx, hash)
 	if err != nil {
-		logger.Error("Error reading response body:", err)
-		return "", err
+		return nil, err
 	}
 
-	var m map[string]interface{}
-	if err := json.Unmarshal(body, &m); err != nil {
-		logger.Error("parse bytes to map[string]interface{}:", err)
-		return "", err
+	result := new(queryResult).add("hash", receipt.Hash).add("status", receipt.Status)
+	if receipt.Status == types.TxStatusPending {
+		return result, nil
 	}
-	data, ok := m["data"].(map[string]interface{})
-	if !ok {
-		logger.Error("parse data to map[string]interface{}:", m)
-		return "", nil
+	return result.add("type", receipt.Type.String()).
+		add("height", receipt.Height).
+		add("index", receipt.Index).
+		add("gas_used", receipt.GasUsed).
+		add("fee", weiString(receipt.Fee)).
+		add("tx_code", receipt.TxCode).
+		add("log", receipt.Log), nil
+}
+
+func queryBlob(ctx context.Context, hash string) (*queryResult, error) {
+	blob, err := newClient(MintNodeRpc).Blob(ctx, hash)
+	if err != nil {
+		return nil, err
 	}
-	balance, ok := data["balance"].(string)
-	if !ok {
-		logger.Error("parse balance to string:", data)
-		return "", nil
+	result := new(queryResult).add("hash", types.NormalizeTxHash(hash)).
+		add("height", blob.Height).
+		add("tx_code", blob.TxCode).
+		add("address", blob.Address).
+		add("namespace", blob.Namespace).
+		add("codec", blob.Codec)
+
+	// size is the decompressed size, left out when the data of a failed blob does not decompress
+	body := types.BlobBody{Data: blob.Data, Codec: blob.Codec}
+	var size countingWriter
+	if _, err := body.Decompress(&size); err == nil {
+		result.add("size", int64(size))
 	}
+	return result.add("compressed_size", body.Size()), nil
+}
 
-	return balance, nil
+// countingWriter counts the bytes written to it.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
 }
 
-func getNonce(address, baseUrl string) (float64, error) {
-	url := fmt.Sprintf("%s/nonce/%s", baseUrl, address)
-	response, err := http.Get(url)
+func queryTendermint(ctx context.Context, kind string) (*queryResult, error) {
+	tdClient, err := tmClient.New(MintTdRpc, "/websocket")
 	if err != nil {
-		logger.Error("get account balance error", "err", err)
-		return 0, err
+		return nil, err
 	}
-	defer response.Body.Close()
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		logger.Error("Error reading response body:", err)
-		return 0, err
+	var height *int64
+	if QueryHeight != 0 {
+		height = &QueryHeight
 	}
 
-	var m map[string]interface{}
-	if err := json.Unmarshal(body, &m); err != nil {
-		logger.Error("parse bytes to map[string]interface{}:", err)
-		return 0, err
+	switch kind {
+	case "block":
+		block, err := tdClient.Block(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		header := block.Block.Header
+		result := new(queryResult).add("height", header.Height).
+			add("hash", block.BlockID.Hash.String()).
+			add("time", header.Time.UTC().Format(time.RFC3339Nano)).
+			add("chain_id", header.ChainID).
+			add("proposer", header.ProposerAddress.String()).
+			add("app_hash", header.AppHash.String()).
+			add("data_hash", header.DataHash.String()).
+			add("txs", len(block.Block.Txs))
+		result.list("transactions", "index", "hash", "type", "sender", "size")
+		for i, tx := range block.Block.Txs {
+			ty, sender := "unknown", ""
+			if decoded, err := client.DecodeTx(tx); err == nil {
+				ty = decoded.Ty.String()
+				if address, err := client.TxSender(decoded); err == nil {
+					sender = address.String()
+				}
+			}
+			result.row(i, types.TxHash(tx), ty, sender, len(tx))
+		}
+		return result, nil
+
+	case "params":
+		params, err := tdClient.ConsensusParams(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		p := params.ConsensusParams
+		return new(queryResult).add("height", params.BlockHeight).
+			add("block_max_bytes", p.Block.MaxBytes).
+			add("block_max_gas", p.Block.MaxGas).
+			add("evidence_max_age_num_blocks", p.Evidence.MaxAgeNumBlocks).
+			add("evidence_max_age_duration", p.Evidence.MaxAgeDuration.String()).
+			add("evidence_max_bytes", p.Evidence.MaxBytes).
+			add("validator_pub_key_types", strings.Join(p.Validator.PubKeyTypes, ",")).
+			add("app_version", p.Version.AppVersion), nil
+
+	default:
+		result := new(queryResult)
+		result.list("validators", "address", "voting_power", "proposer_priority", "pub_key")
+		total := int64(0)
+		perPage := 100
+		for page := 1; ; page++ {
+			validators, err := tdClient.Validators(ctx, height, &page, &perPage)
+			if err != nil {
+				return nil, err
+			}
+			if page == 1 {
+				result.add("height", validators.BlockHeight).add("count", validators.Total)
+			}
+			for _, v := range validators.Validators {
+				result.row(v.Address.String(), v.VotingPower, v.ProposerPriority, fmt.Sprintf("%X", v.PubKey.Bytes()))
+				total += v.VotingPower
+			}
+			if page*perPage >= validators.Total {
+				break
+			}
+		}
+		return result.add("voting_power", total), nil
+	}
+}
+
+// formatEther formats an amount in wei as ether, without trailing zeros.
+func formatEther(wei *big.Int) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	whole, fraction := new(big.Int).QuoRem(new(big.Int).Abs(wei), unit, new(big.Int))
+
+	s := whole.String()
+	if fraction.Sign() != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%018s", fraction.String()), "0")
+	}
+	if wei.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// queryResult is the result of a query, fields in order and optionally a list of rows, printed as json, table
+// or text.
+type queryResult struct {
+	keys    []string
+	values  []interface{}
+	name    string
+	columns []string
+	rows    [][]interface{}
+}
+
+func (r *queryResult) add(key string, value interface{}) *queryResult {
+	r.keys = append(r.keys, key)
+	r.values = append(r.values, value)
+	return r
+}
+
+// list starts a list of rows named name with columns.
+func (r *queryResult) list(name string, columns ...string) {
+	r.name, r.columns, r.rows = name, columns, [][]interface{}{}
+}
+
+func (r *queryResult) row(values ...interface{}) {
+	r.rows = append(r.rows, values)
+}
+
+func (r *queryResult) print(out io.Writer, format string) error {
+	switch format {
+	case "json":
+		b, err := r.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, b, "", "  "); err != nil {
+			return err
+		}
+		indented.WriteByte('\n')
+		_, err = out.Write(indented.Bytes())
+		return err
+
+	case "table":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for i, key := range r.keys {
+			fmt.Fprintf(w, "%s\t%v\n", strings.ToUpper(key), r.values[i])
+		}
+		if len(r.name) != 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, strings.ToUpper(strings.Join(r.columns, "\t")))
+			for _, row := range r.rows {
+				fmt.Fprintln(w, joinValues(row, "\t"))
+			}
+		}
+		return w.Flush()
+
+	default:
+		for i, key := range r.keys {
+			fmt.Fprintf(out, "%s: %v\n", key, r.values[i])
+		}
+		for _, row := range r.rows {
+			pairs := make([]string, len(row))
+			for i, value := range row {
+				pairs[i] = fmt.Sprintf("%s=%v", r.columns[i], value)
+			}
+			fmt.Fprintln(out, strings.Join(pairs, " "))
+		}
+		return nil
+	}
+}
+
+// MarshalJSON encodes the fields in order, the rows as a list of objects.
+func (r *queryResult) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	write := func(keys []string, values []interface{}) error {
+		b.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			v, err := json.Marshal(values[i])
+			if err != nil {
+				return err
+			}
+			b.Write(k)
+			b.WriteByte(':')
+			b.Write(v)
+		}
+		b.WriteByte('}')
+		return nil
+	}
+
+	if err := write(r.keys, r.values); err != nil || len(r.name) == 0 {
+		return b.Bytes(), err
 	}
-	data, ok := m["data"].(map[string]interface{})
-	if !ok {
-		logger.Error("parse data to map[string]interface{}:", m)
-		return 0, fmt.Errorf("parse data to map[string]interface{}: %s", m)
+
+	// reopen the object for the list of rows
+	b.Truncate(b.Len() - 1)
+	if len(r.keys) != 0 {
+		b.WriteByte(',')
 	}
-	// uint64 to float64.........
-	nonce, ok := data["nonce"].(float64)
-	if !ok {
-		logger.Error("parse nonce to float64:", data)
-		return 0, fmt.Errorf("parse nonce to float64: %s", data)
+	name, _ := json.Marshal(r.name)
+	b.Write(name)
+	b.WriteString(":[")
+	for i, row := range r.rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := write(r.columns, row); err != nil {
+			return nil, err
+		}
 	}
+	b.WriteString("]}")
+	return b.Bytes(), nil
+}
 
-	return nonce, nil
+func joinValues(values []interface{}, sep string) string {
+	s := make([]string, len(values))
+	for i, value := range values {
+		s[i] = fmt.Sprint(value)
+	}
+	return strings.Join(s, sep)
 }
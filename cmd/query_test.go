@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/nbnet/side-chain/core/types"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFormatEther(t *testing.T) {
+	amounts := map[string]string{
+		"0":                      "0",
+		"1":                      "0.000000000000000001",
+		"1500000000000000000":    "1.5",
+		"250000000000000000000":  "250",
+		"-250000000000000000000": "-250",
+	}
+	for wei, expected := range amounts {
+		amount, _ := new(big.Int).SetString(wei, 10)
+		if ether := formatEther(amount); ether != expected {
+			t.Fatalf("%s: expected %s, got %s", wei, expected, ether)
+		}
+	}
+}
+
+func TestQueryResult(t *testing.T) {
+	result := new(queryResult).add("height", 3).add("hash", "AB")
+	result.list("validators", "address", "power")
+	result.row("01", 10)
+	result.row("02", 20)
+
+	expected := map[string]string{
+		"json":  "{\n  \"height\": 3,\n  \"hash\": \"AB\",\n  \"validators\": [\n    {\n      \"address\": \"01\",\n      \"power\": 10\n    },\n    {\n      \"address\": \"02\",\n      \"power\": 20\n    }\n  ]\n}\n",
+		"table": "HEIGHT  3\nHASH    AB\n\nADDRESS  POWER\n01       10\n02       20\n",
+		"text":  "height: 3\nhash: AB\naddress=01 power=10\naddress=02 power=20\n",
+	}
+	for format, output := range expected {
+		var b bytes.Buffer
+		if err := result.print(&b, format); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != output {
+			t.Fatalf("%s: expected\n%s\ngot\n%s", format, output, b.String())
+		}
+	}
+}
+
+func TestQueryBlob(t *testing.T) {
+	data := bytes.Repeat([]byte("rollup batch "), 1000)
+	var compressed bytes.Buffer
+	writer, _ := types.NewCodecWriter(types.CodecGzip, &compressed)
+	_, _ = writer.Write(data)
+	_ = writer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.NewRpcResp(nil, map[string]interface{}{"data": "0x" + hex.EncodeToString(compressed.Bytes()), "codec": types.CodecGzip}))
+	}))
+	defer server.Close()
+	MintNodeRpc = server.URL
+
+	result, err := queryBlob(context.Background(), "AB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]interface{})
+	for i, key := range result.keys {
+		values[key] = result.values[i]
+	}
+	if values["size"] != int64(len(data)) || values["compressed_size"] != compressed.Len() {
+		t.Fatalf("expected size %d and compressed_size %d, got %v", len(data), compressed.Len(), values)
+	}
+}
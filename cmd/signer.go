@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/nbnet/side-chain/core/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+var SignerCmd = &cobra.Command{
+	Use:   "signer",
+	Short: "Run the validator signer of a node",
+}
+
+var SignerStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Sign the votes and proposals of a node with the validator key of a signer dir",
+	Long: `Run the validator signer of a signer dir generated by init --remote-signer, so the validator key can live
+on another host than the node. The signer dials the priv_validator_laddr of the node in config/signer.toml and
+redials whenever the connection is lost.
+
+The last signed height, round and step are kept in data/priv_validator_state.json, a vote or proposal
+conflicting with them is refused. The signer dir is locked, so a second signer with the same key can not run
+from it. Never copy the key to another signer dir while this one is in use.`,
+	RunE: startSigner,
+}
+
+func init() {
+	SignerStartCmd.Flags().StringVarP(&SignerDir, "signer-dir", "d", DefaultSignerDir, "Signer directory")
+
+	SignerCmd.AddCommand(SignerStartCmd)
+}
+
+func startSigner(cmd *cobra.Command, args []string) error {
+	signerConfig := &types.SignerConfig{}
+	viper.SetConfigFile(filepath.Join(SignerDir, "config", "signer.toml"))
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	if err := viper.Unmarshal(signerConfig); err != nil {
+		return err
+	}
+
+	unlock, err := utils.LockFile(filepath.Join(SignerDir, "data", "signer.lock"))
+	if err != nil {
+		logger.Error("lock signer dir fail", "err", err)
+		return err
+	}
+	defer unlock()
+
+	signer, err := types.NewSigner(signerConfig, SignerDir, logger)
+	if err != nil {
+		logger.Error("create signer fail", "err", err)
+		return err
+	}
+	if err := signer.Start(); err != nil {
+		logger.Error("start signer fail", "err", err)
+		return err
+	}
+	defer func() {
+		if err := signer.Stop(); err != nil {
+			logger.Error("stop signer fail", "err", err)
+		}
+	}()
+	logger.Info("signer started", "chain_id", signerConfig.ChainId, "node", signerConfig.NodeAddr)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	sig := <-c
+	logger.Info("shutting down", "signal", sig)
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/natefinch/lumberjack"
 	"github.com/nbnet/side-chain/core/service"
 	coreTypes "github.com/nbnet/side-chain/core/types"
@@ -14,6 +15,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 )
 
 var StartCmd = &cobra.Command{
@@ -28,8 +30,10 @@ func init() {
 
 // start initializes and starts a side chain node based on provided configurations.
 // It reads configuration from TOML files, generates a node instance with the configurations,
-// starts the node, and sets up a signal handler to gracefully stop the node upon receiving an interrupt signal.
-// Returns an error if configuration parsing fails, otherwise, it exits the program after a signal is received.
+// starts the node and the RPC service, and sets up a signal handler to gracefully stop both upon receiving an
+// interrupt signal: the RPC service drains its in-flight requests first, then the node stops.
+// Returns an error if configuration parsing or a pending upgrade fails, otherwise, it stops the node after a signal
+// is received or once the node halts at a scheduled upgrade height.
 func start(cmd *cobra.Command, args []string) error {
 
 	tdConfig := &cfg.Config{}
@@ -58,28 +62,61 @@ func start(cmd *cobra.Command, args []string) error {
 	}
 
 	db := service.NewDbService(nodeConfig.Db, l)
+	defer db.Close()
 
-	rpc := service.NewRpc(nodeConfig.Rpc, db, l, output)
-	rpc.Start()
+	// run the migration of the upgrade the previous binary halted on
+	if err := db.ApplyPendingUpgrade(); err != nil {
+		logger.Error("apply pending upgrade fail", "err", err)
+		return err
+	}
 
-	n := genNode(tdConfig, db, l)
+	abci := service.NewAbci(db, nodeConfig, l)
+	n := genNode(tdConfig, abci, l)
 
-	n.Start()
+	rpc := service.NewRpc(nodeConfig, db, abci.Pending, n.EventBus(), l, output)
 
+	if err := n.Start(); err != nil {
+		logger.Error("start node fail", "err", err)
+		return err
+	}
 	defer func() {
 		n.Stop()
 		n.Wait()
 	}()
 
+	if err := rpc.Start(); err != nil {
+		logger.Error("start rpc fail", "err", err)
+		return err
+	}
+	// drain the rpc before the node stops, requests waiting for a commit need new blocks
+	defer func() {
+		timeout := nodeConfig.Rpc.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = coreTypes.DefaultShutdownTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+		defer cancel()
+		if err := rpc.Stop(ctx); err != nil {
+			logger.Error("stop rpc fail", "err", err)
+		}
+	}()
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	<-c
-	os.Exit(0)
+	select {
+	case sig := <-c:
+		logger.Info("shutting down", "signal", sig)
+	case <-abci.Halted():
+		logger.Info("halted for upgrade, restart with the upgraded binary",
+			"name", nodeConfig.Upgrade.Name, "height", nodeConfig.Upgrade.Height)
+	case err := <-rpc.Errors():
+		logger.Error("rpc fail", "err", err)
+		return err
+	}
 	return nil
 }
 
-func genNode(config *cfg.Config, db coreTypes.Db, l tmLog.Logger) *node.Node {
-	abci := service.NewAbci(db, l)
+func genNode(config *cfg.Config, abci *service.Abci, l tmLog.Logger) *node.Node {
 	return coreTypes.NewTd(abci, config, l)
 }
 
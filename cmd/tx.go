@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/nbnet/side-chain/core/utils"
+	"github.com/spf13/cobra"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var TxCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Build, sign and broadcast txs in separate steps",
+	Long: `Build a tx on a machine connected to the node, sign it offline on the machine holding the key and
+broadcast the signed file from any machine:
+
+  sc tx build transfer --address 0x.. --to 0x.. --amount 1000 -o tx.json
+  sc tx sign tx.json --from alice -o signed.json    # offline
+  sc tx broadcast signed.json`,
+}
+
+var TxBuildCmd = &cobra.Command{
+	Use:   "build [mint|transfer|blob|manifest]",
+	Short: "Write an unsigned tx with its nonce and fee",
+	Long: `Write an unsigned tx of --address, or of the multisig account of --multisig, to a tx file. The nonce is read
+from the node unless --nonce is set, so with --nonce the tx is built offline. The fee in the file is the fee in
+wei DeliverTx charges for the tx.
+
+A blob holds the data of --file compressed with --codec. A manifest assembles the blobs of --parts into the object
+of --file, whose size and sha256 the manifest carries.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"mint", "transfer", "blob", "manifest"},
+	RunE:      buildTx,
+}
+
+var TxSignCmd = &cobra.Command{
+	Use:   "sign [tx file]",
+	Short: "Sign a tx file offline",
+	Long: `Sign a tx file with the key of --from, which has to be the sender of the tx. A tx of a multisig account gets
+the signature of the key added, see multisig combine.`,
+	Args: cobra.ExactArgs(1),
+	RunE: signTx,
+}
+
+var TxBroadcastCmd = &cobra.Command{
+	Use:   "broadcast [tx file]",
+	Short: "Submit a signed tx file and wait for its inclusion",
+	Args:  cobra.ExactArgs(1),
+	RunE:  broadcastTx,
+}
+
+var TxDecodeCmd = &cobra.Command{
+	Use:   "decode [tx]",
+	Short: "Print and verify a raw tx",
+	Long: `Print a tx and verify its signature. The tx is json, hex or base64 as in the txs of a block, given as argument
+or as file. Blob data is shortened unless --full is set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: decodeTx,
+}
+
+func init() {
+	TxBuildCmd.Flags().StringVar(&TxAddress, "address", "", "Sender of the tx")
+	TxBuildCmd.Flags().StringVar(&TxMultisig, "multisig", "", "Account file of the multisig account sending the tx")
+	TxBuildCmd.Flags().Int64Var(&TxNonce, "nonce", -1, "Nonce of the sender, read from the node if negative")
+	TxBuildCmd.Flags().StringVar(&TxAmount, "amount", "", "Amount of a mint or transfer in wei, or with a unit: 250eth, 1.5gwei")
+	TxBuildCmd.Flags().StringVar(&TxTo, "to", "", "Recipient of a transfer")
+	TxBuildCmd.Flags().StringVar(&TxFile, "file", "", "Data of a blob, or the object of a manifest")
+	TxBuildCmd.Flags().StringVar(&TxNamespace, "namespace", "", "Namespace of a blob or manifest")
+	TxBuildCmd.Flags().StringVar(&TxCodec, "codec", types.CodecAuto, "Codec of a blob: none, gzip, zstd, snappy or auto")
+	TxBuildCmd.Flags().StringVar(&TxPrev, "prev", "", "Hash of the previous part of a blob")
+	TxBuildCmd.Flags().StringVar(&TxParts, "parts", "", "Blob hashes of a manifest in order, separated by commas")
+	TxBuildCmd.Flags().StringVarP(&TxOutput, "output", "o", "", "Tx file, stdout if empty")
+	TxBuildCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+	TxBuildCmd.MarkFlagsOneRequired("address", "multisig")
+	TxBuildCmd.MarkFlagsMutuallyExclusive("address", "multisig")
+
+	addSigningFlags(TxSignCmd)
+	TxSignCmd.Flags().StringVarP(&TxOutput, "output", "o", "", "Signed tx file, stdout if empty")
+
+	TxBroadcastCmd.Flags().DurationVar(&TxWait, "wait", types.DefaultWaitCommitTimeout, "How long to wait for the inclusion, 0 to not wait")
+	TxBroadcastCmd.Flags().BoolVar(&TxDryRun, "dry-run", false, "Only run CheckTx on the node")
+	TxBroadcastCmd.Flags().StringVarP(&MintNodeRpc, "node-rpc", "n", DefaultMintNodeRpc, "RPC server address")
+
+	TxDecodeCmd.Flags().BoolVar(&TxFull, "full", false, "Print the whole blob data")
+	TxDecodeCmd.Flags().Uint64Var(&TxEthChainId, "eth-chain-id", types.DefaultEthChainId, "Chain id of transfers signed as ethereum txs")
+
+	TxCmd.AddCommand(TxBuildCmd, TxSignCmd, TxBroadcastCmd, TxDecodeCmd)
+}
+
+// txFile is the file of tx build and sign. The fee is informative, the chain computes it from the body.
+type txFile struct {
+	Tx  *types.Tx `json:"tx"`
+	Fee string    `json:"fee"`
+}
+
+func buildTx(cmd *cobra.Command, args []string) error {
+	var multisig *types.Multisig
+	var address common.Address
+	if len(TxMultisig) != 0 {
+		var err error
+		if multisig, address, err = loadMultisigAccount(TxMultisig); err != nil {
+			return err
+		}
+	} else {
+		if !common.IsHexAddress(TxAddress) {
+			return fmt.Errorf("invalid --address %q", TxAddress)
+		}
+		address = common.HexToAddress(TxAddress)
+	}
+
+	nonce := uint64(TxNonce)
+	if TxNonce < 0 {
+		var err error
+		if nonce, err = newClient(MintNodeRpc).Nonce(context.Background(), address); err != nil {
+			return err
+		}
+	}
+
+	var tx *types.Tx
+	switch args[0] {
+	case "mint", "transfer":
+		amount, err := parseAmount(TxAmount)
+		if err != nil {
+			return err
+		}
+		if args[0] == "mint" {
+			tx = client.UnsignedMintTx(address, nonce, amount)
+			break
+		}
+		if !common.IsHexAddress(TxTo) {
+			return fmt.Errorf("invalid --to %q", TxTo)
+		}
+		tx = client.UnsignedTransferTx(address, nonce, common.HexToAddress(TxTo), amount)
+
+	case "blob":
+		data, err := os.ReadFile(TxFile)
+		if err != nil {
+			return err
+		}
+		if !types.ValidCodec(TxCodec) {
+			return fmt.Errorf("unknown codec %q", TxCodec)
+		}
+		if tx, err = client.UnsignedBlobTx(address, nonce, data, TxNamespace, TxCodec, TxPrev); err != nil {
+			return err
+		}
+		body := tx.Body.(types.BlobBody)
+		if errName := body.ValidateMeta(); len(errName) != 0 {
+			return fmt.Errorf("invalid blob: %s", errName)
+		}
+
+	case "manifest":
+		data, err := os.ReadFile(TxFile)
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(data)
+		tx = client.UnsignedManifestTx(address, nonce, TxNamespace, strings.Split(TxParts, ","), int64(len(data)), digest[:])
+		body := tx.Body.(types.ManifestBody)
+		if errName := body.Validate(); len(errName) != 0 {
+			return fmt.Errorf("invalid manifest: %s", errName)
+		}
+
+	default:
+		return fmt.Errorf("can not build a %s tx", args[0])
+	}
+	tx.Multisig = multisig
+
+	return writeTx(TxOutput, tx)
+}
+
+func signTx(cmd *cobra.Command, args []string) error {
+	tx, err := readTx(args[0])
+	if err != nil {
+		return err
+	}
+	signer, err := newSigner()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if tx.Multisig != nil {
+		if err := client.SignMultisigTx(ctx, tx, signer); err != nil {
+			return err
+		}
+		return writeTx(TxOutput, tx)
+	}
+
+	sender, err := client.TxSender(tx)
+	if err != nil {
+		return err
+	}
+	if sender != signer.Address() {
+		return fmt.Errorf("tx of %s can not be signed by %s", sender, signer.Address())
+	}
+	if err := client.SignTxWith(ctx, tx, signer); err != nil {
+		return err
+	}
+
+	return writeTx(TxOutput, tx)
+}
+
+func broadcastTx(cmd *cobra.Command, args []string) error {
+	tx, err := readTx(args[0])
+	if err != nil {
+		return err
+	}
+	if err := client.VerifyTx(tx); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	c := newClient(MintNodeRpc)
+	ctx := context.Background()
+
+	if TxDryRun {
+		result, err := c.DryRunTx(ctx, tx)
+		if err != nil {
+			return err
+		}
+		logger.Info("tx checked", "hash", result.Hash, "fee", weiString(result.Fee))
+		return nil
+	}
+
+	result, err := c.BroadcastTx(ctx, tx, TxWait)
+	if err != nil {
+		return err
+	}
+	if TxWait == 0 {
+		logger.Info("tx broadcast", "hash", result.Hash)
+		return nil
+	}
+	receipt := result.Receipt
+	if receipt == nil || receipt.Status != types.TxStatusIncluded {
+		return fmt.Errorf("tx %s not included: %s", result.Hash, receiptLog(receipt))
+	}
+	logger.Info("tx included", "hash", result.Hash, "height", receipt.Height, "fee", weiString(receipt.Fee))
+	return nil
+}
+
+// decodedTx is the output of tx decode. Verified is "valid", "unsigned", which a blob compressed by the node is, or
+// the reason the signature does not verify.
+type decodedTx struct {
+	Hash     string    `json:"hash"`
+	Sender   string    `json:"sender"`
+	Fee      string    `json:"fee"`
+	Verified string    `json:"verified"`
+	Tx       *types.Tx `json:"tx"`
+}
+
+func decodeTx(cmd *cobra.Command, args []string) error {
+	raw := []byte(args[0])
+	if b, err := os.ReadFile(args[0]); err == nil {
+		raw = b
+	}
+	raw = bytes.TrimSpace(raw)
+
+	// a tx of a block is base64 in the tendermint rpc, hex in explorers
+	j := raw
+	if len(raw) != 0 && raw[0] != '{' {
+		var err error
+		if j, err = hex.DecodeString(utils.RemoveHexPrefix(string(raw))); err != nil {
+			if j, err = base64.StdEncoding.DecodeString(string(raw)); err != nil {
+				return fmt.Errorf("tx is neither json, hex nor base64")
+			}
+		}
+		j = bytes.TrimSpace(j)
+	}
+
+	// the tx of a tx file is hashed as broadcast submits it
+	var f struct {
+		Tx json.RawMessage `json:"tx"`
+	}
+	envelope := json.Unmarshal(j, &f) == nil && len(f.Tx) != 0
+	if envelope {
+		j = f.Tx
+	}
+
+	tx, err := client.DecodeTx(j)
+	if err != nil {
+		return err
+	}
+	if envelope {
+		j, _ = json.Marshal(tx)
+	}
+	sender, err := client.TxSender(tx)
+	if err != nil {
+		return err
+	}
+
+	decoded := decodedTx{Hash: types.TxHash(j), Sender: sender.String(), Fee: client.TxFee(tx).String(), Verified: "valid", Tx: tx}
+	transfer, isTransfer := tx.Body.(types.TransferBody)
+	switch {
+	case isTransfer && len(transfer.EthTx) != 0:
+		if err := transfer.VerifyEthTx(TxEthChainId); err != nil {
+			decoded.Verified = err.Error()
+		}
+	case !tx.Signed():
+		decoded.Verified = "unsigned"
+	default:
+		if err := client.VerifyTx(tx); err != nil {
+			decoded.Verified = err.Error()
+		}
+	}
+
+	if blob, ok := tx.Body.(types.BlobBody); ok && !TxFull && len(blob.Data) > 64 {
+		blob.Data = fmt.Sprintf("%s... (%d bytes)", blob.Data[:64], blob.Size())
+		tx.Body = blob
+	}
+	return writeJson("", decoded)
+}
+
+// amountUnits are the units of parseAmount and their decimals, gwei before wei and ether before eth.
+var amountUnits = []struct {
+	unit     string
+	decimals int
+}{{"gwei", 9}, {"wei", 0}, {"ether", 18}, {"eth", 18}}
+
+// parseAmount parses an amount in wei, decimal or 0x hex, or a decimal with a unit: 250eth, 1.5gwei or 1000wei.
+func parseAmount(s string) (*big.Int, error) {
+	if strings.HasPrefix(s, "0x") {
+		return hexutil.DecodeBig(s)
+	}
+
+	number, decimals := strings.ToLower(strings.TrimSpace(s)), 0
+	for _, u := range amountUnits {
+		if strings.HasSuffix(number, u.unit) {
+			number, decimals = strings.TrimSpace(strings.TrimSuffix(number, u.unit)), u.decimals
+			break
+		}
+	}
+
+	whole, fraction, _ := strings.Cut(number, ".")
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("invalid amount %q, below 1 wei", s)
+	}
+	amount, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", decimals-len(fraction)), 10)
+	if !ok || amount.Sign() < 0 || len(whole) == 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// readTx reads a tx file of tx build or sign, or the json of a tx.
+func readTx(file string) (*types.Tx, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f struct {
+		Tx json.RawMessage `json:"tx"`
+	}
+	if err := json.Unmarshal(b, &f); err == nil && len(f.Tx) != 0 {
+		b = f.Tx
+	}
+
+	tx, err := client.DecodeTx(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return tx, nil
+}
+
+// writeTx writes the tx file of tx to file, or to stdout if file is empty.
+func writeTx(file string, tx *types.Tx) error {
+	return writeJson(file, txFile{Tx: tx, Fee: client.TxFee(tx).String()})
+}
+
+// writeJson writes v as indented json to file, or to stdout if file is empty. An existing file is not overwritten.
+func writeJson(file string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if len(file) == 0 {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%s exists", file)
+	}
+	return os.WriteFile(file, b, 0644)
+}
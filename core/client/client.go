@@ -0,0 +1,507 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/nbnet/side-chain/core/utils"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a typed client of the node RPC service. Every call is bounded by the client timeout unless the
+// context already carries a deadline, idempotent queries are retried on network errors and unavailable nodes.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+	apiKey     string
+}
+
+type Option func(*Client)
+
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout bounds calls whose context has no deadline, zero disables the bound.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries sets how often a failed query is retried, the delay grows linearly with every attempt.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+// WithApiKey authenticates calls with an api key or jwt of a node with auth enabled.
+func WithApiKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// NewClient returns a client of the node RPC service at url, e.g. http://127.0.0.1:7074.
+func NewClient(url string, opts ...Option) *Client {
+	c := &Client{
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: http.DefaultClient,
+		timeout:    types.DefaultClientTimeout,
+		retries:    types.DefaultClientRetries,
+		retryDelay: types.DefaultClientRetryDelay,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Error is returned when the node rejects a call. Code is one of the types.Code* error codes, so callers
+// branch on e.g. types.CodeInvalidNonce, Message is the error name and Details the underlying error.
+type Error struct {
+	Status    int
+	Code      uint32
+	Codespace string
+	Message   string
+	Details   string
+	// RetryAfter is set when the node rate limited the call
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("node rpc: status %d, code %d", e.Status, e.Code)
+	if len(e.Message) != 0 {
+		msg += ": " + e.Message
+	}
+	if len(e.Details) != 0 {
+		msg += ": " + e.Details
+	}
+	return msg
+}
+
+// ErrorCode returns the error code of a rejected call, types.CodeOK if err is not an *Error.
+func ErrorCode(err error) uint32 {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return types.CodeOK
+}
+
+// IsNotFound reports whether err is a not found response, e.g. for a tx that is neither pending nor delivered.
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == types.CodeNotFound
+}
+
+// BroadcastResult is the CheckTx result of a submitted tx. Receipt is set when the call waited for the commit.
+type BroadcastResult struct {
+	Code    uint32   `json:"code"`
+	Data    string   `json:"data"`
+	Log     string   `json:"log"`
+	Hash    string   `json:"hash"`
+	Receipt *Receipt `json:"receipt,omitempty"`
+}
+
+// DryRunResult is the CheckTx result of a tx that was not broadcast. Fee is the fee the tx would be charged,
+// hex encoded wei.
+type DryRunResult struct {
+	Code      uint32 `json:"code"`
+	Log       string `json:"log"`
+	Hash      string `json:"hash"`
+	GasWanted int64  `json:"gas_wanted"`
+	Fee       string `json:"fee"`
+}
+
+// Receipt is the status of a tx, pending until it is delivered.
+type Receipt struct {
+	Status  string       `json:"status"`
+	Hash    string       `json:"hash"`
+	Height  int64        `json:"height"`
+	Index   uint32       `json:"index"`
+	Type    types.TxType `json:"type"`
+	GasUsed int64        `json:"gas_used"`
+	Fee     string       `json:"fee"`
+	TxCode  uint32       `json:"tx_code"`
+	Log     string       `json:"log"`
+}
+
+type TxsResult struct {
+	Txs   []*types.TxRecord `json:"txs"`
+	Total int               `json:"total"`
+	Page  int               `json:"page"`
+	Limit int               `json:"limit"`
+}
+
+// TxsQuery filters the txs of an address, zero values select all types, all heights and the default page.
+type TxsQuery struct {
+	Type       types.TxType
+	FromHeight int64
+	Page       int
+	Limit      int
+}
+
+// Blob is a blob tx as stored on chain, Data is still compressed.
+type Blob struct {
+	Hash      string `json:"hash"`
+	Height    int64  `json:"height"`
+	TxCode    uint32 `json:"tx_code"`
+	Address   string `json:"address"`
+	Namespace string `json:"namespace"`
+	// Data is compressed with Codec, the hex encoded payload of the tx
+	Data  string `json:"data"`
+	Codec string `json:"codec"`
+	// Proof and DataHash are set by ProvenBlob, see VerifyBlob
+	Proof    *tmTypes.TxProof `json:"proof,omitempty"`
+	DataHash string           `json:"data_hash,omitempty"`
+}
+
+// BlobsQuery selects the blobs of a namespace, a zero ToHeight is unbounded.
+type BlobsQuery struct {
+	FromHeight int64
+	ToHeight   int64
+	Page       int
+	Limit      int
+}
+
+func (c *Client) Balance(ctx context.Context, address common.Address) (*big.Int, error) {
+	var data struct {
+		Balance string `json:"balance"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/balance/"+address.String(), nil, nil, 0, &data); err != nil {
+		return nil, err
+	}
+
+	// balances are hex quantities, 0x0 for zero, nodes before the openapi spec encoded zero as 0x
+	balance, ok := new(big.Int).SetString("0"+utils.RemoveHexPrefix(data.Balance), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance %s", data.Balance)
+	}
+	return balance, nil
+}
+
+func (c *Client) Nonce(ctx context.Context, address common.Address) (uint64, error) {
+	var data struct {
+		Nonce uint64 `json:"nonce"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/nonce/"+address.String(), nil, nil, 0, &data); err != nil {
+		return 0, err
+	}
+	return data.Nonce, nil
+}
+
+// Account is the state of an account at a height.
+type Account struct {
+	Address common.Address `json:"address"`
+	Balance *big.Int       `json:"balance"`
+	Nonce   uint64         `json:"nonce"`
+	Height  int64          `json:"height"`
+}
+
+// Account returns the balance and nonce of address at height, at the last committed height if height is 0.
+func (c *Client) Account(ctx context.Context, address common.Address, height int64) (*Account, error) {
+	var balanceData struct {
+		Balance string `json:"balance"`
+		Height  int64  `json:"height"`
+	}
+	query := url.Values{}
+	if height != 0 {
+		query.Set("height", strconv.FormatInt(height, 10))
+	}
+	if err := c.call(ctx, http.MethodGet, "/balance/"+address.String(), query, nil, 0, &balanceData); err != nil {
+		return nil, err
+	}
+	balance, ok := new(big.Int).SetString("0"+utils.RemoveHexPrefix(balanceData.Balance), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance %s", balanceData.Balance)
+	}
+
+	// the nonce is read at the height of the balance, a block may have been committed in between
+	var nonceData struct {
+		Nonce uint64 `json:"nonce"`
+	}
+	query.Set("height", strconv.FormatInt(balanceData.Height, 10))
+	if err := c.call(ctx, http.MethodGet, "/nonce/"+address.String(), query, nil, 0, &nonceData); err != nil {
+		return nil, err
+	}
+
+	return &Account{Address: address, Balance: balance, Nonce: nonceData.Nonce, Height: balanceData.Height}, nil
+}
+
+// SubmitBlob compresses and submits a blob. With a non-zero wait the call returns once the blob is delivered,
+// or with an error carrying the pending receipt when wait expires.
+func (c *Client) SubmitBlob(ctx context.Context, body types.BlobBody, wait time.Duration) (*BroadcastResult, error) {
+	return c.submit(ctx, "/blob", body, wait)
+}
+
+// UploadBlob streams the blob read from data, the node compresses it while it is received. The default timeout
+// covers the whole upload, large blobs need a ctx with a deadline. See SubmitBlob for wait.
+func (c *Client) UploadBlob(ctx context.Context, address common.Address, namespace string, data io.Reader, wait time.Duration) (*BroadcastResult, error) {
+	query := url.Values{}
+	query.Set("address", address.String())
+	if len(namespace) != 0 {
+		query.Set("namespace", namespace)
+	}
+	return c.submitQuery(ctx, "/blob/upload", query, data, wait)
+}
+
+// BroadcastTx submits a signed tx, see SubmitBlob for wait.
+func (c *Client) BroadcastTx(ctx context.Context, tx *types.Tx, wait time.Duration) (*BroadcastResult, error) {
+	return c.submit(ctx, "/tx", tx, wait)
+}
+
+// DryRunTx runs CheckTx on a signed tx against the committed state without broadcasting it. A rejected tx
+// returns an *Error along with the result, which still carries the fee of a blob.
+func (c *Client) DryRunTx(ctx context.Context, tx *types.Tx) (*DryRunResult, error) {
+	query := url.Values{}
+	query.Set("dry_run", "true")
+
+	result := new(DryRunResult)
+	return result, c.call(ctx, http.MethodPost, "/tx", query, tx, 0, result)
+}
+
+func (c *Client) submit(ctx context.Context, path string, body interface{}, wait time.Duration) (*BroadcastResult, error) {
+	return c.submitQuery(ctx, path, url.Values{}, body, wait)
+}
+
+func (c *Client) submitQuery(ctx context.Context, path string, query url.Values, body interface{}, wait time.Duration) (*BroadcastResult, error) {
+	if wait > 0 {
+		query.Set("wait", "commit")
+		query.Set("timeout", wait.String())
+	}
+
+	result := new(BroadcastResult)
+	return result, c.call(ctx, http.MethodPost, path, query, body, wait, result)
+}
+
+func (c *Client) Blob(ctx context.Context, hash string) (*Blob, error) {
+	blob := new(Blob)
+	if err := c.call(ctx, http.MethodGet, "/blob/"+hash, nil, nil, 0, blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// ProvenBlob returns a blob with the merkle proof of its tx against the data hash of its block.
+func (c *Client) ProvenBlob(ctx context.Context, hash string) (*Blob, error) {
+	query := url.Values{}
+	query.Set("prove", "true")
+
+	blob := new(Blob)
+	if err := c.call(ctx, http.MethodGet, "/blob/"+hash, query, nil, 0, blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Blobs lists the blobs delivered in a namespace, oldest first.
+func (c *Client) Blobs(ctx context.Context, namespace string, q BlobsQuery) (*TxsResult, error) {
+	query := url.Values{}
+	if len(namespace) != 0 {
+		query.Set("namespace", namespace)
+	}
+	if q.FromHeight > 0 {
+		query.Set("from_height", strconv.FormatInt(q.FromHeight, 10))
+	}
+	if q.ToHeight > 0 {
+		query.Set("to_height", strconv.FormatInt(q.ToHeight, 10))
+	}
+	if q.Page > 0 {
+		query.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	result := new(TxsResult)
+	if err := c.call(ctx, http.MethodGet, "/blobs", query, nil, 0, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) Tx(ctx context.Context, hash string) (*Receipt, error) {
+	receipt := new(Receipt)
+	if err := c.call(ctx, http.MethodGet, "/tx/"+hash, nil, nil, 0, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func (c *Client) Txs(ctx context.Context, address common.Address, q TxsQuery) (*TxsResult, error) {
+	query := url.Values{}
+	query.Set("address", address.String())
+	if q.Type != 0 {
+		query.Set("type", q.Type.String())
+	}
+	if q.FromHeight > 0 {
+		query.Set("from_height", strconv.FormatInt(q.FromHeight, 10))
+	}
+	if q.Page > 0 {
+		query.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	result := new(TxsResult)
+	if err := c.call(ctx, http.MethodGet, "/txs", query, nil, 0, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// call sends a request and decodes the data of the response into out. The data is decoded for failed
+// responses too, so callers can inspect partial results like a pending receipt. wait extends the timeout.
+// A body implementing io.Reader is streamed as application/octet-stream, other bodies are sent as json.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body interface{}, wait time.Duration, out interface{}) error {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout+wait)
+		defer cancel()
+	}
+
+	var payload []byte
+	var stream io.Reader
+	contentType := "application/json"
+	if reader, ok := body.(io.Reader); ok {
+		stream, contentType = reader, "application/octet-stream"
+	} else if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := c.url + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+
+	// only queries are retried, a resubmitted tx could be delivered twice
+	attempts := 1
+	if method == http.MethodGet {
+		attempts += c.retries
+	}
+
+	var status int
+	var header http.Header
+	var respBody []byte
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryDelay * time.Duration(attempt)):
+			}
+		}
+
+		reader := stream
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		status, header, respBody, err = c.do(ctx, method, u, reader, contentType)
+		if err == nil && status != http.StatusBadGateway && status != http.StatusServiceUnavailable &&
+			status != http.StatusGatewayTimeout {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Error *types.RpcError `json:"error"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if len(respBody) != 0 {
+		if err := json.Unmarshal(respBody, &resp); err != nil && status < http.StatusBadRequest {
+			return fmt.Errorf("decode %s response: %w", path, err)
+		}
+	}
+
+	if len(resp.Data) != 0 && string(resp.Data) != "null" {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("decode %s response: %w", path, err)
+		}
+	}
+
+	return newError(status, header, resp.Error)
+}
+
+// newError returns the *Error of a response carrying rpcError or an error status, nil otherwise.
+func newError(status int, header http.Header, rpcError *types.RpcError) error {
+	var rpcErr *Error
+	if rpcError != nil {
+		rpcErr = &Error{
+			Status:    status,
+			Code:      rpcError.Code,
+			Codespace: rpcError.Codespace,
+			Message:   rpcError.Message,
+			Details:   rpcError.Details,
+		}
+	} else if status >= http.StatusBadRequest {
+		rpcErr = &Error{Status: status, Code: types.CodeInternal, Message: http.StatusText(status)}
+	} else {
+		return nil
+	}
+
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		rpcErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return rpcErr
+}
+
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	if len(c.apiKey) != 0 {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return header
+}
+
+func (c *Client) do(ctx context.Context, method, u string, reader io.Reader, contentType string) (int, http.Header, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	req.Header = c.header()
+	if reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return resp.StatusCode, resp.Header, respBody, nil
+}
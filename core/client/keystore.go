@@ -0,0 +1,189 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyExists   = errors.New("key already exists")
+)
+
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Keystore keeps named secp256k1 keys in a directory, one file per key in the scrypt encrypted keystore json
+// of go-ethereum, so the files can be used with geth and other ethereum tools.
+type Keystore struct {
+	dir     string
+	scryptN int
+	scryptP int
+}
+
+// KeyInfo describes a stored key, the address is read without decrypting the key.
+type KeyInfo struct {
+	Name    string         `json:"name"`
+	Address common.Address `json:"address"`
+}
+
+// NewKeystore returns the keystore in dir with the scrypt parameters new keys are encrypted with, usually
+// keystore.StandardScryptN and keystore.StandardScryptP.
+func NewKeystore(dir string, scryptN, scryptP int) *Keystore {
+	return &Keystore{dir: dir, scryptN: scryptN, scryptP: scryptP}
+}
+
+// Add generates a key named name, encrypted with passphrase.
+func (k *Keystore) Add(name, passphrase string) (common.Address, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return k.Import(name, privateKey, passphrase)
+}
+
+// Import stores privateKey as name, encrypted with passphrase. An existing key is never overwritten.
+func (k *Keystore) Import(name string, privateKey *ecdsa.PrivateKey, passphrase string) (common.Address, error) {
+	path, err := k.path(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return common.Address{}, err
+	}
+	key := &keystore.Key{Id: id, Address: crypto.PubkeyToAddress(privateKey.PublicKey), PrivateKey: privateKey}
+	j, err := keystore.EncryptKey(key, passphrase, k.scryptN, k.scryptP)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if err := os.MkdirAll(k.dir, 0700); err != nil {
+		return common.Address{}, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return common.Address{}, fmt.Errorf("%s: %w", name, ErrKeyExists)
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, err := file.Write(j); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return common.Address{}, err
+	}
+	return key.Address, file.Close()
+}
+
+// ImportJson stores a keystore json, e.g. exported from geth, as name. The key is decrypted with passphrase
+// and stored encrypted with it.
+func (k *Keystore) ImportJson(name string, keyJson []byte, passphrase string) (common.Address, error) {
+	key, err := keystore.DecryptKey(keyJson, passphrase)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return k.Import(name, key.PrivateKey, passphrase)
+}
+
+// Key decrypts the key named name.
+func (k *Keystore) Key(name, passphrase string) (*ecdsa.PrivateKey, error) {
+	j, err := k.Json(name)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(j, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return key.PrivateKey, nil
+}
+
+// Json returns the encrypted keystore json of the key named name.
+func (k *Keystore) Json(name string) ([]byte, error) {
+	path, err := k.path(name)
+	if err != nil {
+		return nil, err
+	}
+	j, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", name, ErrKeyNotFound)
+	}
+	return j, err
+}
+
+// Address returns the address of the key named name.
+func (k *Keystore) Address(name string) (common.Address, error) {
+	j, err := k.Json(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return keyAddress(j)
+}
+
+// List returns the stored keys sorted by name.
+func (k *Keystore) List() ([]KeyInfo, error) {
+	entries, err := os.ReadDir(k.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []KeyInfo
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok || !keyNamePattern.MatchString(name) {
+			continue
+		}
+		address, err := k.Address(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		keys = append(keys, KeyInfo{Name: name, Address: address})
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// Delete removes the key named name once passphrase decrypted it, so a key is not deleted by mistake.
+func (k *Keystore) Delete(name, passphrase string) error {
+	if _, err := k.Key(name, passphrase); err != nil {
+		return err
+	}
+	path, _ := k.path(name)
+	return os.Remove(path)
+}
+
+func (k *Keystore) path(name string) (string, error) {
+	if !keyNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid key name %q, use up to 64 letters, digits, '.', '_' or '-'", name)
+	}
+	return filepath.Join(k.dir, name+".json"), nil
+}
+
+func keyAddress(j []byte) (common.Address, error) {
+	var key struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(j, &key); err != nil {
+		return common.Address{}, err
+	}
+	if !common.IsHexAddress(key.Address) {
+		return common.Address{}, fmt.Errorf("invalid address %q", key.Address)
+	}
+	return common.HexToAddress(key.Address), nil
+}
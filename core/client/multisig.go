@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/types"
+	"slices"
+)
+
+// SignMultisigTx adds the signature of signer to a tx of a multisig account. The signers sign the same tx, e.g.
+// built with UnsignedMintTx for the multisig address, offline and each on its own copy, CombineMultisigTxs
+// merges the copies.
+func SignMultisigTx(ctx context.Context, tx *types.Tx, signer Signer) error {
+	if tx.Multisig == nil {
+		return errors.New("not a multisig tx")
+	}
+	index := tx.Multisig.Index(signer.Address())
+	if index < 0 {
+		return fmt.Errorf("%s is not a signer of the multisig", signer.Address())
+	}
+	if len(tx.Multisig.Signatures) != len(tx.Multisig.Signers) {
+		return fmt.Errorf("%d signatures for %d signers", len(tx.Multisig.Signatures), len(tx.Multisig.Signers))
+	}
+
+	digestHash, err := TxDigestHash(tx)
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Sign(ctx, digestHash)
+	if err != nil {
+		return err
+	}
+
+	tx.Multisig.Signatures[index] = common.Bytes2Hex(signature)
+	return nil
+}
+
+// CombineMultisigTxs merges the signatures of copies of the same multisig tx. It fails if the copies differ in
+// body or multisig, but not if the combined tx still lacks signatures, see VerifyTx.
+func CombineMultisigTxs(txs ...*types.Tx) (*types.Tx, error) {
+	if len(txs) == 0 {
+		return nil, errors.New("no txs to combine")
+	}
+
+	first := txs[0]
+	if first.Multisig == nil {
+		return nil, errors.New("not a multisig tx")
+	}
+	digestHash, err := TxDigestHash(first)
+	if err != nil {
+		return nil, err
+	}
+
+	multisig := *first.Multisig
+	multisig.Signatures = make([]string, len(multisig.Signers))
+	for i, tx := range txs {
+		if tx.Multisig == nil || tx.Multisig.Threshold != multisig.Threshold || !slices.Equal(tx.Multisig.Signers, multisig.Signers) {
+			return nil, fmt.Errorf("tx %d has another multisig", i)
+		}
+		if len(tx.Multisig.Signatures) != len(multisig.Signers) {
+			return nil, fmt.Errorf("tx %d has %d signatures for %d signers", i, len(tx.Multisig.Signatures), len(multisig.Signers))
+		}
+		if h, err := TxDigestHash(tx); err != nil || tx.Ty != first.Ty || common.BytesToHash(h) != common.BytesToHash(digestHash) {
+			return nil, fmt.Errorf("tx %d has another body", i)
+		}
+
+		for j, signature := range tx.Multisig.Signatures {
+			if len(signature) != 0 && len(multisig.Signatures[j]) == 0 {
+				multisig.Signatures[j] = signature
+			}
+		}
+	}
+
+	return &types.Tx{Ty: first.Ty, Multisig: &multisig, Body: first.Body}, nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nbnet/side-chain/core/types"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Object streams the object of the manifest with hash id to w and verifies its size and sha256 against the
+// manifest as served by the node. The default timeout covers the whole download, large objects need a ctx with a deadline.
+func (c *Client) Object(ctx context.Context, id string, w io.Writer) error {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/object/"+id, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = c.header()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error *types.RpcError `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+		return newError(resp.StatusCode, resp.Header, body.Error)
+	}
+
+	size, err := strconv.ParseInt(resp.Header.Get("X-Object-Size"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("object %s without size: %w", id, err)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), resp.Body)
+	if err != nil {
+		return fmt.Errorf("object %s: %w", id, err)
+	}
+	if n != size {
+		return fmt.Errorf("object %s: got %d bytes, manifest states %d", id, n, size)
+	}
+	if digest := hex.EncodeToString(hash.Sum(nil)); digest != resp.Header.Get("X-Object-Digest") {
+		return fmt.Errorf("object %s: digest %s, manifest states %s", id, digest, resp.Header.Get("X-Object-Digest"))
+	}
+	return nil
+}
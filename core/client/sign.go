@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/nbnet/side-chain/core/utils"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// LoadPrivateKey reads a hex encoded secp256k1 private key, with or without 0x prefix, from path.
+func LoadPrivateKey(path string) (*ecdsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.HexToECDSA(utils.RemoveHexPrefix(strings.TrimSpace(string(b))))
+}
+
+// NewMintTx returns a mint of amount wei to the address of privateKey, signed with it.
+func NewMintTx(privateKey *ecdsa.PrivateKey, nonce uint64, amount *big.Int) (*types.Tx, error) {
+	return NewMintTxWith(context.Background(), NewLocalSigner(privateKey), nonce, amount)
+}
+
+// NewMintTxWith returns a mint of amount wei to the address of signer, signed by it.
+func NewMintTxWith(ctx context.Context, signer Signer, nonce uint64, amount *big.Int) (*types.Tx, error) {
+	tx := UnsignedMintTx(signer.Address(), nonce, amount)
+	return tx, SignTxWith(ctx, tx, signer)
+}
+
+// UnsignedMintTx returns a mint of amount wei to address, to be signed e.g. by the signers of a multisig.
+func UnsignedMintTx(address common.Address, nonce uint64, amount *big.Int) *types.Tx {
+	return &types.Tx{
+		Ty: types.Mint,
+		Body: types.MintBody{
+			Nonce:   nonce,
+			Amount:  hexutil.EncodeBig(amount),
+			Address: address.String(),
+		},
+	}
+}
+
+// NewTransferTx returns a transfer of amount wei from the address of privateKey to to, signed with it.
+func NewTransferTx(privateKey *ecdsa.PrivateKey, nonce uint64, to common.Address, amount *big.Int) (*types.Tx, error) {
+	return NewTransferTxWith(context.Background(), NewLocalSigner(privateKey), nonce, to, amount)
+}
+
+// NewTransferTxWith returns a transfer of amount wei from the address of signer to to, signed by it.
+func NewTransferTxWith(ctx context.Context, signer Signer, nonce uint64, to common.Address, amount *big.Int) (*types.Tx, error) {
+	tx := UnsignedTransferTx(signer.Address(), nonce, to, amount)
+	return tx, SignTxWith(ctx, tx, signer)
+}
+
+// UnsignedTransferTx returns a transfer of amount wei from from to to.
+func UnsignedTransferTx(from common.Address, nonce uint64, to common.Address, amount *big.Int) *types.Tx {
+	return &types.Tx{
+		Ty: types.Transfer,
+		Body: types.TransferBody{
+			Nonce:  nonce,
+			From:   from.String(),
+			To:     to.String(),
+			Amount: hexutil.EncodeBig(amount),
+		},
+	}
+}
+
+// NewBlobTx returns a blob of data compressed with codec, signed with privateKey. Signed blobs are broadcast
+// with BroadcastTx and use up a nonce, prev links the blob to the previous part of a split blob.
+func NewBlobTx(privateKey *ecdsa.PrivateKey, nonce uint64, data []byte, namespace, codec, prev string) (*types.Tx, error) {
+	return NewBlobTxWith(context.Background(), NewLocalSigner(privateKey), nonce, data, namespace, codec, prev)
+}
+
+// NewBlobTxWith returns a blob of data compressed with codec, signed by signer, see NewBlobTx.
+func NewBlobTxWith(ctx context.Context, signer Signer, nonce uint64, data []byte, namespace, codec, prev string) (*types.Tx, error) {
+	tx, err := UnsignedBlobTx(signer.Address(), nonce, data, namespace, codec, prev)
+	if err != nil {
+		return nil, err
+	}
+	return tx, SignTxWith(ctx, tx, signer)
+}
+
+// UnsignedBlobTx returns a blob of data of address compressed with codec.
+func UnsignedBlobTx(address common.Address, nonce uint64, data []byte, namespace, codec, prev string) (*types.Tx, error) {
+	tx := &types.Tx{}
+	body := types.BlobBody{
+		Data:      hex.EncodeToString(data),
+		Address:   address.String(),
+		Namespace: namespace,
+		Nonce:     nonce,
+		Prev:      prev,
+	}
+	if err := tx.GenCompressBlobTx(body, codec); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// NewManifestTx returns a manifest assembling the object of the blobs with the tx hashes parts, signed with
+// privateKey. The blobs have to be delivered by the address of privateKey before the manifest.
+func NewManifestTx(privateKey *ecdsa.PrivateKey, nonce uint64, namespace string, parts []string, size int64, digest []byte) (*types.Tx, error) {
+	return NewManifestTxWith(context.Background(), NewLocalSigner(privateKey), nonce, namespace, parts, size, digest)
+}
+
+// NewManifestTxWith returns a manifest signed by signer, see NewManifestTx.
+func NewManifestTxWith(ctx context.Context, signer Signer, nonce uint64, namespace string, parts []string, size int64, digest []byte) (*types.Tx, error) {
+	tx := UnsignedManifestTx(signer.Address(), nonce, namespace, parts, size, digest)
+	return tx, SignTxWith(ctx, tx, signer)
+}
+
+// UnsignedManifestTx returns a manifest of address, see NewManifestTx.
+func UnsignedManifestTx(address common.Address, nonce uint64, namespace string, parts []string, size int64, digest []byte) *types.Tx {
+	return &types.Tx{
+		Ty: types.Manifest,
+		Body: types.ManifestBody{
+			Nonce:     nonce,
+			Address:   address.String(),
+			Namespace: namespace,
+			Parts:     parts,
+			Size:      size,
+			Digest:    hex.EncodeToString(digest),
+		},
+	}
+}
+
+// SignTx signs the digest hash of a mint, transfer, blob or manifest body.
+func SignTx(tx *types.Tx, privateKey *ecdsa.PrivateKey) error {
+	return SignTxWith(context.Background(), tx, NewLocalSigner(privateKey))
+}
+
+// SignTxWith signs the digest hash of a mint, transfer, blob or manifest body with signer.
+func SignTxWith(ctx context.Context, tx *types.Tx, signer Signer) error {
+	digestHash, err := TxDigestHash(tx)
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.Sign(ctx, digestHash)
+	if err != nil {
+		return err
+	}
+
+	tx.Signature = common.Bytes2Hex(signature)
+	return nil
+}
+
+// TxDigestHash returns the hash signed for a mint, transfer, blob or manifest body.
+func TxDigestHash(tx *types.Tx) ([]byte, error) {
+	switch body := tx.Body.(type) {
+	case types.MintBody:
+		return body.DigestHash()
+	case *types.MintBody:
+		return body.DigestHash()
+	case types.TransferBody:
+		return body.DigestHash()
+	case *types.TransferBody:
+		return body.DigestHash()
+	case types.BlobBody:
+		return body.DigestHash()
+	case *types.BlobBody:
+		return body.DigestHash()
+	case types.ManifestBody:
+		return body.DigestHash()
+	case *types.ManifestBody:
+		return body.DigestHash()
+	default:
+		return nil, fmt.Errorf("can not sign %s tx", tx.Ty)
+	}
+}
+
+// TxSender returns the address a mint, transfer, blob or manifest is sent by, the address that signs it.
+func TxSender(tx *types.Tx) (common.Address, error) {
+	switch body := tx.Body.(type) {
+	case types.MintBody:
+		return common.HexToAddress(body.Address), nil
+	case *types.MintBody:
+		return common.HexToAddress(body.Address), nil
+	case types.TransferBody:
+		return common.HexToAddress(body.From), nil
+	case *types.TransferBody:
+		return common.HexToAddress(body.From), nil
+	case types.BlobBody:
+		return common.HexToAddress(body.Address), nil
+	case *types.BlobBody:
+		return common.HexToAddress(body.Address), nil
+	case types.ManifestBody:
+		return common.HexToAddress(body.Address), nil
+	case *types.ManifestBody:
+		return common.HexToAddress(body.Address), nil
+	default:
+		return common.Address{}, fmt.Errorf("no sender of %s tx", tx.Ty)
+	}
+}
+
+// TxFee returns the fee in wei DeliverTx charges for a tx, blobs and manifests pay for their bytes.
+func TxFee(tx *types.Tx) *big.Int {
+	switch body := tx.Body.(type) {
+	case types.BlobBody:
+		return body.Gas()
+	case *types.BlobBody:
+		return body.Gas()
+	case types.ManifestBody:
+		return body.Gas()
+	case *types.ManifestBody:
+		return body.Gas()
+	default:
+		return new(big.Int)
+	}
+}
+
+// VerifyTx checks the signature of a tx, or the signatures of a multisig tx, like CheckTx does.
+func VerifyTx(tx *types.Tx) error {
+	digestHash, err := TxDigestHash(tx)
+	if err != nil {
+		return err
+	}
+	sender, err := TxSender(tx)
+	if err != nil {
+		return err
+	}
+	return tx.VerifySignature(sender, digestHash)
+}
+
+// DecodeTx decodes the json of a tx with the body of its type, e.g. types.MintBody, so it can be signed.
+func DecodeTx(j []byte) (*types.Tx, error) {
+	var raw struct {
+		Ty        types.TxType    `json:"type"`
+		Signature string          `json:"signature"`
+		Multisig  *types.Multisig `json:"multisig"`
+		Body      json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(j, &raw); err != nil {
+		return nil, err
+	}
+
+	tx := &types.Tx{Ty: raw.Ty, Signature: raw.Signature, Multisig: raw.Multisig}
+	var err error
+	switch raw.Ty {
+	case types.Mint:
+		var body types.MintBody
+		err = json.Unmarshal(raw.Body, &body)
+		tx.Body = body
+	case types.Transfer:
+		var body types.TransferBody
+		err = json.Unmarshal(raw.Body, &body)
+		tx.Body = body
+	case types.Blob:
+		var body types.BlobBody
+		err = json.Unmarshal(raw.Body, &body)
+		tx.Body = body
+	case types.Manifest:
+		var body types.ManifestBody
+		err = json.Unmarshal(raw.Body, &body)
+		tx.Body = body
+	default:
+		return nil, fmt.Errorf("unknown tx type %s", raw.Ty)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s body: %w", raw.Ty, err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// SignerWeb3Signer signs with the eth1 sign endpoint of Web3Signer.
+	SignerWeb3Signer = "web3signer"
+	// SignerClef signs with the account_signData JSON-RPC method of Clef.
+	SignerClef = "clef"
+)
+
+// textPrefix is the EIP-191 prefix of a signed 32 byte digest hash.
+var textPrefix = []byte("\x19Ethereum Signed Message:\n32")
+
+// Signer signs the digest hash of tx bodies for an address. A LocalSigner signs with a key in memory, a
+// RemoteSigner asks a separate process holding the key, so the key never is on the machine submitting txs.
+type Signer interface {
+	Address() common.Address
+	// Sign returns the 65 byte [R || S || V] signature, V is 0 or 1, over digestHash or over its EIP-191 text
+	// hash. The chain accepts both, see types.Tx.VerifySignature.
+	Sign(ctx context.Context, digestHash []byte) ([]byte, error)
+}
+
+// LocalSigner signs with a private key in memory.
+type LocalSigner struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+func NewLocalSigner(privateKey *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{privateKey: privateKey}
+}
+
+// NewKeystoreSigner returns a signer of the key name in ks, decrypted with passphrase.
+func NewKeystoreSigner(ks *Keystore, name, passphrase string) (*LocalSigner, error) {
+	privateKey, err := ks.Key(name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalSigner(privateKey), nil
+}
+
+func (s *LocalSigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.privateKey.PublicKey)
+}
+
+func (s *LocalSigner) Sign(_ context.Context, digestHash []byte) ([]byte, error) {
+	return crypto.Sign(digestHash, s.privateKey)
+}
+
+// RemoteSigner signs over HTTP with a Web3Signer or Clef compatible signer. Neither signs raw hashes, so the
+// EIP-191 text hash of the digest hash is signed. Every signature is checked to be made by the address before
+// it is returned.
+type RemoteSigner struct {
+	protocol   string
+	url        string
+	address    common.Address
+	identifier string
+	httpClient *http.Client
+}
+
+type SignerOption func(*RemoteSigner)
+
+func WithSignerHttpClient(httpClient *http.Client) SignerOption {
+	return func(s *RemoteSigner) {
+		s.httpClient = httpClient
+	}
+}
+
+// WithSignerIdentifier sets the key identifier of Web3Signer, the address by default.
+func WithSignerIdentifier(identifier string) SignerOption {
+	return func(s *RemoteSigner) {
+		s.identifier = identifier
+	}
+}
+
+// NewRemoteSigner returns a signer of address with the SignerWeb3Signer or SignerClef protocol at url, e.g.
+// http://127.0.0.1:9000 for Web3Signer or http://127.0.0.1:8550 for Clef.
+func NewRemoteSigner(protocol, url string, address common.Address, opts ...SignerOption) (*RemoteSigner, error) {
+	if protocol != SignerWeb3Signer && protocol != SignerClef {
+		return nil, fmt.Errorf("unknown signer protocol %q", protocol)
+	}
+	if address == (common.Address{}) {
+		return nil, errors.New("remote signer without address")
+	}
+
+	s := &RemoteSigner{
+		protocol:   protocol,
+		url:        strings.TrimSuffix(url, "/"),
+		address:    address,
+		identifier: address.Hex(),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) Sign(ctx context.Context, digestHash []byte) ([]byte, error) {
+	var signature []byte
+	var err error
+	if s.protocol == SignerClef {
+		signature, err = s.signClef(ctx, digestHash)
+	} else {
+		signature, err = s.signWeb3Signer(ctx, digestHash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.protocol, err)
+	}
+
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("%s: signature of %d bytes", s.protocol, len(signature))
+	}
+	// both return V as 27 or 28
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubkey, err := crypto.SigToPub(accounts.TextHash(digestHash), signature)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.protocol, err)
+	}
+	if address := crypto.PubkeyToAddress(*pubkey); address != s.address {
+		return nil, fmt.Errorf("%s: signed by %s instead of %s", s.protocol, address, s.address)
+	}
+	return signature, nil
+}
+
+// signWeb3Signer signs with POST /api/v1/eth1/sign/{identifier}, which signs the keccak256 hash of the data.
+// The data is the EIP-191 message of digestHash, so the signed hash is its text hash.
+func (s *RemoteSigner) signWeb3Signer(ctx context.Context, digestHash []byte) ([]byte, error) {
+	message := append(append([]byte{}, textPrefix...), digestHash...)
+	body, _ := json.Marshal(map[string]string{"data": hexutil.Encode(message)})
+
+	resp, err := s.post(ctx, s.url+"/api/v1/eth1/sign/"+s.identifier, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the signature is returned as text, some versions quote it
+	return hexutil.Decode(strings.Trim(strings.TrimSpace(string(resp)), `"`))
+}
+
+// signClef signs with the account_signData method and the text/plain content type, which signs the text hash.
+func (s *RemoteSigner) signClef(ctx context.Context, digestHash []byte) ([]byte, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "account_signData",
+		"params":  []string{accounts.MimetypeTextPlain, s.address.Hex(), hexutil.Encode(digestHash)},
+	})
+
+	resp, err := s.post(ctx, s.url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result hexutil.Bytes `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("code %d: %s", result.Error.Code, result.Error.Message)
+	}
+	return result.Result, nil
+}
+
+func (s *RemoteSigner) post(ctx context.Context, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// a signature or error fits easily
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/nbnet/side-chain/core/types"
+	"strings"
+)
+
+// StreamMessage is a message of a subscription, Data is decoded with Block, Blob or Balance depending on the topic.
+type StreamMessage struct {
+	Id     string          `json:"id"`
+	Topic  string          `json:"topic"`
+	Height int64           `json:"height"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func (m *StreamMessage) Block() (*types.StreamBlock, error) {
+	block := new(types.StreamBlock)
+	return block, json.Unmarshal(m.Data, block)
+}
+
+func (m *StreamMessage) Blob() (*types.TxRecord, error) {
+	record := new(types.TxRecord)
+	return record, json.Unmarshal(m.Data, record)
+}
+
+func (m *StreamMessage) Balance() (*types.StreamBalance, error) {
+	balance := new(types.StreamBalance)
+	return balance, json.Unmarshal(m.Data, balance)
+}
+
+// Subscribe opens a websocket connection for req and delivers its messages until ctx is done or the
+// connection fails, then the channel is closed. To resume, subscribe again with FromHeight set to the
+// height of the last message received plus one.
+func (c *Client) Subscribe(ctx context.Context, req types.StreamRequest) (<-chan *StreamMessage, error) {
+	u := c.url + "/ws"
+	if strings.HasPrefix(u, "http") {
+		u = "ws" + strings.TrimPrefix(u, "http")
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u, c.header())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.WriteJSON(&req); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	out := make(chan *StreamMessage, types.DefaultStreamBuffer)
+	// done ends the goroutine closing the connection on ctx when the server closes the stream first
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		defer close(done)
+		defer conn.Close()
+
+		for {
+			msg := new(StreamMessage)
+			if err := conn.ReadJSON(msg); err != nil {
+				return
+			}
+
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,227 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/websocket"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestClient checks the typed responses, the retries of queries and the errors of rejected calls.
+func TestClient(t *testing.T) {
+
+	address := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	nonceCalls := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/balance/"+address.String(), func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.NewRpcResp(nil, types.NewRpcBalanceData(nil, 0)))
+	})
+	mux.HandleFunc("/nonce/"+address.String(), func(w http.ResponseWriter, r *http.Request) {
+		nonceCalls++
+		if nonceCalls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// above 2^53, lost when decoded as float64
+		nonce := new(big.Int).SetUint64(1<<60 + 1)
+		_ = json.NewEncoder(w).Encode(types.NewRpcResp(nil, types.NewRpcNonceData(nonce, 0)))
+	})
+	mux.HandleFunc("/tx/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		rpcErr := types.NewRpcError(types.ErrTxNotFound, nil)
+		_ = json.NewEncoder(w).Encode(types.NewRpcResp(rpcErr, types.NewRpcTxData(nil, "", 1)))
+	})
+	mux.HandleFunc("/tx", func(w http.ResponseWriter, r *http.Request) {
+		data := types.NewRpcBlobData(0, nil)
+		data["code"] = types.CodeInvalidNonce
+		data["log"] = types.ErrNonceNotMatch
+		rpcErr := types.NewRpcTxError(types.CodeInvalidNonce, types.Codespace, types.ErrNonceNotMatch)
+		_ = json.NewEncoder(w).Encode(types.NewRpcResp(rpcErr, data))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := client.NewClient(server.URL, client.WithRetries(2, time.Millisecond))
+	ctx := context.Background()
+
+	balance, err := c.Balance(ctx, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Sign() != 0 {
+		t.Fatalf("expected zero balance, got %s", balance)
+	}
+
+	nonce, err := c.Nonce(ctx, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 1<<60+1 || nonceCalls != 2 {
+		t.Fatalf("expected nonce %d after a retry, got %d after %d calls", uint64(1<<60+1), nonce, nonceCalls)
+	}
+
+	if _, err := c.Tx(ctx, "ABCD"); !client.IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := client.NewMintTx(privateKey, 0, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.BroadcastTx(ctx, tx, 0); client.ErrorCode(err) != types.CodeInvalidNonce {
+		t.Fatalf("expected rejected tx, got %v", err)
+	}
+}
+
+// TestSignTx checks that signed mints and transfers verify against the signer address.
+func TestSignTx(t *testing.T) {
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	mintTx, err := client.NewMintTx(privateKey, 3, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintBody := mintTx.Body.(types.MintBody)
+	digestHash, err := mintBody.DigestHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mintTx.VerifySignature(address, digestHash); err != nil {
+		t.Fatal(err)
+	}
+
+	transferTx, err := client.NewTransferTx(privateKey, 4, common.HexToAddress("0x01"), big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferBody := transferTx.Body.(types.TransferBody)
+	digestHash, err = transferBody.DigestHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := transferTx.VerifySignature(address, digestHash); err != nil {
+		t.Fatal(err)
+	}
+
+	blobTx, err := client.NewBlobTx(privateKey, 5, []byte("rollup batch"), "rollup", types.CodecGzip, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobBody := blobTx.Body.(types.BlobBody)
+	digestHash, err = blobBody.DigestHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blobTx.VerifySignature(address, digestHash); err != nil || blobBody.Nonce != 5 {
+		t.Fatal("blob not signed", err)
+	}
+
+	if err := client.SignTx(&types.Tx{Ty: types.UnKnown, Body: "upgrade"}, privateKey); err == nil {
+		t.Fatal("expected unknown bodies not to be signed")
+	}
+}
+
+// TestOfflineTx checks that an unsigned tx survives its json file, is signed offline and keeps its fee.
+func TestOfflineTx(t *testing.T) {
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	blobTx, err := client.UnsignedBlobTx(address, 2, []byte("rollup batch"), "rollup", types.CodecNone, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := []*types.Tx{
+		client.UnsignedMintTx(address, 0, big.NewInt(1000)),
+		client.UnsignedTransferTx(address, 1, common.HexToAddress("0x01"), big.NewInt(10)),
+		blobTx,
+		client.UnsignedManifestTx(address, 3, "rollup", []string{"AB", "CD"}, 24, make([]byte, 32)),
+	}
+	fees := []int64{0, 0, int64(len("rollup batch")) * 2 * 10, 2 * 64 * 10}
+
+	for i, tx := range unsigned {
+		j, _ := json.Marshal(tx)
+		decoded, err := client.DecodeTx(j)
+		if err != nil {
+			t.Fatal(tx.Ty, err)
+		}
+		if sender, err := client.TxSender(decoded); err != nil || sender != address {
+			t.Fatalf("%s: sender %s %v", tx.Ty, sender, err)
+		}
+		if fee := client.TxFee(decoded); fee.Int64() != fees[i] {
+			t.Fatalf("%s: expected fee %d, got %d", tx.Ty, fees[i], fee)
+		}
+		if err := client.VerifyTx(decoded); err == nil {
+			t.Fatalf("%s: unsigned tx verified", tx.Ty)
+		}
+		if err := client.SignTx(decoded, privateKey); err != nil {
+			t.Fatal(tx.Ty, err)
+		}
+		if err := client.VerifyTx(decoded); err != nil {
+			t.Fatal(tx.Ty, err)
+		}
+	}
+}
+
+// TestSubscribeServerClose checks that a subscription closed by the server releases its goroutines, also
+// when its ctx is never done.
+func TestSubscribeServerClose(t *testing.T) {
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var req types.StreamRequest
+		_ = conn.ReadJSON(&req)
+		_ = conn.WriteJSON(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Error: types.ErrStreamClosed})
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	c := client.NewClient(server.URL)
+	before := runtime.NumGoroutine()
+
+	messages, err := c.Subscribe(context.Background(), types.StreamRequest{Id: "1", Topic: types.TopicBlocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for msg := range messages {
+		if msg.Error != types.ErrStreamClosed {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
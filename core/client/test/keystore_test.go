@@ -0,0 +1,64 @@
+package test
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"testing"
+)
+
+// TestKeystore checks that named keys are stored encrypted, compatible with the go-ethereum keystore, and that
+// they are neither overwritten nor deleted without their passphrase.
+func TestKeystore(t *testing.T) {
+
+	ks := client.NewKeystore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+
+	alice, err := ks.Add("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	dev, err := ks.Import("dev", privateKey, "secret")
+	if err != nil || dev != crypto.PubkeyToAddress(privateKey.PublicKey) {
+		t.Fatal(err)
+	}
+	if _, err := ks.Import("dev", privateKey, "other"); !errors.Is(err, client.ErrKeyExists) {
+		t.Fatalf("expected %v, got %v", client.ErrKeyExists, err)
+	}
+	if _, err := ks.Add("../dev", "secret"); err == nil {
+		t.Fatal("expected error for a path as name")
+	}
+
+	keys, err := ks.List()
+	if err != nil || len(keys) != 2 || keys[0].Name != "alice" || keys[0].Address != alice || keys[1].Address != dev {
+		t.Fatalf("unexpected keys %v %v", keys, err)
+	}
+
+	// the stored json is a go-ethereum keystore key
+	j, err := ks.Json("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := keystore.DecryptKey(j, "secret")
+	if err != nil || !key.PrivateKey.Equal(privateKey) {
+		t.Fatalf("not a keystore key: %v", err)
+	}
+	if _, err := ks.ImportJson("copy", j, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.Key("dev", "wrong"); !errors.Is(err, keystore.ErrDecrypt) {
+		t.Fatalf("expected %v, got %v", keystore.ErrDecrypt, err)
+	}
+	if err := ks.Delete("dev", "wrong"); err == nil {
+		t.Fatal("deleted with a wrong passphrase")
+	}
+	if err := ks.Delete("dev", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Key("dev", "secret"); !errors.Is(err, client.ErrKeyNotFound) {
+		t.Fatalf("expected %v, got %v", client.ErrKeyNotFound, err)
+	}
+}
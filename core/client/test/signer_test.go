@@ -0,0 +1,63 @@
+package test
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRemoteSigner checks that txs signed by Web3Signer and Clef compatible signers verify like locally signed
+// txs, and that signatures of another key are rejected.
+func TestRemoteSigner(t *testing.T) {
+
+	privateKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	other, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	server := httptest.NewServer(newStubSigner(privateKey, other))
+	defer server.Close()
+
+	web3Signer, err := client.NewRemoteSigner(client.SignerWeb3Signer, server.URL, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clef, _ := client.NewRemoteSigner(client.SignerClef, server.URL, address)
+
+	ctx := context.Background()
+	for _, signer := range []client.Signer{client.NewLocalSigner(privateKey), web3Signer, clef} {
+		tx, err := client.NewMintTxWith(ctx, signer, 3, big.NewInt(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := tx.Body.(types.MintBody)
+		digestHash, _ := body.DigestHash()
+		if err := tx.VerifySignature(address, digestHash); err != nil {
+			t.Fatalf("%T: %v", signer, err)
+		}
+		if err := tx.VerifySignature(crypto.PubkeyToAddress(other.PublicKey), digestHash); err == nil {
+			t.Fatalf("%T: verified for another address", signer)
+		}
+	}
+
+	unknown, _ := client.NewRemoteSigner(client.SignerClef, server.URL, common.HexToAddress("0x01"))
+	if _, err := client.NewMintTxWith(ctx, unknown, 0, big.NewInt(1)); err == nil || !strings.Contains(err.Error(), "unknown account") {
+		t.Fatalf("expected unknown account, got %v", err)
+	}
+
+	// a signer answering with another key
+	swapped, _ := client.NewRemoteSigner(client.SignerWeb3Signer, server.URL, address,
+		client.WithSignerIdentifier(crypto.PubkeyToAddress(other.PublicKey).Hex()))
+	if _, err := client.NewMintTxWith(ctx, swapped, 0, big.NewInt(1)); err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Fatalf("expected signature of another key to be rejected, got %v", err)
+	}
+
+	if _, err := client.NewRemoteSigner("kms", server.URL, address); err == nil {
+		t.Fatal("expected error for an unknown protocol")
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"net/http"
+	"strings"
+)
+
+// stubSigner answers the Web3Signer eth1 sign endpoint and the Clef account_signData method with keys in
+// memory, it stands in for a remote signer.
+type stubSigner struct {
+	keys map[common.Address]*ecdsa.PrivateKey
+}
+
+func newStubSigner(privateKeys ...*ecdsa.PrivateKey) *stubSigner {
+	s := &stubSigner{keys: make(map[common.Address]*ecdsa.PrivateKey)}
+	for _, privateKey := range privateKeys {
+		s.keys[crypto.PubkeyToAddress(privateKey.PublicKey)] = privateKey
+	}
+	return s
+}
+
+func (s *stubSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if identifier, ok := strings.CutPrefix(r.URL.Path, "/api/v1/eth1/sign/"); ok {
+		s.serveWeb3Signer(w, r, identifier)
+		return
+	}
+	s.serveClef(w, r)
+}
+
+func (s *stubSigner) serveWeb3Signer(w http.ResponseWriter, r *http.Request, identifier string) {
+	if !common.IsHexAddress(identifier) {
+		http.Error(w, "unknown identifier", http.StatusNotFound)
+		return
+	}
+	privateKey, ok := s.keys[common.HexToAddress(identifier)]
+	if !ok {
+		http.Error(w, "unknown identifier", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Data hexutil.Bytes `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signature, err := signText(crypto.Keccak256(req.Data), privateKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(hexutil.Encode(signature)))
+}
+
+func (s *stubSigner) serveClef(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []string        `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.Id}
+	signature, err := s.signData(req.Method, req.Params)
+	if err != nil {
+		resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+	} else {
+		resp["result"] = hexutil.Encode(signature)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *stubSigner) signData(method string, params []string) ([]byte, error) {
+	if method != "account_signData" {
+		return nil, fmt.Errorf("method %s not supported", method)
+	}
+	if len(params) != 3 || params[0] != accounts.MimetypeTextPlain {
+		return nil, fmt.Errorf("only %s data is signed", accounts.MimetypeTextPlain)
+	}
+	privateKey, ok := s.keys[common.HexToAddress(params[1])]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %s", params[1])
+	}
+	data, err := hexutil.Decode(params[2])
+	if err != nil {
+		return nil, err
+	}
+	return signText(accounts.TextHash(data), privateKey)
+}
+
+// signText signs hash with V as 27 or 28, like Web3Signer and Clef.
+func signText(hash []byte, privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	signature, err := crypto.Sign(hash, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/types"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmVersion "github.com/tendermint/tendermint/proto/tendermint/version"
+	tmHttp "github.com/tendermint/tendermint/rpc/client/http"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcTypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestVerifyBlob checks that a blob is verified against its tx hash and the data hash of its block.
+func TestVerifyBlob(t *testing.T) {
+
+	data := bytes.Repeat([]byte("rollup batch "), 100)
+	tx := types.Tx{}
+	body := types.BlobBody{Data: hex.EncodeToString(data), Address: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"}
+	if err := tx.GenCompressBlobTx(body, types.CodecZstd); err != nil {
+		t.Fatal(err)
+	}
+	blobTx, _ := json.Marshal(tx)
+
+	txs := tmTypes.Txs{tmTypes.Tx(`{"type":"mint"}`), blobTx, tmTypes.Tx(`{"type":"transfer"}`)}
+	hash := types.TxHash(blobTx)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stored := tx.Body.(types.BlobBody)
+		result := types.NewRpcGetBlobData(&stored, 7, 0, 0)
+		result["hash"] = hash
+		result["proof"] = txs.Proof(1)
+		result["data_hash"] = hex.EncodeToString(txs.Hash())
+		_ = json.NewEncoder(w).Encode(types.NewRpcResp(nil, result))
+	}))
+	defer server.Close()
+
+	blob, err := client.NewClient(server.URL).ProvenBlob(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := client.VerifyBlob(blob, hash, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) || blob.Codec != types.CodecZstd {
+		t.Fatal("verified blob differs")
+	}
+
+	if err := client.VerifyBlob(blob, types.TxHash(txs[0]), &out); err == nil {
+		t.Fatal("expected error for another tx hash")
+	}
+
+	tampered := *blob
+	tampered.DataHash = hex.EncodeToString(tmTypes.Txs{blobTx}.Hash())
+	if err := client.VerifyBlob(&tampered, hash, &out); err == nil {
+		t.Fatal("expected error for another block")
+	}
+
+	proof := *blob.Proof
+	proof.Data = append(tmTypes.Tx{}, blobTx[:len(blobTx)-2]...)
+	tampered.DataHash, tampered.Proof = blob.DataHash, &proof
+	if err := client.VerifyBlob(&tampered, hash, &out); err == nil {
+		t.Fatal("expected error for altered tx")
+	}
+}
+
+// TestTrust checks that the data hash of a blob is verified against a header signed by the genesis validators.
+func TestTrust(t *testing.T) {
+
+	tx := types.Tx{}
+	if err := tx.GenCompressBlobTx(types.BlobBody{Data: "0102", Address: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"}, types.CodecNone); err != nil {
+		t.Fatal(err)
+	}
+	blobTx, _ := json.Marshal(tx)
+	txs := tmTypes.Txs{blobTx}
+	hash := types.TxHash(blobTx)
+	blob := &client.Blob{Hash: hash, Height: 7, Proof: new(tmTypes.TxProof), DataHash: hex.EncodeToString(txs.Hash())}
+	*blob.Proof = txs.Proof(0)
+
+	trusted, other := tmTypes.NewMockPV(), tmTypes.NewMockPV()
+	genesisOf := func(pv tmTypes.PrivValidator) *tmTypes.GenesisDoc {
+		pubKey, _ := pv.GetPubKey()
+		return &tmTypes.GenesisDoc{ChainID: "side", Validators: []tmTypes.GenesisValidator{{PubKey: pubKey, Power: 10}}}
+	}
+
+	// signer signs the header of height 7 with dataHash
+	var signer tmTypes.PrivValidator
+	var dataHash []byte
+	td := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcTypes.RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+
+		pubKey, _ := signer.GetPubKey()
+		validators := tmTypes.NewValidatorSet([]*tmTypes.Validator{tmTypes.NewValidator(pubKey, 10)})
+		header := &tmTypes.Header{Version: tmVersion.Consensus{Block: version.BlockProtocol}, ChainID: "side", Height: 7, Time: time.Now(), DataHash: dataHash,
+			ValidatorsHash: validators.Hash(), ProposerAddress: pubKey.Address()}
+		blockID := tmTypes.BlockID{Hash: header.Hash(), PartSetHeader: tmTypes.PartSetHeader{Total: 1, Hash: header.Hash()}}
+		voteSet := tmTypes.NewVoteSet("side", 7, 0, tmProto.PrecommitType, validators)
+		commit, err := tmTypes.MakeCommit(blockID, 7, 0, voteSet, []tmTypes.PrivValidator{signer}, time.Now())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		result := coreTypes.NewResultCommit(header, commit, true)
+		_ = json.NewEncoder(w).Encode(rpcTypes.NewRPCSuccessResponse(req.ID, result))
+	}))
+	defer td.Close()
+
+	tdClient, err := tmHttp.New(td.URL, "/websocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trust, err := client.NewTrust(genesisOf(trusted), tdClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	signer, dataHash = trusted, txs.Hash()
+	var out bytes.Buffer
+	if err := trust.VerifyBlob(ctx, blob, hash, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), []byte{1, 2}) {
+		t.Fatal("verified blob differs")
+	}
+
+	// a node serving a data hash of its own
+	dataHash = tmTypes.Txs{tmTypes.Tx("{}")}.Hash()
+	if err := trust.VerifyBlob(ctx, blob, hash, &out); err == nil {
+		t.Fatal("expected error for another data hash")
+	}
+
+	// a header signed by other validators
+	signer, dataHash = other, txs.Hash()
+	if err := trust.VerifyBlob(ctx, blob, hash, &out); err == nil {
+		t.Fatal("expected error for other validators")
+	}
+	if trust, _ := client.NewTrust(genesisOf(other), tdClient); trust.VerifyBlob(ctx, blob, hash, &out) != nil {
+		t.Fatal("expected other validators to be trusted by their genesis")
+	}
+}
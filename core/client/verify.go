@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbnet/side-chain/core/types"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"strings"
+)
+
+// VerifyBlob checks that the tx of a blob returned by ProvenBlob has the given hash and is committed to by
+// the data hash returned with it, then writes the decompressed data to w. The data hash is served by the same
+// node as the tx, VerifyBlob alone only shows that the blob is consistent, Trust.VerifyBlob also checks the
+// data hash against the header signed by the validators.
+func VerifyBlob(blob *Blob, hash string, w io.Writer) error {
+	if blob.Proof == nil {
+		return errors.New("blob has no proof")
+	}
+
+	dataHash, err := hex.DecodeString(blob.DataHash)
+	if err != nil {
+		return fmt.Errorf("decode data hash: %w", err)
+	}
+	if err := blob.Proof.Validate(dataHash); err != nil {
+		return fmt.Errorf("tx not committed to by block %d: %w", blob.Height, err)
+	}
+
+	txBytes := []byte(blob.Proof.Data)
+	if txHash := types.TxHash(txBytes); txHash != types.NormalizeTxHash(hash) {
+		return fmt.Errorf("tx hash %s, expected %s", txHash, types.NormalizeTxHash(hash))
+	}
+
+	var tx struct {
+		Ty   types.TxType   `json:"type"`
+		Body types.BlobBody `json:"body"`
+	}
+	if err := json.Unmarshal(txBytes, &tx); err != nil {
+		return fmt.Errorf("decode tx: %w", err)
+	}
+	if tx.Ty != types.Blob {
+		return fmt.Errorf("tx %s is a %s tx", hash, tx.Ty)
+	}
+
+	if errName, err := tx.Body.Decompress(w); err != nil {
+		return fmt.Errorf("%s: %w", errName, err)
+	}
+	return nil
+}
+
+// CommitClient reads the signed header of a height, the tendermint RPC client implements it.
+type CommitClient interface {
+	Commit(ctx context.Context, height *int64) (*coreTypes.ResultCommit, error)
+}
+
+// Trust verifies block headers against the validators of the genesis. The side chain never updates its
+// validators, a header with a commit signed by more than 2/3 of their voting power is committed, whichever
+// node served it.
+type Trust struct {
+	chainId    string
+	validators *tmTypes.ValidatorSet
+	commits    CommitClient
+}
+
+// NewTrust trusts the validators of genesis and reads the signed headers from commits.
+func NewTrust(genesis *tmTypes.GenesisDoc, commits CommitClient) (*Trust, error) {
+	if err := genesis.ValidateAndComplete(); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %w", err)
+	}
+	if len(genesis.Validators) == 0 {
+		return nil, errors.New("genesis without validators")
+	}
+
+	validators := make([]*tmTypes.Validator, len(genesis.Validators))
+	for i, validator := range genesis.Validators {
+		validators[i] = tmTypes.NewValidator(validator.PubKey, validator.Power)
+	}
+	set, err := tmTypes.ValidatorSetFromExistingValidators(validators)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis validators: %w", err)
+	}
+
+	return &Trust{chainId: genesis.ChainID, validators: set, commits: commits}, nil
+}
+
+// Header returns the header of height once its commit is verified against the trusted validators.
+func (t *Trust) Header(ctx context.Context, height int64) (*tmTypes.Header, error) {
+	result, err := t.commits.Commit(ctx, &height)
+	if err != nil {
+		return nil, fmt.Errorf("commit of block %d: %w", height, err)
+	}
+
+	signed := result.SignedHeader
+	if signed.Header == nil || signed.Commit == nil {
+		return nil, fmt.Errorf("block %d without signed header", height)
+	}
+	// checks the chain id and that the commit is for this header
+	if err := signed.ValidateBasic(t.chainId); err != nil {
+		return nil, fmt.Errorf("block %d: %w", height, err)
+	}
+	if signed.Height != height {
+		return nil, fmt.Errorf("got block %d, expected %d", signed.Height, height)
+	}
+	if !bytes.Equal(signed.ValidatorsHash, t.validators.Hash()) {
+		return nil, fmt.Errorf("block %d is not signed by the genesis validators", height)
+	}
+	if err := t.validators.VerifyCommitLight(t.chainId, signed.Commit.BlockID, height, signed.Commit); err != nil {
+		return nil, fmt.Errorf("block %d: %w", height, err)
+	}
+	return signed.Header, nil
+}
+
+// VerifyBlob verifies the data hash of a blob returned by ProvenBlob against the signed header of its height,
+// then the blob with VerifyBlob.
+func (t *Trust) VerifyBlob(ctx context.Context, blob *Blob, hash string, w io.Writer) error {
+	header, err := t.Header(ctx, blob.Height)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(hex.EncodeToString(header.DataHash), blob.DataHash) {
+		return fmt.Errorf("data hash %s, block %d signed %X", blob.DataHash, blob.Height, header.DataHash)
+	}
+	return VerifyBlob(blob, hash, w)
+}
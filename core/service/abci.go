@@ -5,33 +5,92 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/mitchellh/mapstructure"
 	"github.com/nbnet/side-chain/core/types"
 	tdTypes "github.com/tendermint/tendermint/abci/types"
 	tmLog "github.com/tendermint/tendermint/libs/log"
+	"io"
 	"math/big"
+	"strconv"
 	"strings"
 )
 
 type Abci struct {
 	Db      types.Db
+	Pending *PendingTxs
 	log     types.CustomLogger
 	appHash []byte
+	height  int64
+	txIndex uint32
+	block   *types.BlockRecord
+	votes   []tdTypes.VoteInfo
+	upgrade *types.UpgradeConfig
+	eth     *types.EthConfig
+	halt    chan struct{}
+	// nonces holds the next nonce of the accounts with txs that passed CheckTx since the last commit
+	nonces map[common.Address]uint64
 }
 
-func NewAbci(db types.Db, logger tmLog.Logger) *Abci {
+func NewAbci(db types.Db, config *types.Config, logger tmLog.Logger) *Abci {
+
+	height, appHash, err := db.GetLastBlock()
+	if err != nil {
+		panic(err)
+	}
+
+	if height == 0 {
+		appHash = types.DefaultHash.Bytes()
+	}
+
 	return &Abci{
 		Db:      db,
+		Pending: NewPendingTxs(),
 		log:     types.CustomLogger{Logger: logger},
-		appHash: types.DefaultHash.Bytes(),
+		appHash: appHash,
+		height:  height,
+		upgrade: config.Upgrade,
+		eth:     config.Eth,
+		halt:    make(chan struct{}),
+		nonces:  make(map[common.Address]uint64),
 	}
 }
 
+// Halted is closed once the block at the scheduled upgrade height has been committed,
+// signalling that the node must be stopped and restarted with the upgraded binary.
+func (s *Abci) Halted() <-chan struct{} {
+	return s.halt
+}
+
 func (s *Abci) Info(info tdTypes.RequestInfo) tdTypes.ResponseInfo {
-	return tdTypes.ResponseInfo{}
+	if s.height == 0 {
+		return tdTypes.ResponseInfo{}
+	}
+
+	s.log.Info(types.InfoTitle, "height", s.height, "app_hash", fmt.Sprintf("%x", s.appHash))
+
+	return tdTypes.ResponseInfo{
+		LastBlockHeight:  s.height,
+		LastBlockAppHash: s.appHash,
+	}
 }
 
 func (s *Abci) BeginBlock(block tdTypes.RequestBeginBlock) tdTypes.ResponseBeginBlock {
+
+	// Never execute a block past the upgrade height with a binary that has not applied the upgrade
+	plan, err := s.Db.GetPendingUpgrade()
+	if err != nil {
+		panic(err)
+	}
+	if plan != nil {
+		panic(fmt.Sprintf("upgrade %s needed at height %d", plan.Name, plan.Height))
+	}
+
+	s.height = block.Header.Height
+	s.txIndex = 0
+	s.block = newBlockRecord(block)
+	s.votes = block.LastCommitInfo.Votes
+
 	return tdTypes.ResponseBeginBlock{}
 }
 
@@ -39,8 +98,21 @@ func (s *Abci) CheckTx(tdTx tdTypes.RequestCheckTx) tdTypes.ResponseCheckTx {
 
 	result := s.processCheckTx(tdTx.GetTx())
 
+	hash := types.TxHash(tdTx.GetTx())
+	if result.code == 0 && (tdTx.Type == tdTypes.CheckTxType_New || s.Pending.Has(hash)) {
+		// a passed recheck keeps the tx pending
+		s.Pending.Add(hash)
+	} else if result.code != 0 && tdTx.Type == tdTypes.CheckTxType_Recheck {
+		s.Pending.Remove(hash)
+	}
+	// the next tx of the account may follow in the same block
+	if result.code == 0 && result.usesNonce {
+		s.nonces[result.address] = result.nonce + 1
+	}
+
 	return tdTypes.ResponseCheckTx{
 		Code:      result.code,
+		Codespace: result.codespace(),
 		Log:       result.log,
 		Info:      result.info,
 		GasWanted: result.gas,
@@ -51,28 +123,70 @@ func (s *Abci) DeliverTx(tdTx tdTypes.RequestDeliverTx) tdTypes.ResponseDeliverT
 
 	result := s.processDeliverTx(tdTx.GetTx())
 
+	record := s.indexDeliverTx(tdTx.GetTx(), result)
+	s.txIndex++
+	if s.block != nil {
+		s.block.Txs = append(s.block.Txs, record)
+	}
+	s.Pending.Remove(record.Hash)
+
 	return tdTypes.ResponseDeliverTx{
-		Code:    result.code,
-		Log:     result.log,
-		Info:    result.info,
-		GasUsed: result.gas,
+		Code:      result.code,
+		Codespace: result.codespace(),
+		Log:       result.log,
+		Info:      result.info,
+		GasUsed:   result.gas,
+		Events:    record.Events(),
 	}
 }
 
+// EndBlock indexes the block with the totals and validator records it updates, for the explorer endpoints.
 func (s *Abci) EndBlock(block tdTypes.RequestEndBlock) tdTypes.ResponseEndBlock {
+	if s.block != nil && s.block.Height == block.Height {
+		if err := s.indexBlock(); err != nil {
+			s.log.Error(types.EndBlockTitle, types.ErrIndexBlock, err)
+		}
+	}
+	s.block, s.votes = nil, nil
 	return tdTypes.ResponseEndBlock{}
 }
 
 func (s *Abci) Commit() tdTypes.ResponseCommit {
-	s.log.Info(types.CommitTitle, "app_hash", fmt.Sprintf("%x", s.appHash))
+	s.log.Info(types.CommitTitle, "height", s.height, "app_hash", fmt.Sprintf("%x", s.appHash))
+
+	if err := s.Db.SetLastBlock(s.height, s.appHash); err != nil {
+		s.log.Error(types.CommitTitle, types.ErrProcessCommit, err)
+		panic(err)
+	}
+
+	if err := s.processUpgrade(); err != nil {
+		s.log.Error(types.CommitTitle, types.ErrProcessCommit, err)
+		panic(err)
+	}
+	// the mempool is rechecked against the new state, which counts the delivered nonces
+	s.nonces = make(map[common.Address]uint64)
 
 	return tdTypes.ResponseCommit{
 		Data: s.appHash,
 	}
 }
 
+// Query answers CheckTxQueryPath with the CheckTx result of the tx in Data against the committed state, the tx
+// is not added to the mempool. Value is the gas the tx wants as a decimal, which is the fee of a blob in wei.
 func (s *Abci) Query(query tdTypes.RequestQuery) tdTypes.ResponseQuery {
-	return tdTypes.ResponseQuery{}
+	if query.Path != types.CheckTxQueryPath {
+		return tdTypes.ResponseQuery{}
+	}
+
+	result := s.processCheckTx(query.Data)
+	return tdTypes.ResponseQuery{
+		Code:      result.code,
+		Codespace: result.codespace(),
+		Log:       result.log,
+		Info:      result.info,
+		Value:     []byte(strconv.FormatInt(result.gas, 10)),
+		Height:    s.height,
+	}
 }
 
 func (s *Abci) InitChain(chain tdTypes.RequestInitChain) tdTypes.ResponseInitChain {
@@ -104,23 +218,40 @@ func (s *Abci) SetOption(option tdTypes.RequestSetOption) tdTypes.ResponseSetOpt
 // internalResult is a struct used to encapsulate the results of processing a transaction within the ABCI application.
 // It includes details like log messages, additional info, gas usage, and an error code indicating the status of the transaction processing.
 type internalResult struct {
-	log     string
-	info    string
-	gas     int64
-	code    uint32
-	address common.Address
-	ty      types.TxType
+	log       string
+	info      string
+	gas       int64
+	code      uint32
+	address   common.Address
+	ty        types.TxType
+	recipient common.Address
+	ethHash   string
+	amount    *big.Int
+	blobSize  int
+	namespace string
+	// usesNonce is set by CheckTx for a tx that uses up nonce of address
+	usesNonce bool
+	nonce     uint64
+}
+
+func (r internalResult) codespace() string {
+	if r.code == 0 {
+		return ""
+	}
+	return types.Codespace
 }
 
 func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 	gas := int64(0)
+	// the result of the nonce check of a signed tx
+	var checked internalResult
 
 	var tx types.Tx
 	err := json.Unmarshal(txBytes, &tx)
 	if err != nil {
 		s.log.Error(types.ProcessTxTitle, types.ErrDecodeTx, err)
 		return internalResult{
-			code: 1,
+			code: types.ErrorCode(types.ErrDecodeTx),
 			info: err.Error(),
 			log:  types.ErrDecodeTx,
 		}
@@ -133,7 +264,7 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 		if err != nil {
 			s.log.Error(types.ProcessTxTitle, types.ErrDecodeMintBody, err)
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrDecodeMintBody),
 				log:  types.ErrDecodeMintBody,
 				info: err.Error(),
 			}
@@ -144,7 +275,7 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 		if err != nil {
 			s.log.Error(types.ProcessTxTitle, types.ErrCalculateDigestHash, err)
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrCalculateDigestHash),
 				log:  types.ErrCalculateDigestHash,
 				info: err.Error(),
 			}
@@ -156,30 +287,16 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 		if err != nil {
 			s.log.Error(types.ProcessTxTitle, types.ErrVerifySignature, err)
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrVerifySignature),
 				log:  types.ErrVerifySignature,
 				info: err.Error(),
 			}
 		}
 
-		nonce := new(big.Int).SetUint64(body.Nonce)
 		// check nonce
-		dbNonce, err := s.Db.GetAccountNonce(address)
-		if err != nil {
-			s.log.Error(types.ProcessTxTitle, types.ErrGetNonce, err)
-			return internalResult{
-				code: 1,
-				log:  types.ErrGetNonce,
-				info: err.Error(),
-			}
-		}
-
-		if dbNonce.Cmp(nonce) != 0 {
-			s.log.Debug(types.ProcessTxTitle, types.ErrNonceNotMatch, "", "expected", dbNonce, "get", nonce)
-			return internalResult{
-				code: 1,
-				log:  types.ErrNonceNotMatch,
-			}
+		checked = s.checkNonce(address, body.Nonce)
+		if checked.code != 0 {
+			return checked
 		}
 
 	case types.Blob:
@@ -188,7 +305,7 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 		if err != nil {
 			s.log.Error(types.ProcessTxTitle, types.ErrDecodeBlobBody, err)
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrDecodeBlobBody),
 				log:  types.ErrDecodeBlobBody,
 				info: err.Error(),
 			}
@@ -196,7 +313,7 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 
 		if len(body.Address) == types.DefaultAddressSize {
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrInvalidAddress),
 				log:  types.ErrInvalidAddress,
 			}
 		}
@@ -205,18 +322,43 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 
 		if address.Cmp(types.DefaultAddress) == 0 {
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrInvalidAddress),
 				log:  types.ErrInvalidAddress,
 			}
 		}
 
+		if len(body.Prev) != 0 && !types.ValidTxHash(body.Prev) {
+			return internalResult{
+				code: types.ErrorCode(types.ErrInvalidBlobLink),
+				log:  types.ErrInvalidBlobLink,
+			}
+		}
+
+		// a signed blob uses up a nonce of its address, so it can not be replayed
+		if tx.Signed() {
+			checked = s.checkSignedBlob(&tx, &body, address)
+			if checked.code != 0 {
+				return checked
+			}
+		}
+
+		// the data has to decompress with its codec, within the expansion bounds
+		if errName, err := body.Decompress(io.Discard); err != nil {
+			s.log.Error(types.ProcessTxTitle, errName, err)
+			return internalResult{
+				code: types.ErrorCode(errName),
+				log:  errName,
+				info: err.Error(),
+			}
+		}
+
 		g := body.Gas()
 		gas = g.Int64()
 
-		balance, err := s.Db.GetAccountBalance(address)
+		balance, err := s.Db.GetAccountBalance(address, types.LatestHeight)
 		if err != nil {
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrGetBalance),
 				log:  types.ErrGetBalance,
 				info: err.Error(),
 			}
@@ -224,28 +366,96 @@ func (s *Abci) processCheckTx(txBytes []byte) internalResult {
 
 		if balance.Cmp(g) < 0 {
 			return internalResult{
-				code: 1,
+				code: types.ErrorCode(types.ErrInsufficientBalance),
 				log:  types.ErrInsufficientBalance,
 				gas:  gas,
 			}
 		}
 
+	case types.Transfer:
+		checked = s.checkTransfer(&tx)
+		if checked.code != 0 {
+			return checked
+		}
+
+	case types.Manifest:
+		checked = s.checkManifest(&tx)
+		if checked.code != 0 {
+			return checked
+		}
+		gas = checked.gas
+
 	case types.UnKnown:
 		fallthrough
 	default:
 		s.log.Error(types.ProcessTxTitle, types.ErrUnknownTxBody, tx.Body)
 		return internalResult{
-			code: 1,
+			code: types.ErrorCode(types.ErrUnknownTxBody),
 			log:  types.ErrUnknownTxBody,
 		}
 	}
 
-	return internalResult{gas: gas}
+	checked.gas = gas
+	return checked
+}
+
+// checkSignedBlob verifies the signature and the nonce of a blob signed by its address.
+func (s *Abci) checkSignedBlob(tx *types.Tx, body *types.BlobBody, address common.Address) internalResult {
+	digestHash, err := body.DigestHash()
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrCalculateDigestHash, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrCalculateDigestHash),
+			log:  types.ErrCalculateDigestHash,
+			info: err.Error(),
+		}
+	}
+
+	if err := tx.VerifySignature(address, digestHash); err != nil {
+		s.log.Debug(types.ProcessTxTitle, types.ErrVerifySignature, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrVerifySignature),
+			log:  types.ErrVerifySignature,
+			info: err.Error(),
+		}
+	}
+
+	return s.checkNonce(address, body.Nonce)
+}
+
+// checkNonce compares nonce with the next nonce of address, which counts the txs of address that passed CheckTx
+// since the last commit, so an account can have several txs in one block as long as they come in order.
+func (s *Abci) checkNonce(address common.Address, nonce uint64) internalResult {
+	dbNonce, err := s.Db.GetAccountNonce(address, types.LatestHeight)
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrGetNonce, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrGetNonce),
+			log:  types.ErrGetNonce,
+			info: err.Error(),
+		}
+	}
+
+	expected := dbNonce
+	if next, ok := s.nonces[address]; ok && dbNonce.Cmp(new(big.Int).SetUint64(next)) < 0 {
+		expected = new(big.Int).SetUint64(next)
+	}
+
+	if expected.Cmp(new(big.Int).SetUint64(nonce)) != 0 {
+		s.log.Debug(types.ProcessTxTitle, types.ErrNonceNotMatch, "", "expected", expected, "get", nonce)
+		return internalResult{
+			code: types.ErrorCode(types.ErrNonceNotMatch),
+			log:  types.ErrNonceNotMatch,
+		}
+	}
+
+	return internalResult{address: address, usesNonce: true, nonce: nonce}
 }
 
 func (s *Abci) processDeliverTx(txBytes []byte) internalResult {
 
 	address := types.DefaultAddress
+	result := internalResult{}
 	var tx types.Tx
 	// Success by default, only successful in checkTx will reach here
 	_ = json.Unmarshal(txBytes, &tx)
@@ -266,10 +476,11 @@ func (s *Abci) processDeliverTx(txBytes []byte) internalResult {
 		if err != nil {
 			s.log.Error(types.ProcessTxTitle, types.ErrUpdateNonce, err)
 			return internalResult{
-				code:    1,
+				code:    types.ErrorCode(types.ErrUpdateNonce),
 				log:     types.ErrUpdateNonce,
 				info:    err.Error(),
 				address: address,
+				ty:      tx.Ty,
 			}
 		}
 
@@ -283,18 +494,22 @@ func (s *Abci) processDeliverTx(txBytes []byte) internalResult {
 			if err != nil {
 				s.log.Error(types.ProcessTxTitle, types.ErrUpdateBalance, err)
 				return internalResult{
-					code:    1,
+					code:    types.ErrorCode(types.ErrUpdateBalance),
 					log:     types.ErrUpdateBalance,
 					info:    err.Error(),
 					address: address,
+					ty:      tx.Ty,
+					amount:  amount,
 				}
 			}
+			result.amount = amount
 		} else {
 			s.log.Error(types.ProcessTxTitle, types.ErrDecodeAmount, body.Amount)
 			return internalResult{
-				code:    1,
+				code:    types.ErrorCode(types.ErrDecodeAmount),
 				log:     types.ErrDecodeAmount,
 				address: address,
+				ty:      tx.Ty,
 			}
 		}
 	case types.Blob:
@@ -303,28 +518,122 @@ func (s *Abci) processDeliverTx(txBytes []byte) internalResult {
 		_ = mapstructure.Decode(tx.Body, &body)
 		gas := body.Gas()
 		address = common.HexToAddress(body.Address)
+		result.blobSize = body.Size()
+		result.namespace = body.Namespace
+		if tx.Signed() {
+			if err := s.Db.UpdateAccountNonce(address); err != nil {
+				s.log.Error(types.ProcessTxTitle, types.ErrUpdateNonce, err)
+				return internalResult{
+					code:      types.ErrorCode(types.ErrUpdateNonce),
+					log:       types.ErrUpdateNonce,
+					info:      err.Error(),
+					address:   address,
+					ty:        tx.Ty,
+					blobSize:  result.blobSize,
+					namespace: result.namespace,
+				}
+			}
+		}
 		if err := s.Db.SubAccountBalance(address, gas); err != nil {
 			s.log.Error(types.ProcessTxTitle, types.ErrUpdateBalance, err)
 			return internalResult{
-				code:    1,
-				log:     types.ErrUpdateBalance,
-				info:    err.Error(),
-				gas:     gas.Int64(),
-				address: address,
+				code:      types.ErrorCode(types.ErrUpdateBalance),
+				log:       types.ErrUpdateBalance,
+				info:      err.Error(),
+				gas:       gas.Int64(),
+				address:   address,
+				ty:        tx.Ty,
+				blobSize:  result.blobSize,
+				namespace: result.namespace,
 			}
 		}
+		result.gas = gas.Int64()
+	case types.Transfer:
+		result = s.deliverTransfer(&tx)
+		if result.code != 0 {
+			return result
+		}
+		address = result.address
+	case types.Manifest:
+		result = s.deliverManifest(&tx)
+		if result.code != 0 {
+			return result
+		}
+		address = result.address
 	case types.UnKnown:
 		fallthrough
 	default:
 		s.log.Error(types.ProcessTxTitle, types.ErrUnknownTxBody, tx.Body)
 		return internalResult{
-			code: 1,
+			code: types.ErrorCode(types.ErrUnknownTxBody),
 			log:  types.ErrUnknownTxBody,
 		}
 	}
 
-	return internalResult{
-		address: address,
-		ty:      tx.Ty,
+	result.address = address
+	result.ty = tx.Ty
+	return result
+}
+
+// indexDeliverTx records the outcome of a delivered tx in the app-side index of its sender.
+func (s *Abci) indexDeliverTx(txBytes []byte, result internalResult) *types.TxRecord {
+	amount := result.amount
+	if amount == nil {
+		amount = big.NewInt(0)
+	}
+
+	recipient := ""
+	if result.ty == types.Transfer {
+		recipient = result.recipient.String()
+	}
+
+	record := &types.TxRecord{
+		Hash:      types.TxHash(txBytes),
+		Height:    s.height,
+		Index:     s.txIndex,
+		Type:      result.ty,
+		Sender:    result.address.String(),
+		Recipient: recipient,
+		EthHash:   result.ethHash,
+		Amount:    hexutil.EncodeBig(amount),
+		Fee:       hexutil.EncodeBig(big.NewInt(result.gas)),
+		GasUsed:   result.gas,
+		BlobSize:  result.blobSize,
+		Namespace: result.namespace,
+		Code:      result.code,
+		Log:       result.log,
+	}
+
+	if result.ty == types.Mint || result.ty == types.Blob || result.ty == types.Transfer || result.ty == types.Manifest {
+		if err := s.Db.IndexTx(record); err != nil {
+			s.log.Error(types.IndexTxTitle, types.ErrIndexTx, err)
+		}
+	}
+
+	return record
+}
+
+// processUpgrade records the scheduled upgrade as pending and halts the node once the block at the
+// upgrade height has been committed. The migration itself runs when the upgraded binary starts.
+func (s *Abci) processUpgrade() error {
+	if !s.upgrade.Scheduled() || s.upgrade.Height != s.height {
+		return nil
 	}
+
+	applied, err := s.Db.IsUpgradeApplied(s.upgrade.Name)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	if err := s.Db.SetPendingUpgrade(s.upgrade); err != nil {
+		return err
+	}
+
+	s.log.Info(types.UpgradeTitle, "halt", s.upgrade.Name, "height", s.height)
+	close(s.halt)
+
+	return nil
 }
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	"strings"
+	"time"
+)
+
+// identityKey is the gin context key of the authenticated identity, the key name or the jwt subject.
+const identityKey = "identity"
+
+// auth authenticates requests when enabled. Failed authentications are answered with 401, the
+// identity of successful ones is used for rate limiting. The routes openapi.json documents without security,
+// like the spec itself, /ready and the dashboard page, are public.
+func (rpc *Rpc) auth(c *gin.Context) {
+	config := rpc.authConfig
+	if config == nil || len(config.Mode) == 0 || rpc.public[c.Request.Method+" "+c.FullPath()] {
+		return
+	}
+
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if len(token) == 0 || token == c.GetHeader("Authorization") {
+		if config.PublicReads && c.Request.Method == "GET" {
+			return
+		}
+		rpc.unauthorized(c, errors.New("missing bearer token"))
+		return
+	}
+
+	var identity string
+	var err error
+	switch config.Mode {
+	case types.AuthModeApiKey:
+		identity, err = verifyApiKey(config.Keys, token)
+	case types.AuthModeJwt:
+		identity, err = verifyJwt(config.JwtSecret, token, time.Now())
+	default:
+		err = fmt.Errorf("unknown auth mode %s", config.Mode)
+	}
+	if err != nil {
+		rpc.unauthorized(c, err)
+		return
+	}
+
+	c.Set(identityKey, identity)
+}
+
+func (rpc *Rpc) unauthorized(c *gin.Context, err error) {
+	rpc.log.Error(types.AuthTitle, types.ErrUnauthorized, err)
+	c.Header("WWW-Authenticate", "Bearer")
+	c.AbortWithStatusJSON(401, types.NewRpcResp(types.NewRpcError(types.ErrUnauthorized, err), nil))
+}
+
+func verifyApiKey(keys []*types.ApiKey, token string) (string, error) {
+	for _, key := range keys {
+		if len(key.Key) != 0 && subtle.ConstantTimeCompare([]byte(key.Key), []byte(token)) == 1 {
+			return "key:" + key.Name, nil
+		}
+	}
+	return "", errors.New("unknown api key")
+}
+
+// verifyJwt verifies an HS256 token and returns its subject. exp and nbf are checked when present.
+func verifyJwt(secret, token string, now time.Time) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("jwt secret not configured")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed jwt")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "HS256" {
+		return "", fmt.Errorf("unsupported jwt alg %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", errors.New("invalid jwt signature")
+	}
+
+	var claims struct {
+		Sub string `json:"sub"`
+		Exp int64  `json:"exp"`
+		Nbf int64  `json:"nbf"`
+	}
+	if err := decodeJwtPart(parts[1], &claims); err != nil {
+		return "", err
+	}
+	if claims.Exp != 0 && now.Unix() >= claims.Exp {
+		return "", errors.New("jwt expired")
+	}
+	if claims.Nbf != 0 && now.Unix() < claims.Nbf {
+		return "", errors.New("jwt not yet valid")
+	}
+
+	return "jwt:" + claims.Sub, nil
+}
+
+func decodeJwtPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"sync"
+)
+
+// blobKey signs the blobs /blob and /blob/upload compress for its account. CheckTx takes the nonces of an
+// account in order, so mu keeps a blob from being signed before the previous one is in the mempool.
+type blobKey struct {
+	mu         sync.Mutex
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+	// pending holds the nonces of the blobs broadcast by their hash, until they leave the mempool
+	pending map[string]uint64
+}
+
+func loadBlobKey(file string) (*blobKey, error) {
+	privateKey, err := crypto.LoadECDSA(file)
+	if err != nil {
+		return nil, err
+	}
+	return &blobKey{
+		privateKey: privateKey,
+		address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		pending:    make(map[string]uint64),
+	}, nil
+}
+
+// signs reports whether the blobs of address are signed with the blob key, other blobs are broadcast unsigned.
+func (key *blobKey) signs(address string) bool {
+	return key != nil && common.HexToAddress(address) == key.address
+}
+
+// nextNonce returns the nonce following the blobs of the key in the mempool, or the nonce of the state. The
+// mempool is looked at first, a blob delivered meanwhile is counted by the state.
+func (key *blobKey) nextNonce(rpc *Rpc) (uint64, error) {
+	next := uint64(0)
+	for hash, nonce := range key.pending {
+		if !rpc.pending.Has(hash) {
+			delete(key.pending, hash)
+		} else if nonce >= next {
+			next = nonce + 1
+		}
+	}
+
+	nonce, err := rpc.db.GetAccountNonce(key.address, types.LatestHeight)
+	if err != nil {
+		return 0, err
+	}
+	if nonce.Uint64() > next {
+		next = nonce.Uint64()
+	}
+	return next, nil
+}
+
+// blobSigner completes a blob tx with nonce and signs it with sign.
+type blobSigner func(nonce uint64, sign func(digestHash []byte) (string, error)) ([]byte, error)
+
+// broadcastSigned broadcasts the blob tx signer completes with the next nonce of the blob key, see broadcast.
+func (rpc *Rpc) broadcastSigned(c *gin.Context, title string, signer blobSigner) {
+	key := rpc.blobKey
+	rpc.broadcastWith(c, title, func() (*coreTypes.ResultBroadcastTx, string, error) {
+		key.mu.Lock()
+		defer key.mu.Unlock()
+
+		nonce, err := key.nextNonce(rpc)
+		if err != nil {
+			return nil, types.ErrGetNonce, err
+		}
+		j, err := signer(nonce, func(digestHash []byte) (string, error) {
+			signature, err := crypto.Sign(digestHash, key.privateKey)
+			return common.Bytes2Hex(signature), err
+		})
+		if err != nil {
+			return nil, types.ErrSignBlob, err
+		}
+
+		result, err := rpc.tdClient.BroadcastTxSync(c.Request.Context(), tmTypes.Tx(j))
+		if err != nil {
+			return nil, types.ErrBroadcastTxSync, err
+		}
+		// a rejected blob leaves the nonce to the next one
+		if result.Code == 0 {
+			key.pending[types.TxHash(j)] = nonce
+		}
+		return result, "", nil
+	})
+}
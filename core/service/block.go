@@ -0,0 +1,222 @@
+package service
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"sort"
+	"strconv"
+)
+
+// newBlockRecord starts the record of a block from its header, the txs are added as they are delivered.
+func newBlockRecord(block tdTypes.RequestBeginBlock) *types.BlockRecord {
+	header := block.Header
+	return &types.BlockRecord{
+		Height:        header.Height,
+		Hash:          fmt.Sprintf("%X", block.Hash),
+		Time:          header.Time.UTC(),
+		ChainId:       header.ChainID,
+		Proposer:      fmt.Sprintf("%X", header.ProposerAddress),
+		LastBlockHash: fmt.Sprintf("%X", header.LastBlockId.Hash),
+		DataHash:      fmt.Sprintf("%X", header.DataHash),
+		AppHash:       fmt.Sprintf("%X", header.AppHash),
+		Fees:          "0x0",
+		Txs:           make([]*types.TxRecord, 0),
+	}
+}
+
+// indexBlock aggregates the block being ended and adds it to the totals of the previous block. The proposal
+// and the votes of the last commit are counted to the validators, unless the block is executed again after a
+// crash before its commit and was indexed already.
+func (s *Abci) indexBlock() error {
+	block := s.block
+	block.Aggregate()
+
+	stats, err := s.Db.GetStats(block.Height - 1)
+	if err != nil {
+		return err
+	}
+
+	indexed, err := s.Db.GetBlock(block.Height)
+	if err != nil {
+		return err
+	}
+	validators := make([]*types.ValidatorRecord, 0)
+	if indexed == nil {
+		if validators, err = s.countValidators(block); err != nil {
+			return err
+		}
+	}
+
+	return s.Db.IndexBlock(block, stats.Add(block), validators)
+}
+
+// countValidators returns the records of the validators that voted for the previous block or proposed block,
+// updated with their votes and the proposal.
+func (s *Abci) countValidators(block *types.BlockRecord) ([]*types.ValidatorRecord, error) {
+	known, err := s.Db.GetValidators()
+	if err != nil {
+		return nil, err
+	}
+	validators := make(map[string]*types.ValidatorRecord, len(known))
+	for _, validator := range known {
+		validators[validator.Address] = validator
+	}
+
+	updated := make([]*types.ValidatorRecord, 0, len(s.votes)+1)
+	seen := func(address string) *types.ValidatorRecord {
+		validator, ok := validators[address]
+		if !ok {
+			validator = &types.ValidatorRecord{Address: address}
+			validators[address] = validator
+		}
+		if validator.LastSeenHeight != block.Height {
+			validator.LastSeenHeight = block.Height
+			updated = append(updated, validator)
+		}
+		return validator
+	}
+
+	// the votes are the commit of the previous block, the first block has none
+	for _, vote := range s.votes {
+		validator := seen(fmt.Sprintf("%X", vote.Validator.Address))
+		validator.Power = vote.Validator.Power
+		if vote.SignedLastBlock {
+			validator.Signed++
+			validator.LastSignedHeight = block.Height - 1
+		} else {
+			validator.Missed++
+		}
+	}
+	if len(block.Proposer) != 0 {
+		proposer := seen(block.Proposer)
+		proposer.Proposed++
+		proposer.LastProposedHeight = block.Height
+	}
+
+	return updated, nil
+}
+
+// latestBlockHeight returns the last committed height, the explorer endpoints do not show the block being
+// executed.
+func (rpc *Rpc) latestBlockHeight(c *gin.Context, title string) (int64, bool) {
+	height, _, err := rpc.db.GetLastBlock()
+	if err != nil {
+		rpc.log.Error(title, types.ErrGetLastBlock, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetLastBlock, err), nil))
+		return 0, false
+	}
+	return height, true
+}
+
+// latestBlockHandler returns the last committed block.
+func (rpc *Rpc) latestBlockHandler(c *gin.Context) {
+	height, ok := rpc.latestBlockHeight(c, types.BlocksHandlerTitle)
+	if !ok {
+		return
+	}
+	rpc.writeBlock(c, height, height)
+}
+
+// blockHandler returns the block at :height with its txs, their decoded types, the blob bytes and the fees.
+func (rpc *Rpc) blockHandler(c *gin.Context) {
+	height, err := strconv.ParseInt(c.Param("height"), 10, 64)
+	if err != nil || height < 1 {
+		rpc.log.Error(types.BlocksHandlerTitle, types.ErrInvalidQuery, c.Param("height"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcBlockData(nil, 1)))
+		return
+	}
+
+	latest, ok := rpc.latestBlockHeight(c, types.BlocksHandlerTitle)
+	if !ok {
+		return
+	}
+	rpc.writeBlock(c, height, latest)
+}
+
+func (rpc *Rpc) writeBlock(c *gin.Context, height, latest int64) {
+	var block *types.BlockRecord
+	var err error
+	if height <= latest {
+		block, err = rpc.db.GetBlock(height)
+	}
+	if err != nil {
+		rpc.log.Error(types.BlocksHandlerTitle, types.ErrGetBlock, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetBlock, err), types.NewRpcBlockData(nil, 1)))
+		return
+	}
+	if block == nil {
+		c.JSON(404, types.NewRpcResp(types.NewRpcError(types.ErrBlockNotFound, nil), types.NewRpcBlockData(nil, 1)))
+		return
+	}
+
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcBlockData(block, 0)))
+}
+
+// validatorsHandler lists the validators seen in the blocks the node executed, by voting power. The height of
+// the response is the last block counted, a validator last seen below it left the validator set.
+func (rpc *Rpc) validatorsHandler(c *gin.Context) {
+	validators, err := rpc.db.GetValidators()
+	if err != nil {
+		rpc.log.Error(types.ValidatorsHandlerTitle, types.ErrGetValidators, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetValidators, err), types.NewRpcValidatorsData(nil, 0, 1)))
+		return
+	}
+
+	height := int64(0)
+	for _, validator := range validators {
+		height = max(height, validator.LastSeenHeight)
+	}
+	sort.SliceStable(validators, func(i, j int) bool {
+		return validators[i].Power > validators[j].Power
+	})
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcValidatorsData(validators, height, 0)))
+}
+
+// statsHandler returns the totals at the last committed block and ?points samples of them ?interval blocks
+// apart, oldest first, the changes between two samples are the activity in between.
+func (rpc *Rpc) statsHandler(c *gin.Context) {
+	interval, err := strconv.ParseInt(c.DefaultQuery("interval", strconv.FormatInt(types.DefaultStatsInterval, 10)), 10, 64)
+	if err != nil || interval < 1 {
+		rpc.log.Error(types.StatsHandlerTitle, types.ErrInvalidQuery, c.Query("interval"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcStatsData(nil, nil, 1)))
+		return
+	}
+
+	points, err := strconv.Atoi(c.DefaultQuery("points", strconv.Itoa(types.DefaultPageLimit)))
+	if err != nil || points < 1 || points > types.MaxPageLimit {
+		rpc.log.Error(types.StatsHandlerTitle, types.ErrInvalidQuery, c.Query("points"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcStatsData(nil, nil, 1)))
+		return
+	}
+
+	height, ok := rpc.latestBlockHeight(c, types.StatsHandlerTitle)
+	if !ok {
+		return
+	}
+
+	samples := make([]*types.ChainStats, 0, points)
+	for i := points - 1; i >= 0; i-- {
+		at := height - int64(i)*interval
+		if at < 1 {
+			continue
+		}
+		stats, err := rpc.db.GetStats(at)
+		if err != nil {
+			rpc.log.Error(types.StatsHandlerTitle, types.ErrGetStats, err)
+			c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetStats, err), types.NewRpcStatsData(nil, nil, 1)))
+			return
+		}
+		// heights before the first aggregated block have no totals
+		if stats != nil && (len(samples) == 0 || samples[len(samples)-1].Height != stats.Height) {
+			samples = append(samples, stats)
+		}
+	}
+
+	var latest *types.ChainStats
+	if len(samples) != 0 {
+		latest = samples[len(samples)-1]
+	}
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcStatsData(latest, samples, 0)))
+}
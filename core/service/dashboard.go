@@ -0,0 +1,57 @@
+package service
+
+import (
+	_ "embed"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+)
+
+// dashboardPage is the web dashboard of the node, a single page without external assets reading the RPC
+// endpoints, served at /dashboard when rpc.dashboard is set.
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// dashboardPolicy keeps the page from loading anything but itself and the RPC endpoints.
+const dashboardPolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; img-src data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// dashboardHandler serves the page, 404 unless rpc.dashboard is set. The page itself holds no data, it is
+// served without credentials and sends the token entered in it with its requests.
+func (rpc *Rpc) dashboardHandler(c *gin.Context) {
+	if !rpc.rpcConfig.Dashboard {
+		c.JSON(404, types.NewRpcResp(types.NewRpcError(types.ErrDashboardDisabled, nil), nil))
+		return
+	}
+	c.Header("Content-Security-Policy", dashboardPolicy)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "no-cache")
+	c.Data(200, "text/html; charset=utf-8", dashboardPage)
+}
+
+// statusHandler reports the sync status of the node, its peers and the size of its mempool.
+func (rpc *Rpc) statusHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	status, err := rpc.tdClient.Status(ctx)
+	if err != nil {
+		rpc.log.Error(types.StatusHandlerTitle, types.ErrNodeStatus, err)
+		c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrNodeStatus, err), types.NewRpcStatusData(nil, nil, nil, 1)))
+		return
+	}
+
+	netInfo, err := rpc.tdClient.NetInfo(ctx)
+	if err != nil {
+		rpc.log.Error(types.StatusHandlerTitle, types.ErrNodeStatus, err)
+		c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrNodeStatus, err), types.NewRpcStatusData(nil, nil, nil, 1)))
+		return
+	}
+
+	mempool, err := rpc.tdClient.NumUnconfirmedTxs(ctx)
+	if err != nil {
+		rpc.log.Error(types.StatusHandlerTitle, types.ErrNodeStatus, err)
+		c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrNodeStatus, err), types.NewRpcStatusData(nil, nil, nil, 1)))
+		return
+	}
+
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcStatusData(status, netInfo, mempool, 0)))
+}
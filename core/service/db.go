@@ -1,18 +1,32 @@
 package service
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/nbnet/side-chain/core/types"
 	tmLog "github.com/tendermint/tendermint/libs/log"
 	"math/big"
+	"sync"
+	"sync/atomic"
 )
 
 type DbService struct {
 	config *types.DbConfig
 	db     *badger.DB
 	log    *types.CustomLogger
+
+	// dirty holds the addresses written since the last commit, SetLastBlock versions their state
+	dirtyMu sync.Mutex
+	dirty   map[common.Address]struct{}
+
+	// pruning is set while pruneHistory sweeps in the background, Close waits for the sweep
+	pruning atomic.Bool
+	sweeps  sync.WaitGroup
 }
 
 func NewDbService(config *types.DbConfig, logger tmLog.Logger) *DbService {
@@ -28,11 +42,24 @@ func NewDbService(config *types.DbConfig, logger tmLog.Logger) *DbService {
 		panic(err)
 	}
 
-	return &DbService{
+	d := &DbService{
 		config: config,
 		db:     db,
 		log:    log,
+		dirty:  make(map[common.Address]struct{}),
+	}
+	if err := d.indexNamespaces(); err != nil {
+		panic(err)
+	}
+	if err := d.initHistory(); err != nil {
+		panic(err)
 	}
+	return d
+}
+
+func (d *DbService) Close() error {
+	d.sweeps.Wait()
+	return d.db.Close()
 }
 
 func (d *DbService) AddAccountBalance(address common.Address, amount *big.Int) error {
@@ -67,6 +94,9 @@ func (d *DbService) AddAccountBalance(address common.Address, amount *big.Int) e
 		return nil
 	})
 
+	if result == nil {
+		d.markDirty(address)
+	}
 	return result
 }
 
@@ -102,6 +132,9 @@ func (d *DbService) SubAccountBalance(address common.Address, amount *big.Int) e
 		return nil
 	})
 
+	if result == nil {
+		d.markDirty(address)
+	}
 	return result
 }
 
@@ -136,10 +169,19 @@ func (d *DbService) UpdateAccountNonce(address common.Address) error {
 		return nil
 	})
 
+	if result == nil {
+		d.markDirty(address)
+	}
 	return result
 }
 
-func (d *DbService) GetAccountBalance(address common.Address) (*big.Int, error) {
+// GetAccountBalance returns the balance of address committed at height, or the latest balance at
+// types.LatestHeight.
+func (d *DbService) GetAccountBalance(address common.Address, height int64) (*big.Int, error) {
+	if height != types.LatestHeight {
+		return d.getHistory(types.BalanceHistoryKey, address, height, types.GetAccountBalanceTitle, types.ErrGetBalance)
+	}
+
 	result := big.NewInt(0)
 	err := d.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get(types.BalanceKey(address))
@@ -162,7 +204,12 @@ func (d *DbService) GetAccountBalance(address common.Address) (*big.Int, error)
 	return result, err
 }
 
-func (d *DbService) GetAccountNonce(address common.Address) (*big.Int, error) {
+// GetAccountNonce returns the nonce of address committed at height, or the latest nonce at types.LatestHeight.
+func (d *DbService) GetAccountNonce(address common.Address, height int64) (*big.Int, error) {
+	if height != types.LatestHeight {
+		return d.getHistory(types.NonceHistoryKey, address, height, types.GetAccountNonceTitle, types.ErrGetNonce)
+	}
+
 	result := big.NewInt(0)
 	err := d.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get(types.NonceKey(address))
@@ -184,3 +231,698 @@ func (d *DbService) GetAccountNonce(address common.Address) (*big.Int, error) {
 
 	return result, err
 }
+
+// getHistory returns the last version of the value of address committed at or below height.
+func (d *DbService) getHistory(historyKey func(common.Address, int64) []byte, address common.Address, height int64, title, errName string) (*big.Int, error) {
+	result := big.NewInt(0)
+	err := d.db.View(func(txn *badger.Txn) error {
+		oldest, latest, err := d.historyRange(txn)
+		if err != nil {
+			return err
+		}
+		if height < oldest || height > latest {
+			return &types.StateNotAvailableError{Height: height, Oldest: oldest, Latest: latest}
+		}
+
+		key := historyKey(address, height)
+		addressPrefix := key[:len(key)-8]
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: addressPrefix, Reverse: true})
+		defer it.Close()
+		// a reverse iterator seeks the last key at or before the key
+		it.Seek(key)
+		if !it.ValidForPrefix(addressPrefix) {
+			return nil
+		}
+		return it.Item().Value(func(val []byte) error {
+			result.SetBytes(val)
+			return nil
+		})
+	})
+
+	var notAvailable *types.StateNotAvailableError
+	if err != nil && !errors.As(err, &notAvailable) {
+		d.log.Error(title, errName, err)
+	}
+	return result, err
+}
+
+// GetHistoryRange returns the heights whose state is kept, from the history base to the last committed height.
+func (d *DbService) GetHistoryRange() (int64, int64, error) {
+	var oldest, latest int64
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		oldest, latest, err = d.historyRange(txn)
+		return err
+	})
+	if err != nil {
+		d.log.Error(types.InfoTitle, types.ErrGetLastBlock, err)
+	}
+	return oldest, latest, err
+}
+
+// historyRange returns the heights whose state is kept, from the history base to the last committed height.
+func (d *DbService) historyRange(txn *badger.Txn) (int64, int64, error) {
+	latest, err := getInt(txn, types.LastBlockHeightKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	base, err := getInt(txn, types.HistoryBaseKey)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return max(base, 1), latest, nil
+}
+
+// getInt reads an integer stored as big endian bytes, 0 if the key is not set.
+func getInt(txn *badger.Txn, key []byte) (int64, error) {
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value := big.NewInt(0)
+	err = item.Value(func(val []byte) error {
+		value.SetBytes(val)
+		return nil
+	})
+	return value.Int64(), err
+}
+
+func (d *DbService) GetLastBlock() (int64, []byte, error) {
+	height := big.NewInt(0)
+	var appHash []byte
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(types.LastBlockHeightKey)
+
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			d.log.Error(types.InfoTitle, types.ErrGetLastBlock, err)
+			return err
+		}
+		err = item.Value(func(val []byte) error {
+			height.SetBytes(val)
+			return nil
+		})
+		if err != nil {
+			d.log.Error(types.InfoTitle, types.ErrGetLastBlock, err)
+			return err
+		}
+
+		item, err = txn.Get(types.LastBlockAppHashKey)
+		if err != nil {
+			d.log.Error(types.InfoTitle, types.ErrGetLastBlock, err)
+			return err
+		}
+		appHash, err = item.ValueCopy(nil)
+		return err
+	})
+
+	return height.Int64(), appHash, err
+}
+
+// SetLastBlock commits height. The balances and nonces written since the last commit are versioned at height,
+// and their versions older than the KeepRecent heights are pruned. Accounts no longer written are pruned by
+// pruneHistory in the background, every KeepRecent heights the history base moves up.
+func (d *DbService) SetLastBlock(height int64, appHash []byte) error {
+	d.dirtyMu.Lock()
+	defer d.dirtyMu.Unlock()
+
+	sweep := int64(0)
+	result := d.db.Update(func(txn *badger.Txn) error {
+		err := txn.Set(types.LastBlockHeightKey, big.NewInt(height).Bytes())
+		if err != nil {
+			d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+			return err
+		}
+
+		err = txn.Set(types.LastBlockAppHashKey, appHash)
+		if err != nil {
+			d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+			return err
+		}
+
+		for address := range d.dirty {
+			if err := d.versionAccount(txn, address, height); err != nil {
+				d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+				return err
+			}
+		}
+
+		// the base moves up with pruning, so a larger KeepRecent later does not read pruned heights
+		if d.config.KeepRecent > 0 && height-d.config.KeepRecent+1 > 1 {
+			base, err := getInt(txn, types.HistoryBaseKey)
+			if err == nil && height-d.config.KeepRecent+1 > base {
+				if (height-d.config.KeepRecent+1)/d.config.KeepRecent > base/d.config.KeepRecent {
+					sweep = height - d.config.KeepRecent + 1
+				}
+				err = txn.Set(types.HistoryBaseKey, big.NewInt(height-d.config.KeepRecent+1).Bytes())
+			}
+			if err != nil {
+				d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+				return err
+			}
+		}
+		return nil
+	})
+
+	if result == nil {
+		clear(d.dirty)
+		if sweep > 0 {
+			d.sweepHistory(sweep)
+		}
+	}
+	return result
+}
+
+// sweepHistory runs pruneHistory in the background, unless a sweep is still running. A skipped or failed sweep
+// leaves the stale versions to the next one, which prunes up to a higher base.
+func (d *DbService) sweepHistory(base int64) {
+	if !d.pruning.CompareAndSwap(false, true) {
+		d.log.Debug(types.CommitTitle, "history sweep running, skipped base", base)
+		return
+	}
+
+	d.sweeps.Add(1)
+	go func() {
+		defer d.sweeps.Done()
+		defer d.pruning.Store(false)
+		if err := d.pruneHistory(base); err != nil {
+			d.log.Error(types.CommitTitle, types.ErrPruneHistory, err)
+		}
+	}()
+}
+
+// pruneHistory deletes the versions of all accounts that are no longer read with base as the oldest kept height,
+// the versions before the last one at or below base.
+func (d *DbService) pruneHistory(base int64) error {
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	pruned := 0
+	err := d.db.View(func(txn *badger.Txn) error {
+		for _, prefix := range [][]byte{types.BalanceHistoryKeyPrefix, types.NonceHistoryKeyPrefix} {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+			// the last version at or below base of the account iterated
+			var last []byte
+			for it.Rewind(); it.Valid(); it.Next() {
+				key := it.Item().Key()
+				if len(key) != len(prefix)+common.AddressLength+8 || int64(binary.BigEndian.Uint64(key[len(key)-8:])) > base {
+					continue
+				}
+				if last != nil && bytes.Equal(last[:len(last)-8], key[:len(key)-8]) {
+					if err := batch.Delete(last); err != nil {
+						it.Close()
+						return err
+					}
+					pruned++
+				}
+				last = it.Item().KeyCopy(nil)
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err == nil {
+		err = batch.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	d.log.Debug(types.CommitTitle, "history base", base, "pruned", pruned)
+	return nil
+}
+
+func (d *DbService) markDirty(address common.Address) {
+	d.dirtyMu.Lock()
+	defer d.dirtyMu.Unlock()
+	d.dirty[address] = struct{}{}
+}
+
+// versionAccount copies the balance and nonce of address to its versions at height. Of the versions at or
+// below the oldest kept height, only the last one is still read, the others are deleted.
+func (d *DbService) versionAccount(txn *badger.Txn, address common.Address, height int64) error {
+	oldest := int64(0)
+	if d.config.KeepRecent > 0 {
+		oldest = height - d.config.KeepRecent + 1
+	}
+
+	for _, key := range []struct {
+		latest  []byte
+		history func(common.Address, int64) []byte
+	}{
+		{types.BalanceKey(address), types.BalanceHistoryKey},
+		{types.NonceKey(address), types.NonceHistoryKey},
+	} {
+		value := []byte{}
+		item, err := txn.Get(key.latest)
+		if err == nil {
+			value, err = item.ValueCopy(nil)
+		}
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		if err := txn.Set(key.history(address, height), value); err != nil {
+			return err
+		}
+		if oldest <= 1 {
+			continue
+		}
+
+		// the versions before the last one at or below oldest
+		var pruned [][]byte
+		prefix := key.history(address, 0)
+		prefix = prefix[:len(prefix)-8]
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, Reverse: true})
+		for it.Seek(key.history(address, oldest)); it.ValidForPrefix(prefix); it.Next() {
+			pruned = append(pruned, it.Item().KeyCopy(nil))
+		}
+		it.Close()
+		for i := 1; i < len(pruned); i++ {
+			if err := txn.Delete(pruned[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// initHistory versions the state of a database written before balances and nonces were versioned at its last
+// committed height, from which on the history is kept, once per database.
+func (d *DbService) initHistory() error {
+	return d.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(types.HistoryBaseKey); err == nil {
+			return nil
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+			return err
+		}
+
+		height, err := getInt(txn, types.LastBlockHeightKey)
+		if err != nil {
+			d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+			return err
+		}
+
+		if height == 0 {
+			return txn.Set(types.HistoryBaseKey, big.NewInt(height).Bytes())
+		}
+
+		accounts := make(map[common.Address]struct{})
+		for _, prefix := range [][]byte{types.BalanceKeyPrefix, types.NonceKeyPrefix} {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+			for it.Rewind(); it.Valid(); it.Next() {
+				if key := it.Item().Key(); len(key) == len(prefix)+common.AddressLength {
+					accounts[common.BytesToAddress(key[len(prefix):])] = struct{}{}
+				}
+			}
+			it.Close()
+		}
+		for address := range accounts {
+			if err := d.versionAccount(txn, address, height); err != nil {
+				d.log.Error(types.CommitTitle, types.ErrSetLastBlock, err)
+				return err
+			}
+		}
+		d.log.Info(types.CommitTitle, "history", height, "accounts", len(accounts))
+
+		return txn.Set(types.HistoryBaseKey, big.NewInt(height).Bytes())
+	})
+}
+
+func (d *DbService) GetPendingUpgrade() (*types.UpgradeConfig, error) {
+	var plan *types.UpgradeConfig
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(types.PendingUpgradeKey)
+
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			d.log.Error(types.UpgradeTitle, types.ErrGetUpgrade, err)
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			plan = &types.UpgradeConfig{}
+			return json.Unmarshal(val, plan)
+		})
+	})
+
+	return plan, err
+}
+
+func (d *DbService) SetPendingUpgrade(plan *types.UpgradeConfig) error {
+	value, err := json.Marshal(plan)
+	if err != nil {
+		d.log.Error(types.UpgradeTitle, types.ErrSetUpgrade, err)
+		return err
+	}
+
+	result := d.db.Update(func(txn *badger.Txn) error {
+		err := txn.Set(types.PendingUpgradeKey, value)
+		if err != nil {
+			d.log.Error(types.UpgradeTitle, types.ErrSetUpgrade, err)
+			return err
+		}
+		d.log.Info(types.UpgradeTitle, "Name", plan.Name, "Height", plan.Height)
+		return nil
+	})
+
+	return result
+}
+
+func (d *DbService) IsUpgradeApplied(name string) (bool, error) {
+	applied := false
+	err := d.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(types.UpgradeKey(name))
+
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			d.log.Error(types.UpgradeTitle, types.ErrGetUpgrade, err)
+			return err
+		}
+		applied = true
+		return nil
+	})
+
+	return applied, err
+}
+
+func (d *DbService) IndexTx(record *types.TxRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		d.log.Error(types.IndexTxTitle, types.ErrIndexTx, err)
+		return err
+	}
+
+	result := d.db.Update(func(txn *badger.Txn) error {
+		key := types.TxIndexKey(common.HexToAddress(record.Sender), record.Height, record.Index)
+		err := txn.Set(key, value)
+		if err != nil {
+			d.log.Error(types.IndexTxTitle, types.ErrIndexTx, err)
+			return err
+		}
+
+		err = txn.Set(types.TxHashKey(record.Hash), value)
+		if err != nil {
+			d.log.Error(types.IndexTxTitle, types.ErrIndexTx, err)
+			return err
+		}
+
+		if record.Type == types.Blob {
+			err = txn.Set(types.NamespaceKey(record.Namespace, record.Height, record.Index), value)
+			if err != nil {
+				d.log.Error(types.IndexTxTitle, types.ErrIndexTx, err)
+				return err
+			}
+		}
+
+		// txs decoded from an ethereum tx can also be looked up by their ethereum hash
+		if len(record.EthHash) != 0 {
+			err = txn.Set(types.TxHashKey(types.NormalizeTxHash(record.EthHash)), value)
+			if err != nil {
+				d.log.Error(types.IndexTxTitle, types.ErrIndexTx, err)
+				return err
+			}
+		}
+		d.log.Debug(types.IndexTxTitle, "Address", record.Sender, "Hash", record.Hash)
+		return nil
+	})
+
+	return result
+}
+
+// GetTxsByAddress returns the txs sent by address from fromHeight on, oldest first, optionally filtered by type.
+// offset and limit select a page of the matching txs; the total number of matches is returned alongside.
+func (d *DbService) GetTxsByAddress(address common.Address, ty types.TxType, fromHeight int64, offset, limit int) ([]*types.TxRecord, int, error) {
+	records := make([]*types.TxRecord, 0)
+	total := 0
+	err := d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := types.TxIndexAddressKey(address)
+		for it.Seek(types.TxIndexKey(address, fromHeight, 0)); it.ValidForPrefix(prefix); it.Next() {
+			record := &types.TxRecord{}
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, record)
+			})
+			if err != nil {
+				d.log.Error(types.TxsHandlerTitle, types.ErrGetTxs, err)
+				return err
+			}
+
+			if ty != 0 && record.Type != ty {
+				continue
+			}
+
+			if total >= offset && len(records) < limit {
+				records = append(records, record)
+			}
+			total++
+		}
+		return nil
+	})
+
+	return records, total, err
+}
+
+// GetBlobsByNamespace returns the blobs of a namespace delivered from fromHeight to toHeight, oldest first.
+// A zero toHeight is unbounded. offset and limit select a page, the total number of matches is returned alongside.
+func (d *DbService) GetBlobsByNamespace(namespace string, fromHeight, toHeight int64, offset, limit int) ([]*types.TxRecord, int, error) {
+	records := make([]*types.TxRecord, 0)
+	total := 0
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := types.NamespacePrefixKey(namespace)
+		for it.Seek(types.NamespaceKey(namespace, fromHeight, 0)); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			if toHeight > 0 && int64(binary.BigEndian.Uint64(key[len(prefix):])) > toHeight {
+				break
+			}
+
+			if total >= offset && len(records) < limit {
+				record := &types.TxRecord{}
+				err := it.Item().Value(func(val []byte) error {
+					return json.Unmarshal(val, record)
+				})
+				if err != nil {
+					d.log.Error(types.BlobsHandlerTitle, types.ErrGetBlobs, err)
+					return err
+				}
+				records = append(records, record)
+			}
+			total++
+		}
+		return nil
+	})
+
+	return records, total, err
+}
+
+// indexNamespaces adds the blobs indexed before blobs were indexed by namespace to the namespace index,
+// once per database.
+func (d *DbService) indexNamespaces() error {
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	indexed := false
+	blobs := 0
+	err := d.db.View(func(txn *badger.Txn) error {
+		if _, err := txn.Get(types.NamespaceIndexedKey); err == nil {
+			indexed = true
+			return nil
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: types.TxHashKeyPrefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				record := &types.TxRecord{}
+				if err := json.Unmarshal(val, record); err != nil || record.Type != types.Blob {
+					return err
+				}
+				blobs++
+				return batch.Set(types.NamespaceKey(record.Namespace, record.Height, record.Index), append([]byte{}, val...))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		d.log.Error(types.IndexNamespacesTitle, types.ErrIndexTx, err)
+		return err
+	}
+	if indexed {
+		return nil
+	}
+
+	if err := batch.Set(types.NamespaceIndexedKey, []byte{1}); err != nil {
+		d.log.Error(types.IndexNamespacesTitle, types.ErrIndexTx, err)
+		return err
+	}
+	if err := batch.Flush(); err != nil {
+		d.log.Error(types.IndexNamespacesTitle, types.ErrIndexTx, err)
+		return err
+	}
+	if blobs != 0 {
+		d.log.Info(types.IndexNamespacesTitle, "blobs", blobs)
+	}
+	return nil
+}
+
+// GetTx returns the index entry of a delivered tx, or nil if no tx with that hash has been delivered.
+func (d *DbService) GetTx(hash string) (*types.TxRecord, error) {
+	var record *types.TxRecord
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(types.TxHashKey(hash))
+
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			d.log.Error(types.TxHandlerTitle, types.ErrGetTx, err)
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			record = &types.TxRecord{}
+			return json.Unmarshal(val, record)
+		})
+	})
+
+	return record, err
+}
+
+// IndexBlock stores the record of a block with the totals taken at it and the validator records it updated.
+func (d *DbService) IndexBlock(block *types.BlockRecord, stats *types.ChainStats, validators []*types.ValidatorRecord) error {
+	result := d.db.Update(func(txn *badger.Txn) error {
+		value, err := json.Marshal(block)
+		if err == nil {
+			err = txn.Set(types.BlockKey(block.Height), value)
+		}
+		if err != nil {
+			d.log.Error(types.IndexBlockTitle, types.ErrIndexBlock, err)
+			return err
+		}
+
+		value, err = json.Marshal(stats)
+		if err == nil {
+			err = txn.Set(types.StatsKey(stats.Height), value)
+		}
+		if err != nil {
+			d.log.Error(types.IndexBlockTitle, types.ErrIndexBlock, err)
+			return err
+		}
+
+		for _, validator := range validators {
+			address, err := hex.DecodeString(validator.Address)
+			if err != nil {
+				d.log.Error(types.IndexBlockTitle, types.ErrIndexBlock, err)
+				return err
+			}
+			value, err := json.Marshal(validator)
+			if err == nil {
+				err = txn.Set(types.ValidatorKey(address), value)
+			}
+			if err != nil {
+				d.log.Error(types.IndexBlockTitle, types.ErrIndexBlock, err)
+				return err
+			}
+		}
+		d.log.Debug(types.IndexBlockTitle, "Height", block.Height, "Txs", block.TxCount)
+		return nil
+	})
+
+	return result
+}
+
+// GetBlock returns the record of the block at height, or nil if the node did not index it.
+func (d *DbService) GetBlock(height int64) (*types.BlockRecord, error) {
+	var block *types.BlockRecord
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(types.BlockKey(height))
+
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			d.log.Error(types.BlocksHandlerTitle, types.ErrGetBlock, err)
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			block = &types.BlockRecord{}
+			return json.Unmarshal(val, block)
+		})
+	})
+
+	return block, err
+}
+
+// GetStats returns the totals taken at the last indexed block at or below height, or nil if there is none.
+func (d *DbService) GetStats(height int64) (*types.ChainStats, error) {
+	var stats *types.ChainStats
+	err := d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: types.StatsKeyPrefix, Reverse: true})
+		defer it.Close()
+
+		// a reverse iterator seeks the last key at or before the key
+		it.Seek(types.StatsKey(height))
+		if !it.ValidForPrefix(types.StatsKeyPrefix) {
+			return nil
+		}
+		return it.Item().Value(func(val []byte) error {
+			stats = &types.ChainStats{}
+			return json.Unmarshal(val, stats)
+		})
+	})
+
+	if err != nil {
+		d.log.Error(types.StatsHandlerTitle, types.ErrGetStats, err)
+	}
+	return stats, err
+}
+
+// GetValidators returns the records of every validator seen in a block, ordered by address.
+func (d *DbService) GetValidators() ([]*types.ValidatorRecord, error) {
+	validators := make([]*types.ValidatorRecord, 0)
+	err := d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: types.ValidatorKeyPrefix})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			validator := &types.ValidatorRecord{}
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, validator)
+			})
+			if err != nil {
+				return err
+			}
+			validators = append(validators, validator)
+		}
+		return nil
+	})
+
+	if err != nil {
+		d.log.Error(types.ValidatorsHandlerTitle, types.ErrGetValidators, err)
+	}
+	return validators, err
+}
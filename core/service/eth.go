@@ -0,0 +1,372 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ethHandler serves the ethereum JSON-RPC facade, single and batch requests, so wallets and tooling like
+// ethers or viem can read balances and nonces and send value transfers. State queries read the committed state
+// at the block of their tag or number, kept for the keep_recent heights of the node. Requests are at most
+// types.MaxEthRequestBytes, batches at most types.MaxEthBatch requests.
+func (rpc *Rpc) ethHandler(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, types.MaxEthRequestBytes))
+	if err != nil {
+		rpc.log.Error(types.EthHandlerTitle, types.ErrDecodeTx, err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(413, types.NewEthError(nil, types.EthErrInvalidRequest, fmt.Sprintf("request larger than %d bytes", types.MaxEthRequestBytes)))
+			return
+		}
+		c.JSON(200, types.NewEthError(nil, types.EthErrParse, err.Error()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) != 0 && body[0] == '[' {
+		var reqs []types.EthRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			c.JSON(200, types.NewEthError(nil, types.EthErrParse, err.Error()))
+			return
+		}
+		if len(reqs) == 0 {
+			c.JSON(200, types.NewEthError(nil, types.EthErrInvalidRequest, "empty batch"))
+			return
+		}
+		if len(reqs) > types.MaxEthBatch {
+			c.JSON(200, types.NewEthError(nil, types.EthErrInvalidRequest, fmt.Sprintf("batch of %d requests, at most %d", len(reqs), types.MaxEthBatch)))
+			return
+		}
+
+		resps := make([]*types.EthResponse, 0, len(reqs))
+		for i := range reqs {
+			resps = append(resps, rpc.ethCall(c.Request.Context(), &reqs[i]))
+		}
+		c.JSON(200, resps)
+		return
+	}
+
+	var req types.EthRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.JSON(200, types.NewEthError(nil, types.EthErrParse, err.Error()))
+		return
+	}
+
+	c.JSON(200, rpc.ethCall(c.Request.Context(), &req))
+}
+
+func (rpc *Rpc) ethCall(ctx context.Context, req *types.EthRequest) *types.EthResponse {
+	if rpc.ethConfig == nil || rpc.ethConfig.ChainId == 0 {
+		return types.NewEthError(req.Id, types.EthErrInternal, types.ErrEthDisabled)
+	}
+
+	switch req.Method {
+	case "eth_chainId":
+		return types.NewEthResult(req.Id, hexutil.EncodeUint64(rpc.ethConfig.ChainId))
+	case "net_version":
+		return types.NewEthResult(req.Id, strconv.FormatUint(rpc.ethConfig.ChainId, 10))
+	case "eth_gasPrice", "eth_maxPriorityFeePerGas":
+		// transfers do not pay fees
+		return types.NewEthResult(req.Id, "0x0")
+	case "eth_estimateGas":
+		return types.NewEthResult(req.Id, hexutil.EncodeUint64(21000))
+	case "eth_blockNumber":
+		height, _, err := rpc.db.GetLastBlock()
+		if err != nil {
+			return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+		}
+		return types.NewEthResult(req.Id, hexutil.EncodeUint64(uint64(height)))
+	case "eth_getBalance":
+		address, err := ethAddressParam(req)
+		if err != nil {
+			return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+		}
+		height, resp := rpc.ethHeightParam(req, 1)
+		if resp != nil {
+			return resp
+		}
+		balance, err := rpc.db.GetAccountBalance(address, height)
+		if err != nil {
+			return ethStateError(req, err)
+		}
+		return types.NewEthResult(req.Id, hexutil.EncodeBig(balance))
+	case "eth_getTransactionCount":
+		address, err := ethAddressParam(req)
+		if err != nil {
+			return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+		}
+		height, resp := rpc.ethHeightParam(req, 1)
+		if resp != nil {
+			return resp
+		}
+		nonce, err := rpc.db.GetAccountNonce(address, height)
+		if err != nil {
+			return ethStateError(req, err)
+		}
+		return types.NewEthResult(req.Id, hexutil.EncodeBig(nonce))
+	case "eth_getBlockByNumber":
+		return rpc.ethGetBlockByNumber(ctx, req)
+	case "eth_sendRawTransaction":
+		return rpc.ethSendRawTransaction(ctx, req)
+	case "eth_getTransactionReceipt":
+		return rpc.ethGetTransactionReceipt(ctx, req)
+	default:
+		return types.NewEthError(req.Id, types.EthErrMethodNotFound, fmt.Sprintf("method %s not supported", req.Method))
+	}
+}
+
+func (rpc *Rpc) ethSendRawTransaction(ctx context.Context, req *types.EthRequest) *types.EthResponse {
+	var raw string
+	if err := ethParam(req, 0, &raw); err != nil {
+		return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+	}
+
+	tx, ethTx, err := types.NewEthTransferTx(raw, rpc.ethConfig.ChainId)
+	if err != nil {
+		return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+	}
+
+	j, err := json.Marshal(tx)
+	if err != nil {
+		rpc.log.Error(types.EthHandlerTitle, types.ErrEncodeTx, err)
+		return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+	}
+
+	result, err := rpc.tdClient.BroadcastTxSync(ctx, tmTypes.Tx(j))
+	if err != nil {
+		rpc.log.Error(types.EthHandlerTitle, types.ErrBroadcastTxSync, err)
+		return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+	}
+
+	if result.Code != 0 {
+		resp := types.NewEthError(req.Id, types.EthErrTxRejected, result.Log)
+		resp.Error.Data = types.NewRpcTxError(result.Code, result.Codespace, result.Log)
+		return resp
+	}
+
+	return types.NewEthResult(req.Id, ethTx.Hash().Hex())
+}
+
+func (rpc *Rpc) ethGetTransactionReceipt(ctx context.Context, req *types.EthRequest) *types.EthResponse {
+	var hash string
+	if err := ethParam(req, 0, &hash); err != nil {
+		return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+	}
+
+	record, err := rpc.db.GetTx(types.NormalizeTxHash(hash))
+	if err != nil {
+		return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+	}
+	if record == nil {
+		return types.NewEthResult(req.Id, nil)
+	}
+
+	info, err := rpc.tdClient.BlockchainInfo(ctx, record.Height, record.Height)
+	if err != nil || len(info.BlockMetas) == 0 {
+		return types.NewEthError(req.Id, types.EthErrInternal, fmt.Sprintf("block %d not found", record.Height))
+	}
+	blockHash := hexutil.Encode(info.BlockMetas[0].BlockID.Hash)
+
+	status := hexutil.EncodeUint64(1)
+	if record.Code != 0 {
+		status = hexutil.EncodeUint64(0)
+	}
+
+	receipt := ethTxObject(record, blockHash)
+	receipt["transactionHash"] = receipt["hash"]
+	receipt["status"] = status
+	receipt["gasUsed"] = hexutil.EncodeUint64(uint64(record.GasUsed))
+	receipt["cumulativeGasUsed"] = hexutil.EncodeUint64(uint64(record.GasUsed))
+	receipt["effectiveGasPrice"] = "0x0"
+	receipt["contractAddress"] = nil
+	receipt["logs"] = []interface{}{}
+	receipt["logsBloom"] = hexutil.Encode(ethTypes.Bloom{}.Bytes())
+
+	return types.NewEthResult(req.Id, receipt)
+}
+
+func (rpc *Rpc) ethGetBlockByNumber(ctx context.Context, req *types.EthRequest) *types.EthResponse {
+	var tag string
+	if err := ethParam(req, 0, &tag); err != nil {
+		return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+	}
+	full := false
+	if len(req.Params) > 1 {
+		if err := ethParam(req, 1, &full); err != nil {
+			return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+		}
+	}
+
+	var height *int64
+	switch tag {
+	case "latest", "pending", "safe", "finalized":
+	case "earliest":
+		h := int64(1)
+		height = &h
+	default:
+		n, err := hexutil.DecodeUint64(tag)
+		if err != nil {
+			return types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+		}
+		h := int64(n)
+		height = &h
+	}
+
+	block, err := rpc.tdClient.Block(ctx, height)
+	if err != nil {
+		if height != nil {
+			// unknown heights are null, like for any ethereum node
+			return types.NewEthResult(req.Id, nil)
+		}
+		return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+	}
+
+	results, err := rpc.tdClient.BlockResults(ctx, &block.Block.Height)
+	if err != nil {
+		return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+	}
+
+	blockHash := hexutil.Encode(block.BlockID.Hash)
+	txs := make([]interface{}, 0, len(block.Block.Txs))
+	for idx, tx := range block.Block.Txs {
+		record := types.NewTxRecord(tx, block.Block.Height, uint32(idx), results.TxsResults[idx])
+		obj := ethTxObject(record, blockHash)
+		if full {
+			txs = append(txs, obj)
+		} else {
+			txs = append(txs, obj["hash"])
+		}
+	}
+
+	parentHash := common.Hash{}.Hex()
+	if len(block.Block.LastBlockID.Hash) != 0 {
+		parentHash = hexutil.Encode(block.Block.LastBlockID.Hash)
+	}
+
+	return types.NewEthResult(req.Id, gin.H{
+		"number":           hexutil.EncodeUint64(uint64(block.Block.Height)),
+		"hash":             blockHash,
+		"parentHash":       parentHash,
+		"timestamp":        hexutil.EncodeUint64(uint64(block.Block.Time.Unix())),
+		"miner":            common.BytesToAddress(block.Block.ProposerAddress).Hex(),
+		"stateRoot":        hexutil.Encode(common.BytesToHash(block.Block.AppHash).Bytes()),
+		"transactionsRoot": hexutil.Encode(common.BytesToHash(block.Block.DataHash).Bytes()),
+		"receiptsRoot":     ethTypes.EmptyReceiptsHash.Hex(),
+		"sha3Uncles":       ethTypes.EmptyUncleHash.Hex(),
+		"logsBloom":        hexutil.Encode(ethTypes.Bloom{}.Bytes()),
+		"mixHash":          common.Hash{}.Hex(),
+		"nonce":            hexutil.Encode(make([]byte, 8)),
+		"difficulty":       "0x0",
+		"totalDifficulty":  "0x0",
+		"extraData":        "0x",
+		"size":             hexutil.EncodeUint64(uint64(block.Block.Size())),
+		"gasLimit":         "0x0",
+		"gasUsed":          "0x0",
+		"baseFeePerGas":    "0x0",
+		"uncles":           []string{},
+		"transactions":     txs,
+	})
+}
+
+// ethTxObject presents a delivered tx like an ethereum tx. Mints are sent to the minter itself, blobs have no recipient.
+func ethTxObject(record *types.TxRecord, blockHash string) gin.H {
+	hash := "0x" + strings.ToLower(record.Hash)
+	if len(record.EthHash) != 0 {
+		hash = record.EthHash
+	}
+
+	var to interface{}
+	switch record.Type {
+	case types.Mint:
+		to = record.Sender
+	case types.Transfer:
+		to = record.Recipient
+	}
+
+	return gin.H{
+		"hash":             hash,
+		"blockHash":        blockHash,
+		"blockNumber":      hexutil.EncodeUint64(uint64(record.Height)),
+		"transactionIndex": hexutil.EncodeUint64(uint64(record.Index)),
+		"from":             record.Sender,
+		"to":               to,
+		"value":            record.Amount,
+		"nonce":            "0x0",
+		"gas":              "0x0",
+		"gasPrice":         "0x0",
+		"input":            "0x",
+		"type":             "0x0",
+	}
+}
+
+func ethParam(req *types.EthRequest, idx int, v interface{}) error {
+	if len(req.Params) <= idx {
+		return fmt.Errorf("missing param %d", idx)
+	}
+	return json.Unmarshal(req.Params[idx], v)
+}
+
+// ethHeightParam returns the height of the optional block param at idx, the last committed height for latest
+// and pending blocks and the oldest kept height for earliest, or the error response of the request.
+func (rpc *Rpc) ethHeightParam(req *types.EthRequest, idx int) (int64, *types.EthResponse) {
+	tag := "latest"
+	if len(req.Params) > idx {
+		if err := ethParam(req, idx, &tag); err != nil {
+			return 0, types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+		}
+	}
+
+	switch tag {
+	case "latest", "pending", "safe", "finalized", "earliest":
+		oldest, latest, err := rpc.db.GetHistoryRange()
+		if err != nil {
+			return 0, types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+		}
+		if tag == "earliest" {
+			return oldest, nil
+		}
+		return latest, nil
+	}
+	n, err := hexutil.DecodeUint64(tag)
+	if err != nil {
+		return 0, types.NewEthError(req.Id, types.EthErrInvalidParams, err.Error())
+	}
+	if n == 0 || n > math.MaxInt64 {
+		return 0, types.NewEthError(req.Id, types.EthErrInvalidParams, fmt.Sprintf("no state at block %s", tag))
+	}
+	return int64(n), nil
+}
+
+// ethStateError is the response to a failed state read, a height whose state is not kept is not found.
+func ethStateError(req *types.EthRequest, err error) *types.EthResponse {
+	var notAvailable *types.StateNotAvailableError
+	if errors.As(err, &notAvailable) {
+		return types.NewEthError(req.Id, types.EthErrNotFound, err.Error())
+	}
+	return types.NewEthError(req.Id, types.EthErrInternal, err.Error())
+}
+
+func ethAddressParam(req *types.EthRequest) (common.Address, error) {
+	var address string
+	if err := ethParam(req, 0, &address); err != nil {
+		return common.Address{}, err
+	}
+	if !common.IsHexAddress(address) {
+		return common.Address{}, fmt.Errorf("invalid address %s", address)
+	}
+	return common.HexToAddress(address), nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nbnet/side-chain/core/types"
+)
+
+// checkManifest verifies the form, signature and nonce of a manifest, that every part is a blob its address
+// delivered successfully and that the address can pay the fee.
+func (s *Abci) checkManifest(tx *types.Tx) internalResult {
+	var body types.ManifestBody
+	err := mapstructure.Decode(tx.Body, &body)
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrDecodeManifestBody, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrDecodeManifestBody),
+			log:  types.ErrDecodeManifestBody,
+			info: err.Error(),
+		}
+	}
+
+	if errName := body.Validate(); len(errName) != 0 {
+		return internalResult{
+			code: types.ErrorCode(errName),
+			log:  errName,
+		}
+	}
+	address := common.HexToAddress(body.Address)
+
+	digestHash, err := body.DigestHash()
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrCalculateDigestHash, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrCalculateDigestHash),
+			log:  types.ErrCalculateDigestHash,
+			info: err.Error(),
+		}
+	}
+
+	if err := tx.VerifySignature(address, digestHash); err != nil {
+		s.log.Debug(types.ProcessTxTitle, types.ErrVerifySignature, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrVerifySignature),
+			log:  types.ErrVerifySignature,
+			info: err.Error(),
+		}
+	}
+
+	checked := s.checkNonce(address, body.Nonce)
+	if checked.code != 0 {
+		return checked
+	}
+
+	if result := s.checkManifestParts(&body, address); result.code != 0 {
+		return result
+	}
+
+	gas := body.Gas()
+	balance, err := s.Db.GetAccountBalance(address, types.LatestHeight)
+	if err != nil {
+		return internalResult{
+			code: types.ErrorCode(types.ErrGetBalance),
+			log:  types.ErrGetBalance,
+			info: err.Error(),
+		}
+	}
+
+	if balance.Cmp(gas) < 0 {
+		return internalResult{
+			code: types.ErrorCode(types.ErrInsufficientBalance),
+			log:  types.ErrInsufficientBalance,
+			gas:  gas.Int64(),
+		}
+	}
+
+	checked.gas = gas.Int64()
+	return checked
+}
+
+// checkManifestParts looks the parts of a manifest up in the tx index, the result carries their total size.
+// Only delivered txs are indexed, so a part in the same block as the manifest has to come first.
+func (s *Abci) checkManifestParts(body *types.ManifestBody, address common.Address) internalResult {
+	size := 0
+	for _, part := range body.Parts {
+		record, err := s.Db.GetTx(part)
+		if err != nil {
+			s.log.Error(types.ProcessTxTitle, types.ErrGetTx, err)
+			return internalResult{
+				code: types.ErrorCode(types.ErrGetTx),
+				log:  types.ErrGetTx,
+				info: err.Error(),
+			}
+		}
+
+		if record == nil {
+			return internalResult{
+				code: types.ErrorCode(types.ErrManifestPartMissing),
+				log:  types.ErrManifestPartMissing,
+				info: part,
+			}
+		}
+
+		// the hash of an ethereum tx also finds its record, which is not a blob
+		if record.Type != types.Blob || record.Code != 0 || record.Hash != part ||
+			common.HexToAddress(record.Sender) != address {
+			return internalResult{
+				code: types.ErrorCode(types.ErrInvalidManifestPart),
+				log:  types.ErrInvalidManifestPart,
+				info: part,
+			}
+		}
+		size += record.BlobSize
+	}
+
+	return internalResult{blobSize: size}
+}
+
+// deliverManifest checks the parts again, earlier txs of the block may have been indexed since CheckTx, then
+// bumps the nonce and charges the fee. The balance is checked again like for a transfer.
+func (s *Abci) deliverManifest(tx *types.Tx) internalResult {
+	var body types.ManifestBody
+	// Success by default, only successful in checkTx will reach here
+	_ = mapstructure.Decode(tx.Body, &body)
+	address := common.HexToAddress(body.Address)
+	gas := body.Gas()
+
+	result := s.checkManifestParts(&body, address)
+	result.address = address
+	result.ty = tx.Ty
+	result.namespace = body.Namespace
+	if result.code != 0 {
+		return result
+	}
+
+	if err := s.Db.UpdateAccountNonce(address); err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrUpdateNonce, err)
+		result.code = types.ErrorCode(types.ErrUpdateNonce)
+		result.log = types.ErrUpdateNonce
+		result.info = err.Error()
+		return result
+	}
+
+	balance, err := s.Db.GetAccountBalance(address, types.LatestHeight)
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrGetBalance, err)
+		result.code = types.ErrorCode(types.ErrGetBalance)
+		result.log = types.ErrGetBalance
+		result.info = err.Error()
+		return result
+	}
+
+	if balance.Cmp(gas) < 0 {
+		result.code = types.ErrorCode(types.ErrInsufficientBalance)
+		result.log = types.ErrInsufficientBalance
+		return result
+	}
+
+	if err := s.Db.SubAccountBalance(address, gas); err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrUpdateBalance, err)
+		result.code = types.ErrorCode(types.ErrUpdateBalance)
+		result.log = types.ErrUpdateBalance
+		result.info = err.Error()
+		return result
+	}
+
+	result.gas = gas.Int64()
+	return result
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nbnet/side-chain/core/types"
+	"strconv"
+)
+
+// objectHandler streams the object of a manifest, the decompressed data of its parts in order. The id of the
+// object is the hash of its manifest tx. Size and digest of the manifest are sent as headers, the chain does not
+// check them against the parts, so the body is streamed without Content-Length. It is cut short when a part can
+// not be read, readers verify size and digest.
+func (rpc *Rpc) objectHandler(c *gin.Context) {
+	id := types.NormalizeTxHash(c.Param("id"))
+
+	record, err := rpc.db.GetTx(id)
+	if err != nil {
+		rpc.log.Error(types.ObjectHandlerTitle, types.ErrGetTx, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetTx, err), nil))
+		return
+	}
+	if record == nil || record.Type != types.Manifest || record.Code != 0 {
+		c.JSON(404, types.NewRpcResp(types.NewRpcError(types.ErrObjectNotFound, nil), nil))
+		return
+	}
+
+	var manifest types.ManifestBody
+	if err := rpc.txBody(c.Request.Context(), id, types.Manifest, &manifest); err != nil {
+		rpc.log.Error(types.ObjectHandlerTitle, types.ErrReadObject, err)
+		c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrReadObject, err), nil))
+		return
+	}
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("X-Object-Size", strconv.FormatInt(manifest.Size, 10))
+	c.Header("X-Object-Digest", manifest.Digest)
+	c.Header("X-Object-Parts", strconv.Itoa(len(manifest.Parts)))
+	c.Status(200)
+
+	for _, part := range manifest.Parts {
+		var body types.BlobBody
+		if err := rpc.txBody(c.Request.Context(), part, types.Blob, &body); err != nil {
+			rpc.log.Error(types.ObjectHandlerTitle, types.ErrReadObject, err, "id", id, "part", part)
+			return
+		}
+		if _, err := body.Decompress(c.Writer); err != nil {
+			rpc.log.Error(types.ObjectHandlerTitle, types.ErrReadObject, err, "id", id, "part", part)
+			return
+		}
+	}
+}
+
+// txBody decodes the body of the delivered tx with the given hash and type into body.
+func (rpc *Rpc) txBody(ctx context.Context, hash string, ty types.TxType, body interface{}) error {
+	b, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+
+	result, err := rpc.tdClient.Tx(ctx, b, false)
+	if err != nil {
+		return err
+	}
+
+	var tx types.Tx
+	if err := json.Unmarshal(result.Tx, &tx); err != nil {
+		return err
+	}
+	if tx.Ty != ty {
+		return fmt.Errorf("tx %s is a %s, not a %s", hash, tx.Ty, ty)
+	}
+	return mapstructure.Decode(tx.Body, body)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OpenApiSpec documents the RPC service, it is served at /openapi.json. Every route must be described
+// in it, its path and query parameters are validated against it before the handler runs.
+//
+//go:embed openapi.json
+var OpenApiSpec []byte
+
+type openApi struct {
+	Paths map[string]map[string]openApiOperation `json:"paths"`
+}
+
+type openApiOperation struct {
+	Parameters []openApiParameter `json:"parameters"`
+	// Security is empty for public operations, nil for those of the global security
+	Security *[]map[string][]string `json:"security"`
+}
+
+type openApiParameter struct {
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Schema   openApiSchema `json:"schema"`
+}
+
+type openApiSchema struct {
+	Type    string   `json:"type"`
+	Format  string   `json:"format"`
+	Pattern string   `json:"pattern"`
+	Enum    []string `json:"enum"`
+	Minimum *int64   `json:"minimum"`
+	Maximum *int64   `json:"maximum"`
+}
+
+func loadOpenApi() *openApi {
+	spec := new(openApi)
+	if err := json.Unmarshal(OpenApiSpec, spec); err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+// OpenApiPath converts a gin route path like /tx/:hash to its OpenAPI form /tx/{hash}.
+func OpenApiPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// route registers a handler behind the validation of its documented parameters. Registering an
+// undocumented route panics, so the spec can not fall behind the handlers. Operations documented with an
+// empty security are public, auth lets them pass.
+func (rpc *Rpc) route(method, path string, handler gin.HandlerFunc) {
+	operation, ok := rpc.openApi.Paths[OpenApiPath(path)][strings.ToLower(method)]
+	if !ok {
+		panic(fmt.Sprintf("route %s %s missing in openapi.json", method, path))
+	}
+	if operation.Security != nil && len(*operation.Security) == 0 {
+		rpc.public[method+" "+path] = true
+	}
+
+	validators := make([]func(c *gin.Context) *types.RpcError, 0, len(operation.Parameters))
+	for _, param := range operation.Parameters {
+		validators = append(validators, newParamValidator(param))
+	}
+
+	rpc.engine.Handle(method, path, func(c *gin.Context) {
+		for _, validate := range validators {
+			if rpcErr := validate(c); rpcErr != nil {
+				rpc.log.Error(types.ValidateTitle, rpcErr.Message, rpcErr.Details)
+				c.AbortWithStatusJSON(400, types.NewRpcResp(rpcErr, nil))
+				return
+			}
+		}
+		handler(c)
+	})
+}
+
+func newParamValidator(param openApiParameter) func(c *gin.Context) *types.RpcError {
+	var pattern *regexp.Regexp
+	if len(param.Schema.Pattern) != 0 {
+		pattern = regexp.MustCompile(param.Schema.Pattern)
+	}
+
+	errName := types.ErrInvalidQuery
+	if param.Schema.Format == "address" {
+		errName = types.ErrInvalidAddress
+	}
+
+	return func(c *gin.Context) *types.RpcError {
+		value := c.Query(param.Name)
+		if param.In == "path" {
+			value = c.Param(param.Name)
+		}
+
+		if len(value) == 0 {
+			if param.Required {
+				return types.NewRpcError(errName, fmt.Errorf("missing parameter %s", param.Name))
+			}
+			return nil
+		}
+
+		if pattern != nil && !pattern.MatchString(value) {
+			return types.NewRpcError(errName, fmt.Errorf("parameter %s does not match %s", param.Name, param.Schema.Pattern))
+		}
+
+		if len(param.Schema.Enum) != 0 {
+			found := false
+			for _, e := range param.Schema.Enum {
+				found = found || e == value
+			}
+			if !found {
+				return types.NewRpcError(errName, fmt.Errorf("parameter %s must be one of %s", param.Name, strings.Join(param.Schema.Enum, ", ")))
+			}
+		}
+
+		if param.Schema.Type == "integer" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return types.NewRpcError(errName, fmt.Errorf("parameter %s is not an integer", param.Name))
+			}
+			if param.Schema.Minimum != nil && n < *param.Schema.Minimum || param.Schema.Maximum != nil && n > *param.Schema.Maximum {
+				return types.NewRpcError(errName, fmt.Errorf("parameter %s out of range", param.Name))
+			}
+		}
+
+		return nil
+	}
+}
+
+func (rpc *Rpc) openApiHandler(c *gin.Context) {
+	c.Data(200, "application/json", OpenApiSpec)
+}
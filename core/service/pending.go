@@ -0,0 +1,60 @@
+package service
+
+import (
+	"github.com/nbnet/side-chain/core/types"
+	"sync"
+	"time"
+)
+
+// PendingTxs tracks the hashes of txs accepted by CheckTx that have not been delivered yet.
+// It only lives in memory, the mempool is not persisted across restarts either. The mempool rechecks its txs
+// after every block, which refreshes them, so a tx evicted from the mempool expires after ttl.
+type PendingTxs struct {
+	mu  sync.RWMutex
+	txs map[string]time.Time
+	ttl time.Duration
+	// swept is when the expired txs were last removed
+	swept time.Time
+}
+
+func NewPendingTxs() *PendingTxs {
+	return NewPendingTxsWithTTL(types.PendingTxTTL)
+}
+
+func NewPendingTxsWithTTL(ttl time.Duration) *PendingTxs {
+	return &PendingTxs{
+		txs:   make(map[string]time.Time),
+		ttl:   ttl,
+		swept: time.Now(),
+	}
+}
+
+// Add records a tx accepted by CheckTx, or refreshes it when it passes a recheck.
+func (p *PendingTxs) Add(hash string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.txs[hash] = now
+
+	if now.Sub(p.swept) >= p.ttl {
+		for h, added := range p.txs {
+			if now.Sub(added) >= p.ttl {
+				delete(p.txs, h)
+			}
+		}
+		p.swept = now
+	}
+}
+
+func (p *PendingTxs) Remove(hash string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.txs, hash)
+}
+
+func (p *PendingTxs) Has(hash string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	added, ok := p.txs[hash]
+	return ok && time.Since(added) < p.ttl
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter counts requests and request body bytes per identity in fixed windows.
+type rateLimiter struct {
+	mu       sync.Mutex
+	window   time.Duration
+	requests int64
+	bytes    int64
+	counters map[string]*rateCounter
+	swept    time.Time
+}
+
+type rateCounter struct {
+	start    time.Time
+	requests int64
+	bytes    int64
+}
+
+func newRateLimiter(window time.Duration, requests, bytes int64) *rateLimiter {
+	if requests <= 0 && bytes <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		window:   window,
+		requests: requests,
+		bytes:    bytes,
+		counters: make(map[string]*rateCounter),
+	}
+}
+
+// counter returns the counter of the current window of id, expired counters are dropped once per window.
+func (l *rateLimiter) counter(id string, now time.Time) *rateCounter {
+	if now.Sub(l.swept) >= l.window {
+		for k, counter := range l.counters {
+			if now.Sub(counter.start) >= l.window {
+				delete(l.counters, k)
+			}
+		}
+		l.swept = now
+	}
+
+	counter, ok := l.counters[id]
+	if !ok || now.Sub(counter.start) >= l.window {
+		counter = &rateCounter{start: now}
+		l.counters[id] = counter
+	}
+	return counter
+}
+
+// take accounts a request announcing size body bytes, it fails when a limit would be exceeded.
+func (l *rateLimiter) take(id string, size int64, now time.Time) (rateCounter, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter := l.counter(id, now)
+	if l.requests > 0 && counter.requests >= l.requests || l.bytes > 0 && size > 0 && counter.bytes+size > l.bytes {
+		return *counter, false
+	}
+
+	counter.requests++
+	if size > 0 {
+		counter.bytes += size
+	}
+	return *counter, true
+}
+
+// charge accounts body bytes read beyond the announced size, e.g. of chunked requests.
+func (l *rateLimiter) charge(id string, n int64, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.counter(id, now).bytes += n
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// rateLimit limits authenticated requests per identity and anonymous requests per client ip. The quota
+// of the current window is returned in X-RateLimit-* headers, exceeded limits are answered with 429.
+func (rpc *Rpc) rateLimit(c *gin.Context) {
+	id, ok := c.Get(identityKey)
+	limiter := rpc.keyLimiter
+	if !ok {
+		id = "ip:" + c.ClientIP()
+		limiter = rpc.ipLimiter
+	}
+	if limiter == nil {
+		return
+	}
+
+	size := c.Request.ContentLength
+	now := time.Now()
+	counter, ok := limiter.take(id.(string), size, now)
+
+	reset := counter.start.Add(limiter.window).Sub(now)
+	if limiter.requests > 0 {
+		c.Header("X-RateLimit-Limit", strconv.FormatInt(limiter.requests, 10))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(max(limiter.requests-counter.requests, 0), 10))
+	}
+	if limiter.bytes > 0 {
+		c.Header("X-RateLimit-Bytes-Limit", strconv.FormatInt(limiter.bytes, 10))
+		c.Header("X-RateLimit-Bytes-Remaining", strconv.FormatInt(max(limiter.bytes-counter.bytes, 0), 10))
+	}
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(int64(reset.Seconds()+0.999), 10))
+
+	if !ok {
+		err := fmt.Errorf("%s exceeded its quota, retry in %s", id, reset.Round(time.Second))
+		rpc.log.Error(types.RateLimitTitle, types.ErrRateLimited, err)
+		c.Header("Retry-After", strconv.FormatInt(int64(reset.Seconds()+0.999), 10))
+		c.AbortWithStatusJSON(429, types.NewRpcResp(types.NewRpcError(types.ErrRateLimited, err), nil))
+		return
+	}
+
+	if limiter.bytes <= 0 || c.Request.Body == nil || size >= 0 {
+		return
+	}
+
+	// the size of chunked bodies is only known once they are read
+	body := &countingReader{ReadCloser: c.Request.Body}
+	c.Request.Body = body
+	c.Next()
+	limiter.charge(id.(string), body.n, time.Now())
+}
@@ -2,29 +2,62 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"github.com/mitchellh/mapstructure"
 	"github.com/nbnet/side-chain/core/types"
 	tmLog "github.com/tendermint/tendermint/libs/log"
 	tmClient "github.com/tendermint/tendermint/rpc/client/http"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
 	tmTypes "github.com/tendermint/tendermint/types"
 	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
 type Rpc struct {
 	rpcConfig *types.RpcConfig
+	ethConfig *types.EthConfig
 	db        types.Db
 	log       types.CustomLogger
 	engine    *gin.Engine
 	tdClient  *tmClient.HTTP
+	pending   *PendingTxs
+	eventBus  *tmTypes.EventBus
+	openApi   *openApi
+	blobKey   *blobKey
+	// public holds the routes documented without security, by method and path
+	public map[string]bool
+
+	authConfig *types.AuthConfig
+	keyLimiter *rateLimiter
+	ipLimiter  *rateLimiter
+
+	server   *http.Server
+	listener net.Listener
+	errs     chan error
+	draining atomic.Bool
+	// ctx is cancelled on Stop, it ends the websocket streams which the server does not track
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewRpc(rpcConfig *types.RpcConfig, db types.Db, logger tmLog.Logger, output io.Writer) *Rpc {
+func NewRpc(config *types.Config, db types.Db, pending *PendingTxs, eventBus *tmTypes.EventBus, logger tmLog.Logger, output io.Writer) *Rpc {
+
+	if !types.ValidCodec(config.Rpc.BlobCodec) {
+		panic(fmt.Sprintf("unknown blob codec %s", config.Rpc.BlobCodec))
+	}
 
-	tdClient, err := tmClient.New(rpcConfig.TdRpc, "/websocket")
+	tdClient, err := tmClient.New(config.Rpc.TdRpc, "/websocket")
 	if err != nil {
 		panic(err)
 	}
@@ -36,85 +69,669 @@ func NewRpc(rpcConfig *types.RpcConfig, db types.Db, logger tmLog.Logger, output
 	engine.Use(gin.LoggerWithWriter(output))
 	engine.Use(gin.RecoveryWithWriter(output))
 
-	return &Rpc{
-		rpcConfig: rpcConfig,
+	rpc := &Rpc{
+		rpcConfig: config.Rpc,
+		ethConfig: config.Eth,
 		db:        db,
 		log: types.CustomLogger{
 			Logger: logger,
 		},
 		engine:   engine,
 		tdClient: tdClient,
+		pending:  pending,
+		eventBus: eventBus,
+		openApi:  loadOpenApi(),
+		public:   make(map[string]bool),
+
+		authConfig: config.Auth,
+		errs:       make(chan error, 1),
+	}
+	rpc.ctx, rpc.cancel = context.WithCancel(context.Background())
+
+	if len(config.Rpc.BlobKey) != 0 {
+		if rpc.blobKey, err = loadBlobKey(config.Rpc.BlobKey); err != nil {
+			panic(fmt.Sprintf("load blob key: %s", err))
+		}
 	}
+
+	var trustedProxies []string
+	if limit := config.RateLimit; limit != nil {
+		window := time.Duration(limit.Window) * time.Second
+		if window <= 0 {
+			window = time.Duration(types.DefaultRateLimitWindow) * time.Second
+		}
+		rpc.keyLimiter = newRateLimiter(window, limit.KeyRequests, limit.KeyBytes)
+		rpc.ipLimiter = newRateLimiter(window, limit.IpRequests, limit.IpBytes)
+		trustedProxies = limit.TrustedProxies
+	}
+	if err := engine.SetTrustedProxies(trustedProxies); err != nil {
+		panic(err)
+	}
+
+	engine.Use(rpc.auth, rpc.rateLimit)
+
+	rpc.route("GET", "/balance/:address", rpc.balanceHandler)
+	rpc.route("GET", "/nonce/:address", rpc.nonceHandler)
+	rpc.route("POST", "/blob", rpc.blobHandler)
+	rpc.route("POST", "/blob/upload", rpc.uploadBlobHandler)
+	rpc.route("GET", "/blob/:hash", rpc.getBlobHandler)
+	rpc.route("POST", "/tx", rpc.broadcastTxHandler)
+	rpc.route("GET", "/blobs", rpc.blobsHandler)
+	rpc.route("GET", "/object/:id", rpc.objectHandler)
+	rpc.route("GET", "/txs", rpc.txsHandler)
+	rpc.route("GET", "/tx/:hash", rpc.txHandler)
+	rpc.route("GET", "/blocks/latest", rpc.latestBlockHandler)
+	rpc.route("GET", "/blocks/:height", rpc.blockHandler)
+	rpc.route("GET", "/validators", rpc.validatorsHandler)
+	rpc.route("GET", "/stats", rpc.statsHandler)
+	rpc.route("GET", "/status", rpc.statusHandler)
+	rpc.route("GET", "/ws", rpc.streamHandler)
+	rpc.route("POST", "/eth", rpc.ethHandler)
+	rpc.route("GET", "/openapi.json", rpc.openApiHandler)
+	rpc.route("GET", "/ready", rpc.readyHandler)
+	rpc.route("GET", "/dashboard", rpc.dashboardHandler)
+
+	return rpc
 }
 
-func (rpc *Rpc) Start() {
-	go func() {
-		rpc.log.Info("Starting RPC service")
+// Start listens on the configured address, with TLS if a certificate is configured, and serves in the
+// background. It returns once the service accepts connections, or the error preventing it to listen.
+func (rpc *Rpc) Start() error {
+	rpc.server = &http.Server{
+		Handler:           rpc.engine,
+		ReadHeaderTimeout: types.DefaultReadHeaderTimeout,
+	}
+
+	tlsEnabled := len(rpc.rpcConfig.TlsCert) != 0 || len(rpc.rpcConfig.TlsKey) != 0
+	if tlsEnabled {
+		cert, err := tls.LoadX509KeyPair(rpc.rpcConfig.TlsCert, rpc.rpcConfig.TlsKey)
+		if err != nil {
+			return fmt.Errorf("load tls certificate: %w", err)
+		}
+		rpc.server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", rpc.rpcConfig.Host, rpc.rpcConfig.Port))
+	if err != nil {
+		return fmt.Errorf("rpc listen: %w", err)
+	}
+	rpc.listener = listener
+
+	rpc.log.Info("Starting RPC service", "addr", listener.Addr().String(), "tls", tlsEnabled)
 
-		rpc.engine.GET("/balance/:address", rpc.balanceHandler)
-		rpc.engine.GET("/nonce/:address", rpc.nonceHandler)
-		rpc.engine.POST("/blob", rpc.blobHandler)
-		rpc.engine.Run(fmt.Sprintf("%s:%d", rpc.rpcConfig.Host, rpc.rpcConfig.Port))
+	go func() {
+		var err error
+		if tlsEnabled {
+			err = rpc.server.ServeTLS(listener, "", "")
+		} else {
+			err = rpc.server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			rpc.errs <- err
+		}
 	}()
 
-	time.Sleep(time.Second * 3)
+	return nil
+}
+
+// Addr returns the address the service listens on, once started.
+func (rpc *Rpc) Addr() string {
+	if rpc.listener == nil {
+		return ""
+	}
+	return rpc.listener.Addr().String()
+}
+
+// Errors reports the error the service stopped serving with.
+func (rpc *Rpc) Errors() <-chan error {
+	return rpc.errs
+}
+
+// Stop stops accepting connections and waits until in-flight requests, e.g. blob uploads, completed or
+// ctx expired, then closes the remaining connections and the websocket streams. /ready reports 503 meanwhile.
+// The tendermint node has to be stopped after the service, so waiting requests see their txs committed.
+func (rpc *Rpc) Stop(ctx context.Context) error {
+	rpc.draining.Store(true)
+	defer rpc.cancel()
+
+	if rpc.server == nil {
+		return nil
+	}
+
+	rpc.log.Info("Stopping RPC service")
+	if err := rpc.server.Shutdown(ctx); err != nil {
+		_ = rpc.server.Close()
+		return err
+	}
+	return nil
+}
+
+// readyHandler reports whether the service accepts requests, for load balancers and orchestrators.
+func (rpc *Rpc) readyHandler(c *gin.Context) {
+	if rpc.draining.Load() {
+		c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrShuttingDown, nil), types.NewRpcReadyData(false, 1)))
+		return
+	}
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcReadyData(true, 0)))
+}
+
+// Handler returns the http handler of the RPC service.
+func (rpc *Rpc) Handler() http.Handler {
+	return rpc.engine
+}
+
+// Routes lists the registered routes.
+func (rpc *Rpc) Routes() gin.RoutesInfo {
+	return rpc.engine.Routes()
+}
+
+func (rpc *Rpc) maxBlobBytes() int {
+	if rpc.rpcConfig.MaxBlobBytes <= 0 {
+		return types.DefaultMaxBlobBytes
+	}
+	return rpc.rpcConfig.MaxBlobBytes
+}
+
+// blobCodec returns the codec of a blob submitted with the requested codec, the node policy if none.
+func (rpc *Rpc) blobCodec(requested string) string {
+	if len(requested) != 0 {
+		return requested
+	}
+	if len(rpc.rpcConfig.BlobCodec) != 0 {
+		return rpc.rpcConfig.BlobCodec
+	}
+	return types.DefaultBlobCodec
 }
 
 func (rpc *Rpc) balanceHandler(c *gin.Context) {
 	addressStr := c.Param("address")
 	address := common.HexToAddress(addressStr)
-	balance, err := rpc.db.GetAccountBalance(address)
+	height, err := strconv.ParseInt(c.DefaultQuery("height", "0"), 10, 64)
+	if err != nil || height < 0 {
+		rpc.log.Error(types.BalanceHandlerTitle, types.ErrInvalidQuery, c.Query("height"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcBalanceData(nil, 1)))
+		return
+	}
+
+	height, err = rpc.stateHeight(height)
+	var balance *big.Int
+	if err == nil {
+		balance, err = rpc.db.GetAccountBalance(address, height)
+	}
 	if err != nil {
-		rpc.log.Error(types.BalanceHandlerTitle, types.ErrGetBalance, err)
-		c.JSON(500, types.NewRpcResp(err, types.NewRpcBalanceData(nil, 1)))
+		status, errName := stateError(err, types.ErrGetBalance)
+		rpc.log.Error(types.BalanceHandlerTitle, errName, err)
+		c.JSON(status, types.NewRpcResp(types.NewRpcError(errName, err), types.NewRpcBalanceData(nil, 1)))
 		return
 	}
 
-	c.JSON(200, types.NewRpcResp(err, types.NewRpcBalanceData(balance, 0)))
+	data := types.NewRpcBalanceData(balance, 0)
+	data["height"] = height
+	c.JSON(200, types.NewRpcResp(nil, data))
 }
 
 func (rpc *Rpc) nonceHandler(c *gin.Context) {
 	addressStr := c.Param("address")
 	address := common.HexToAddress(addressStr)
-	nonce, err := rpc.db.GetAccountNonce(address)
+	height, err := strconv.ParseInt(c.DefaultQuery("height", "0"), 10, 64)
+	if err != nil || height < 0 {
+		rpc.log.Error(types.NonceHandlerTitle, types.ErrInvalidQuery, c.Query("height"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcNonceData(nil, 1)))
+		return
+	}
+
+	height, err = rpc.stateHeight(height)
+	var nonce *big.Int
+	if err == nil {
+		nonce, err = rpc.db.GetAccountNonce(address, height)
+	}
 	if err != nil {
-		rpc.log.Error(types.NonceHandlerTitle, types.ErrGetBalance, err)
-		c.JSON(500, types.NewRpcResp(err, types.NewRpcNonceData(nil, 1)))
+		status, errName := stateError(err, types.ErrGetNonce)
+		rpc.log.Error(types.NonceHandlerTitle, errName, err)
+		c.JSON(status, types.NewRpcResp(types.NewRpcError(errName, err), types.NewRpcNonceData(nil, 1)))
 		return
 	}
 
-	c.JSON(200, types.NewRpcResp(err, types.NewRpcNonceData(nonce, 0)))
+	data := types.NewRpcNonceData(nonce, 0)
+	data["height"] = height
+	c.JSON(200, types.NewRpcResp(nil, data))
+}
+
+// stateHeight resolves types.LatestHeight of a state query to the last committed height, so a query does not
+// read a block being executed. Before the first commit it stays at types.LatestHeight, the genesis state.
+func (rpc *Rpc) stateHeight(height int64) (int64, error) {
+	if height != types.LatestHeight {
+		return height, nil
+	}
+	_, latest, err := rpc.db.GetHistoryRange()
+	return latest, err
+}
+
+// stateError returns the status and error name of a failed state read, 404 for a height whose state is not kept.
+func stateError(err error, errName string) (int, string) {
+	var notAvailable *types.StateNotAvailableError
+	if errors.As(err, &notAvailable) {
+		return 404, types.ErrStateNotAvailable
+	}
+	return 500, errName
 }
 
 func (rpc *Rpc) blobHandler(c *gin.Context) {
+	maxBytes := rpc.maxBlobBytes()
+	if c.Request.ContentLength > int64(2*maxBytes+types.DefaultMaxNamespaceLength+1024) {
+		rpc.log.Error(types.BlobHandlerTitle, types.ErrBlobTooLarge, c.Request.ContentLength)
+		c.JSON(413, types.NewRpcResp(types.NewRpcError(types.ErrBlobTooLarge, nil), types.NewRpcBlobData(1, nil)))
+		return
+	}
+	// hex doubles the size, the rest of the body is small
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(2*maxBytes+types.DefaultMaxNamespaceLength+1024))
+
 	var body types.BlobBody
-	if err := c.BindJSON(&body); err != nil {
-		rpc.log.Error(types.BlobHandlerTitle, types.ErrDecodeMintBody, err)
-		c.JSON(400, types.NewRpcResp(err, types.NewRpcBlobData(1, nil)))
+	if err := c.ShouldBindJSON(&body); err != nil {
+		rpc.log.Error(types.BlobHandlerTitle, types.ErrDecodeBlobBody, err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(413, types.NewRpcResp(types.NewRpcError(types.ErrBlobTooLarge, err), types.NewRpcBlobData(1, nil)))
+			return
+		}
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrDecodeBlobBody, err), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	if errName := body.Validate(maxBytes); len(errName) != 0 {
+		rpc.log.Error(types.BlobHandlerTitle, errName, body.Address)
+		status := 400
+		if errName == types.ErrBlobTooLarge {
+			status = 413
+		}
+		c.JSON(status, types.NewRpcResp(types.NewRpcError(errName, nil), types.NewRpcBlobData(1, nil)))
 		return
 	}
 
 	tx := types.Tx{}
-	if err := tx.GenGzipCompressBlobTx(body); err != nil {
-		rpc.log.Error(types.BlobHandlerTitle, types.ErrGenGzipCompressBlobTx, err)
-		c.JSON(500, types.NewRpcResp(err, types.NewRpcBlobData(1, nil)))
+	if err := tx.GenCompressBlobTx(body, rpc.blobCodec(body.Codec)); err != nil {
+		rpc.log.Error(types.BlobHandlerTitle, types.ErrCompressBlobTx, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrCompressBlobTx, err), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	if rpc.blobKey.signs(body.Address) {
+		rpc.broadcastSigned(c, types.BlobHandlerTitle, func(nonce uint64, sign func([]byte) (string, error)) ([]byte, error) {
+			body := tx.Body.(types.BlobBody)
+			body.Nonce = nonce
+			digestHash, err := body.DigestHash()
+			if err != nil {
+				return nil, err
+			}
+			if tx.Signature, err = sign(digestHash); err != nil {
+				return nil, err
+			}
+			tx.Body = body
+			return json.Marshal(tx)
+		})
 		return
 	}
 
 	j, err := json.Marshal(tx)
 	if err != nil {
 		rpc.log.Error(types.BlobHandlerTitle, types.ErrEncodeTx, err)
-		c.JSON(500, types.NewRpcResp(err, types.NewRpcBlobData(1, nil)))
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrEncodeTx, err), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	rpc.broadcast(c, types.BlobHandlerTitle, j)
+}
+
+// broadcastTxHandler broadcasts a tx signed by the client, e.g. a mint, a natively signed transfer, a blob
+// the client compressed and signed or a manifest. With ?dry_run=true the tx is only checked, see dryRun.
+func (rpc *Rpc) broadcastTxHandler(c *gin.Context) {
+	maxBytes := rpc.maxBlobBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(2*maxBytes+types.DefaultMaxNamespaceLength+1024))
+
+	var tx types.Tx
+	if err := c.ShouldBindJSON(&tx); err != nil {
+		rpc.log.Error(types.BroadcastTxHandlerTitle, types.ErrDecodeTx, err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(413, types.NewRpcResp(types.NewRpcError(types.ErrBlobTooLarge, err), types.NewRpcBlobData(1, nil)))
+			return
+		}
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrDecodeTx, err), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	switch tx.Ty {
+	case types.Mint, types.Transfer:
+	case types.Blob:
+		// unsigned blobs are submitted through /blob, which validates and compresses them
+		if errName := rpc.validateSignedBlob(&tx, maxBytes); len(errName) != 0 {
+			rpc.log.Error(types.BroadcastTxHandlerTitle, errName, tx.Ty)
+			status := 400
+			if errName == types.ErrBlobTooLarge {
+				status = 413
+			}
+			c.JSON(status, types.NewRpcResp(types.NewRpcError(errName, nil), types.NewRpcBlobData(1, nil)))
+			return
+		}
+	case types.Manifest:
+		var body types.ManifestBody
+		errName := types.ErrDecodeManifestBody
+		if err := mapstructure.Decode(tx.Body, &body); err == nil {
+			errName = body.Validate()
+		}
+		if len(errName) != 0 {
+			rpc.log.Error(types.BroadcastTxHandlerTitle, errName, tx.Ty)
+			c.JSON(400, types.NewRpcResp(types.NewRpcError(errName, nil), types.NewRpcBlobData(1, nil)))
+			return
+		}
+	default:
+		rpc.log.Error(types.BroadcastTxHandlerTitle, types.ErrUnknownTxBody, tx.Ty)
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrUnknownTxBody, nil), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	j, err := json.Marshal(tx)
+	if err != nil {
+		rpc.log.Error(types.BroadcastTxHandlerTitle, types.ErrEncodeTx, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrEncodeTx, err), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		rpc.dryRun(c, j)
+		return
+	}
+
+	rpc.broadcast(c, types.BroadcastTxHandlerTitle, j)
+}
+
+// validateSignedBlob checks a blob compressed by the client: it has to be signed, stored with a concrete codec
+// and compressed to at most maxBytes bytes.
+func (rpc *Rpc) validateSignedBlob(tx *types.Tx, maxBytes int) string {
+	if !tx.Signed() {
+		return types.ErrVerifySignature
+	}
+
+	var body types.BlobBody
+	if err := mapstructure.Decode(tx.Body, &body); err != nil {
+		return types.ErrDecodeBlobBody
+	}
+	if errName := body.ValidateMeta(); len(errName) != 0 {
+		return errName
+	}
+	if len(body.Codec) == 0 || body.Codec == types.CodecAuto {
+		return types.ErrUnknownCodec
+	}
+	if body.Size() > maxBytes {
+		return types.ErrBlobTooLarge
+	}
+	return ""
+}
+
+// dryRun runs CheckTx on a tx against the committed state without broadcasting it. The response carries the
+// CheckTx result and the fee the tx would be charged in wei.
+func (rpc *Rpc) dryRun(c *gin.Context, j []byte) {
+	result, err := rpc.tdClient.ABCIQuery(c.Request.Context(), types.CheckTxQueryPath, j)
+	if err != nil {
+		rpc.log.Error(types.BroadcastTxHandlerTitle, types.ErrDryRunTx, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrDryRunTx, err), types.NewRpcDryRunData(nil, "")))
+		return
+	}
+
+	response := result.Response
+	data := types.NewRpcDryRunData(&response, types.TxHash(j))
+	if response.Code != 0 {
+		c.JSON(200, types.NewRpcResp(types.NewRpcTxError(response.Code, response.Codespace, response.Log), data))
+		return
+	}
+	c.JSON(200, types.NewRpcResp(nil, data))
+}
+
+// broadcast submits a tx to the mempool. With ?wait=commit the response is held until the tx is
+// delivered or ?timeout= (default 30s) expires, and carries the tx receipt. A timeout out of range is rejected
+// before the tx is broadcast.
+func (rpc *Rpc) broadcast(c *gin.Context, title string, j []byte) {
+	rpc.broadcastWith(c, title, func() (*coreTypes.ResultBroadcastTx, string, error) {
+		result, err := rpc.tdClient.BroadcastTxSync(c.Request.Context(), tmTypes.Tx(j))
+		return result, types.ErrBroadcastTxSync, err
+	})
+}
+
+// broadcastWith answers like broadcast for the tx broadcastTx submits, which reports the error name of a failure.
+func (rpc *Rpc) broadcastWith(c *gin.Context, title string, broadcastTx func() (*coreTypes.ResultBroadcastTx, string, error)) {
+	timeout := types.DefaultWaitCommitTimeout
+	if t := c.Query("timeout"); len(t) != 0 {
+		var err error
+		timeout, err = time.ParseDuration(t)
+		if err != nil || timeout <= 0 || timeout > types.MaxWaitCommitTimeout {
+			err = fmt.Errorf("timeout %s not in (0, %s]", t, types.MaxWaitCommitTimeout)
+			rpc.log.Error(title, types.ErrInvalidQuery, err)
+			c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, err), types.NewRpcBlobData(1, nil)))
+			return
+		}
+	}
+
+	result, errName, err := broadcastTx()
+	if err != nil {
+		rpc.log.Error(title, errName, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(errName, err), types.NewRpcBlobData(1, nil)))
+		return
+	}
+
+	if result.Code != 0 {
+		c.JSON(200, types.NewRpcResp(types.NewRpcTxError(result.Code, result.Codespace, result.Log), types.NewRpcBlobData(0, result)))
+		return
+	}
+
+	if c.Query("wait") != "commit" {
+		c.JSON(200, types.NewRpcResp(nil, types.NewRpcBlobData(0, result)))
+		return
+	}
+
+	record, err := rpc.waitCommit(c.Request.Context(), result.Hash.String(), timeout)
+	if err != nil {
+		rpc.log.Error(title, types.ErrWaitCommitTimeout, err)
+		data := types.NewRpcBlobData(0, result)
+		data["receipt"] = types.NewRpcTxData(nil, types.TxStatusPending, 1)
+		c.JSON(504, types.NewRpcResp(types.NewRpcError(types.ErrWaitCommitTimeout, err), data))
+		return
+	}
+
+	data := types.NewRpcBlobData(0, result)
+	data["receipt"] = types.NewRpcTxData(record, record.Status(), 0)
+	c.JSON(200, types.NewRpcResp(nil, data))
+}
+
+// getBlobHandler returns a blob tx as stored on chain, its data still compressed.
+func (rpc *Rpc) getBlobHandler(c *gin.Context) {
+	hash, err := hex.DecodeString(types.NormalizeTxHash(c.Param("hash")))
+	if err != nil {
+		rpc.log.Error(types.GetBlobHandlerTitle, types.ErrInvalidQuery, err)
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, err), types.NewRpcGetBlobData(nil, 0, 0, 1)))
+		return
+	}
+
+	prove := c.Query("prove") == "true"
+	result, err := rpc.tdClient.Tx(c.Request.Context(), hash, prove)
+	if err != nil {
+		rpc.log.Error(types.GetBlobHandlerTitle, types.ErrTxNotFound, err)
+		c.JSON(404, types.NewRpcResp(types.NewRpcError(types.ErrTxNotFound, nil), types.NewRpcGetBlobData(nil, 0, 0, 1)))
+		return
+	}
+
+	var tx types.Tx
+	if err := json.Unmarshal(result.Tx, &tx); err != nil || tx.Ty != types.Blob {
+		rpc.log.Error(types.GetBlobHandlerTitle, types.ErrDecodeBlobBody, err)
+		c.JSON(404, types.NewRpcResp(types.NewRpcError(types.ErrTxNotFound, nil), types.NewRpcGetBlobData(nil, 0, 0, 1)))
+		return
+	}
+
+	var body types.BlobBody
+	if err := mapstructure.Decode(tx.Body, &body); err != nil {
+		rpc.log.Error(types.GetBlobHandlerTitle, types.ErrDecodeBlobBody, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrDecodeBlobBody, err), types.NewRpcGetBlobData(nil, 0, 0, 1)))
+		return
+	}
+
+	data := types.NewRpcGetBlobData(&body, result.Height, result.TxResult.Code, 0)
+	data["hash"] = result.Hash.String()
+
+	// the proof links the tx to the data hash of the block header, which the validators signed
+	if prove {
+		info, err := rpc.tdClient.BlockchainInfo(c.Request.Context(), result.Height, result.Height)
+		if err != nil || len(info.BlockMetas) == 0 {
+			rpc.log.Error(types.GetBlobHandlerTitle, types.ErrGetBlockHeader, err)
+			c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrGetBlockHeader, err), types.NewRpcGetBlobData(nil, 0, 0, 1)))
+			return
+		}
+		data["proof"] = result.Proof
+		data["data_hash"] = info.BlockMetas[0].Header.DataHash.String()
+	}
+	c.JSON(200, types.NewRpcResp(nil, data))
+}
+
+// waitCommit polls the tx index until the tx with the given hash has been delivered or the timeout expires.
+func (rpc *Rpc) waitCommit(ctx context.Context, hash string, timeout time.Duration) (*types.TxRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(types.DefaultWaitCommitInterval)
+	defer ticker.Stop()
+
+	for {
+		record, err := rpc.db.GetTx(hash)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return record, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("tx %s not committed within %s", hash, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// blobsHandler lists the blobs of a namespace in a height range, oldest first. The records carry the tx
+// hashes the blobs are fetched with.
+func (rpc *Rpc) blobsHandler(c *gin.Context) {
+	namespace := c.Query("namespace")
+
+	fromHeight, err := strconv.ParseInt(c.DefaultQuery("from_height", "0"), 10, 64)
+	if err != nil || fromHeight < 0 {
+		rpc.log.Error(types.BlobsHandlerTitle, types.ErrInvalidQuery, c.Query("from_height"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	toHeight, err := strconv.ParseInt(c.DefaultQuery("to_height", "0"), 10, 64)
+	if err != nil || toHeight < 0 || toHeight > 0 && toHeight < fromHeight {
+		rpc.log.Error(types.BlobsHandlerTitle, types.ErrInvalidQuery, c.Query("to_height"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		rpc.log.Error(types.BlobsHandlerTitle, types.ErrInvalidQuery, c.Query("page"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(types.DefaultPageLimit)))
+	if err != nil || limit < 1 || limit > types.MaxPageLimit {
+		rpc.log.Error(types.BlobsHandlerTitle, types.ErrInvalidQuery, c.Query("limit"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	blobs, total, err := rpc.db.GetBlobsByNamespace(namespace, fromHeight, toHeight, (page-1)*limit, limit)
+	if err != nil {
+		rpc.log.Error(types.BlobsHandlerTitle, types.ErrGetBlobs, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetBlobs, err), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcTxsData(blobs, total, page, limit, 0)))
+}
+
+func (rpc *Rpc) txsHandler(c *gin.Context) {
+	addressStr := c.Query("address")
+	if !common.IsHexAddress(addressStr) {
+		rpc.log.Error(types.TxsHandlerTitle, types.ErrInvalidAddress, addressStr)
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidAddress, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
 		return
 	}
+	address := common.HexToAddress(addressStr)
+
+	ty := types.TxType(0)
+	if tyStr := c.Query("type"); len(tyStr) != 0 {
+		ty = types.ParseTxType(tyStr)
+		if ty == types.UnKnown {
+			rpc.log.Error(types.TxsHandlerTitle, types.ErrInvalidQuery, tyStr)
+			c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+			return
+		}
+	}
 
-	tdTx := tmTypes.Tx(j)
-	result, err := rpc.tdClient.BroadcastTxSync(context.Background(), tdTx)
+	fromHeight, err := strconv.ParseInt(c.DefaultQuery("from_height", "0"), 10, 64)
+	if err != nil || fromHeight < 0 {
+		rpc.log.Error(types.TxsHandlerTitle, types.ErrInvalidQuery, c.Query("from_height"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		rpc.log.Error(types.TxsHandlerTitle, types.ErrInvalidQuery, c.Query("page"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(types.DefaultPageLimit)))
+	if err != nil || limit < 1 || limit > types.MaxPageLimit {
+		rpc.log.Error(types.TxsHandlerTitle, types.ErrInvalidQuery, c.Query("limit"))
+		c.JSON(400, types.NewRpcResp(types.NewRpcError(types.ErrInvalidQuery, nil), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	txs, total, err := rpc.db.GetTxsByAddress(address, ty, fromHeight, (page-1)*limit, limit)
 	if err != nil {
-		rpc.log.Error(types.BlobHandlerTitle, types.ErrBroadcastTxSync, err)
-		c.JSON(500, types.NewRpcResp(err, types.NewRpcBlobData(1, nil)))
+		rpc.log.Error(types.TxsHandlerTitle, types.ErrGetTxs, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetTxs, err), types.NewRpcTxsData(nil, 0, 0, 0, 1)))
+		return
+	}
+
+	c.JSON(200, types.NewRpcResp(nil, types.NewRpcTxsData(txs, total, page, limit, 0)))
+}
+
+func (rpc *Rpc) txHandler(c *gin.Context) {
+	hash := types.NormalizeTxHash(c.Param("hash"))
+
+	record, err := rpc.db.GetTx(hash)
+	if err != nil {
+		rpc.log.Error(types.TxHandlerTitle, types.ErrGetTx, err)
+		c.JSON(500, types.NewRpcResp(types.NewRpcError(types.ErrGetTx, err), types.NewRpcTxData(nil, "", 1)))
+		return
+	}
+
+	if record != nil {
+		c.JSON(200, types.NewRpcResp(nil, types.NewRpcTxData(record, record.Status(), 0)))
+		return
+	}
+
+	if rpc.pending.Has(hash) {
+		data := types.NewRpcTxData(nil, types.TxStatusPending, 0)
+		data["hash"] = hash
+		c.JSON(200, types.NewRpcResp(nil, data))
 		return
 	}
 
-	c.JSON(200, types.NewRpcResp(nil, types.NewRpcBlobData(0, result)))
+	c.JSON(404, types.NewRpcResp(types.NewRpcError(types.ErrTxNotFound, nil), types.NewRpcTxData(nil, "", 1)))
 }
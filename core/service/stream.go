@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/nbnet/side-chain/core/types"
+	tmPubsub "github.com/tendermint/tendermint/libs/pubsub"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+
+	streamCounter uint64
+)
+
+// stream is one websocket connection. Every subscription of the connection is served by its own
+// tendermint event bus subscription, at most types.MaxStreamSubscriptions at a time, subs counts them.
+// Writes to the connection are serialized by mu.
+type stream struct {
+	rpc    *Rpc
+	conn   *websocket.Conn
+	name   string
+	subs   int32
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (rpc *Rpc) streamHandler(c *gin.Context) {
+	if rpc.eventBus == nil {
+		c.JSON(503, types.NewRpcResp(types.NewRpcError(types.ErrEventBusUnavailable, nil), nil))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		rpc.log.Error(types.StreamHandlerTitle, types.ErrSubscribe, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(rpc.ctx)
+	s := &stream{
+		rpc:    rpc,
+		conn:   conn,
+		name:   fmt.Sprintf("stream-%d", atomic.AddUint64(&streamCounter, 1)),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	go s.ping()
+	s.read()
+}
+
+// read accepts subscription requests until the client disconnects.
+func (s *stream) read() {
+	defer func() {
+		s.cancel()
+		_ = s.rpc.eventBus.UnsubscribeAll(context.Background(), s.name)
+		_ = s.conn.Close()
+	}()
+
+	for {
+		var req types.StreamRequest
+		if err := s.conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		if errStr := req.Validate(); len(errStr) != 0 {
+			s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Error: errStr})
+			continue
+		}
+
+		if atomic.AddInt32(&s.subs, 1) > types.MaxStreamSubscriptions {
+			atomic.AddInt32(&s.subs, -1)
+			s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Error: types.ErrTooManySubscriptions})
+			continue
+		}
+
+		go func() {
+			defer atomic.AddInt32(&s.subs, -1)
+			s.subscribe(req)
+		}()
+	}
+}
+
+func (s *stream) ping() {
+	ticker := time.NewTicker(types.DefaultStreamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(types.DefaultStreamPingInterval))
+			s.mu.Unlock()
+			if err != nil {
+				s.cancel()
+				return
+			}
+		}
+	}
+}
+
+func (s *stream) write(msg *types.StreamMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.conn.WriteJSON(msg); err != nil {
+		s.cancel()
+		return false
+	}
+	return true
+}
+
+// subscribe subscribes to the event bus first, replays the requested heights, then forwards live events
+// above the last replayed height, so no height is missed or sent twice. The event bus subscription ends
+// with the subscription.
+func (s *stream) subscribe(req types.StreamRequest) {
+	query := tmTypes.EventQueryTx
+	if req.Topic == types.TopicBlocks {
+		query = tmTypes.EventQueryNewBlock
+	}
+
+	subscriber := fmt.Sprintf("%s/%s", s.name, req.Id)
+	sub, err := s.rpc.eventBus.Subscribe(s.ctx, subscriber, query, types.DefaultStreamBuffer)
+	if err != nil {
+		s.rpc.log.Error(types.StreamHandlerTitle, types.ErrSubscribe, err)
+		s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Error: err.Error()})
+		return
+	}
+	defer func() {
+		_ = s.rpc.eventBus.Unsubscribe(context.Background(), subscriber, query)
+	}()
+
+	last := int64(0)
+	if req.FromHeight > 0 {
+		status, err := s.rpc.tdClient.Status(s.ctx)
+		if err != nil {
+			s.rpc.log.Error(types.StreamHandlerTitle, types.ErrReplay, err)
+			s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Height: req.FromHeight, Error: types.ErrReplay})
+			return
+		}
+		// older heights are read from get /blobs or get /txs
+		latest := status.SyncInfo.LatestBlockHeight
+		if latest-req.FromHeight >= types.MaxStreamReplay {
+			s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Height: req.FromHeight, Error: types.ErrReplayRange})
+			return
+		}
+
+		last, err = s.replay(req, latest)
+		if err != nil {
+			s.rpc.log.Error(types.StreamHandlerTitle, types.ErrReplay, err)
+			s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Height: last + 1, Error: types.ErrReplay})
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-sub.Cancelled():
+			// the client did not keep up, it resumes from the height of its last message
+			s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Error: types.ErrStreamClosed})
+			return
+		case msg := <-sub.Out():
+			if !s.forward(req, msg, last) {
+				return
+			}
+		}
+	}
+}
+
+func (s *stream) forward(req types.StreamRequest, msg tmPubsub.Message, last int64) bool {
+	switch data := msg.Data().(type) {
+	case tmTypes.EventDataNewBlock:
+		if data.Block.Height <= last {
+			return true
+		}
+		return s.write(newBlockMessage(req, data.Block))
+	case tmTypes.EventDataTx:
+		if data.Height <= last {
+			return true
+		}
+		record := types.NewTxRecord(data.Tx, data.Height, data.Index, &data.Result)
+		return s.sendTx(req, record, true)
+	}
+	return true
+}
+
+// replay sends the messages of the heights from req.FromHeight up to latest and returns the last height sent.
+func (s *stream) replay(req types.StreamRequest, latest int64) (int64, error) {
+	for height := req.FromHeight; height <= latest; height++ {
+		h := height
+		block, err := s.rpc.tdClient.Block(s.ctx, &h)
+		if err != nil {
+			return height - 1, err
+		}
+
+		if req.Topic == types.TopicBlocks {
+			if !s.write(newBlockMessage(req, block.Block)) {
+				return height - 1, s.ctx.Err()
+			}
+			continue
+		}
+
+		results, err := s.rpc.tdClient.BlockResults(s.ctx, &h)
+		if err != nil {
+			return height - 1, err
+		}
+
+		for idx, tx := range block.Block.Txs {
+			record := types.NewTxRecord(tx, height, uint32(idx), results.TxsResults[idx])
+			if !s.sendTx(req, record, false) {
+				return height - 1, s.ctx.Err()
+			}
+		}
+	}
+
+	return latest, nil
+}
+
+// sendTx sends a delivered tx matching the subscription. Live balance changes carry the balance committed at
+// the height of the tx.
+func (s *stream) sendTx(req types.StreamRequest, record *types.TxRecord, live bool) bool {
+	switch req.Topic {
+	case types.TopicBlobs:
+		if !req.MatchBlob(record) {
+			return true
+		}
+		return s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Height: record.Height, Data: record})
+	case types.TopicBalances:
+		for _, change := range req.BalanceChanges(record) {
+			if live {
+				balance, err := s.rpc.db.GetAccountBalance(common.HexToAddress(change.Address), record.Height)
+				if err != nil {
+					s.rpc.log.Error(types.StreamHandlerTitle, types.ErrGetBalance, err)
+				} else {
+					change.Balance = hexutil.EncodeBig(balance)
+				}
+			}
+			if !s.write(&types.StreamMessage{Id: req.Id, Topic: req.Topic, Height: record.Height, Data: change}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newBlockMessage(req types.StreamRequest, block *tmTypes.Block) *types.StreamMessage {
+	return &types.StreamMessage{
+		Id:     req.Id,
+		Topic:  req.Topic,
+		Height: block.Height,
+		Data: &types.StreamBlock{
+			Hash:     block.Hash().String(),
+			Time:     block.Time,
+			Proposer: block.ProposerAddress.String(),
+			NumTxs:   len(block.Txs),
+		},
+	}
+}
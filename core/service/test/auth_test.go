@@ -0,0 +1,124 @@
+package test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const balancePath = "/balance/0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+
+func newAuthRpc(t *testing.T, auth *types.AuthConfig, limit *types.RateLimitConfig) *service.Rpc {
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	t.Cleanup(func() { db.Close() })
+
+	config := &types.Config{
+		Rpc:       &types.RpcConfig{TdRpc: "http://127.0.0.1:1"},
+		Eth:       &types.EthConfig{},
+		Auth:      auth,
+		RateLimit: limit,
+	}
+	return service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard)
+}
+
+func serve(rpc *service.Rpc, method, path, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rpc.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func signJwt(secret, claims string) string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+// TestAuth checks api key and jwt authentication and public reads.
+func TestAuth(t *testing.T) {
+
+	rpc := newAuthRpc(t, &types.AuthConfig{
+		Mode:        types.AuthModeApiKey,
+		Keys:        []*types.ApiKey{{Name: "alice", Key: "secret"}},
+		PublicReads: true,
+	}, nil)
+
+	if w := serve(rpc, "GET", balancePath, "", ""); w.Code != 200 {
+		t.Fatalf("public read rejected: %d", w.Code)
+	}
+	if w := serve(rpc, "POST", "/tx", "", "{}"); w.Code != 401 {
+		t.Fatalf("expected 401 without key, got %d", w.Code)
+	}
+	if w := serve(rpc, "POST", "/tx", "wrong", "{}"); w.Code != 401 {
+		t.Fatalf("expected 401 with wrong key, got %d", w.Code)
+	}
+	// authenticated, rejected by the handler
+	if w := serve(rpc, "POST", "/tx", "secret", `{"type":"blob","body":{}}`); w.Code != 400 {
+		t.Fatalf("expected 400 with key, got %d", w.Code)
+	}
+
+	rpc = newAuthRpc(t, &types.AuthConfig{Mode: types.AuthModeJwt, JwtSecret: "jwtsecret"}, nil)
+
+	valid := signJwt("jwtsecret", fmt.Sprintf(`{"sub":"bob","exp":%d}`, time.Now().Add(time.Hour).Unix()))
+	expired := signJwt("jwtsecret", fmt.Sprintf(`{"sub":"bob","exp":%d}`, time.Now().Add(-time.Hour).Unix()))
+	forged := signJwt("other", `{"sub":"bob"}`)
+
+	if w := serve(rpc, "GET", balancePath, valid, ""); w.Code != 200 {
+		t.Fatalf("valid jwt rejected: %d %s", w.Code, w.Body.String())
+	}
+	for _, token := range []string{"", expired, forged} {
+		if w := serve(rpc, "GET", balancePath, token, ""); w.Code != 401 {
+			t.Fatalf("expected 401 for %q, got %d", token, w.Code)
+		}
+	}
+}
+
+// TestRateLimit checks per key and per ip request and byte quotas.
+func TestRateLimit(t *testing.T) {
+
+	rpc := newAuthRpc(t, &types.AuthConfig{
+		Mode:        types.AuthModeApiKey,
+		Keys:        []*types.ApiKey{{Name: "alice", Key: "a"}, {Name: "bob", Key: "b"}},
+		PublicReads: true,
+	}, &types.RateLimitConfig{Window: 60, KeyRequests: 2, IpRequests: 1, IpBytes: 10})
+
+	for i := 0; i < 2; i++ {
+		w := serve(rpc, "GET", balancePath, "a", "")
+		if w.Code != 200 || w.Header().Get("X-RateLimit-Remaining") != fmt.Sprintf("%d", 1-i) {
+			t.Fatalf("request %d: %d remaining %s", i, w.Code, w.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	w := serve(rpc, "GET", balancePath, "a", "")
+	if w.Code != 429 || len(w.Header().Get("Retry-After")) == 0 {
+		t.Fatalf("expected 429 with Retry-After, got %d", w.Code)
+	}
+	// keys are limited separately
+	if w := serve(rpc, "GET", balancePath, "b", ""); w.Code != 200 {
+		t.Fatalf("second key limited: %d", w.Code)
+	}
+
+	// anonymous requests are limited per ip, on requests and on bytes
+	rpc = newAuthRpc(t, nil, &types.RateLimitConfig{Window: 60, IpRequests: 5, IpBytes: 10})
+	if w := serve(rpc, "POST", "/tx", "", `{"type":"blob","body":{}}`); w.Code != 429 {
+		t.Fatalf("expected 429 for oversized body, got %d", w.Code)
+	}
+	if w := serve(rpc, "GET", balancePath, "", ""); w.Code != 200 || w.Header().Get("X-RateLimit-Bytes-Remaining") != "10" {
+		t.Fatalf("unexpected byte quota %d %s", w.Code, w.Header().Get("X-RateLimit-Bytes-Remaining"))
+	}
+}
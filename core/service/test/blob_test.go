@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestSignedBlob checks that signed blobs are verified and use up a nonce, and that a dry run reports their fee.
+func TestSignedBlob(t *testing.T) {
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+
+	if err := db.AddAccountBalance(address, big.NewInt(1000000)); err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("rollup batch "), 100)
+	tx, err := client.NewBlobTx(privateKey, 0, data, "rollup", types.CodecZstd, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, _ := json.Marshal(tx)
+	body := tx.Body.(types.BlobBody)
+
+	query := abci.Query(tdTypes.RequestQuery{Path: types.CheckTxQueryPath, Data: txBytes})
+	if query.Code != 0 || string(query.Value) != strconv.FormatInt(body.Gas().Int64(), 10) {
+		t.Fatalf("dry run: %d %s %s", query.Code, query.Log, query.Value)
+	}
+
+	tampered := body
+	tampered.Namespace = "other"
+	tamperedTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Signature: tx.Signature, Body: tampered})
+	later, _ := client.NewBlobTx(privateKey, 1, data, "rollup", types.CodecZstd, "")
+	laterTx, _ := json.Marshal(later)
+	linked, _ := client.NewBlobTx(privateKey, 0, data, "rollup", types.CodecZstd, "0x1234")
+	linkedTx, _ := json.Marshal(linked)
+
+	cases := []struct {
+		tx  []byte
+		log string
+	}{
+		{tamperedTx, types.ErrVerifySignature},
+		{laterTx, types.ErrNonceNotMatch},
+		{linkedTx, types.ErrInvalidBlobLink},
+	}
+	for _, c := range cases {
+		if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: c.tx}); check.Log != c.log {
+			t.Fatalf("expected %s, got %d %s", c.log, check.Code, check.Log)
+		}
+	}
+
+	unsigned, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String(), Codec: types.CodecNone}})
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: 1}})
+	for _, txBytes := range [][]byte{txBytes, unsigned} {
+		if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: txBytes}); check.Code != 0 {
+			t.Fatalf("check failed: %s %s", check.Log, check.Info)
+		}
+		if deliver := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: txBytes}); deliver.Code != 0 {
+			t.Fatalf("deliver failed: %s", deliver.Log)
+		}
+	}
+	abci.Commit()
+
+	// only the signed blob used up a nonce, it can not be replayed
+	if nonce, _ := db.GetAccountNonce(address, types.LatestHeight); nonce.Uint64() != 1 {
+		t.Fatalf("expected nonce 1, got %d", nonce)
+	}
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: txBytes}); check.Log != types.ErrNonceNotMatch {
+		t.Fatalf("replayed blob: %d %s", check.Code, check.Log)
+	}
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: laterTx}); check.Code != 0 {
+		t.Fatalf("next blob rejected: %s", check.Log)
+	}
+}
+
+// TestBroadcastBlob checks that /tx takes signed blobs only and dry runs txs without broadcasting them.
+func TestBroadcastBlob(t *testing.T) {
+
+	privateKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	rpc, txs := newUploadRpc(t, 1<<20, 64<<10)
+
+	post := func(query string, tx *types.Tx) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(tx)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/tx"+query, bytes.NewReader(j))
+		req.Header.Set("Content-Type", "application/json")
+		rpc.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	tx, _ := client.NewBlobTx(privateKey, 0, []byte("rollup batch"), "rollup", types.CodecAuto, "")
+	w := post("?dry_run=true", tx)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"fee":"0x3c"`) || len(*txs) != 0 {
+		t.Fatalf("dry run: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := post("", tx); w.Code != 200 || len(*txs) != 1 {
+		t.Fatalf("broadcast: %d %s", w.Code, w.Body.String())
+	}
+
+	unsigned := *tx
+	unsigned.Signature = ""
+	if w := post("", &unsigned); w.Code != 400 || !strings.Contains(w.Body.String(), types.ErrVerifySignature) {
+		t.Fatalf("expected 400 for an unsigned blob, got %d", w.Code)
+	}
+
+	auto := *tx
+	body := tx.Body.(types.BlobBody)
+	body.Codec = types.CodecAuto
+	auto.Body = body
+	if w := post("", &auto); w.Code != 400 || !strings.Contains(w.Body.String(), types.ErrUnknownCodec) {
+		t.Fatalf("expected 400 for a blob without codec, got %d", w.Code)
+	}
+}
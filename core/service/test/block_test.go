@@ -0,0 +1,149 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestBlocks executes blocks with a mint, blobs and a failed tx and checks the block, validator and stats
+// endpoints, also after a block is executed again.
+func TestBlocks(t *testing.T) {
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+
+	config := &types.Config{
+		Rpc: &types.RpcConfig{TdRpc: "http://127.0.0.1:1"},
+		Eth: &types.EthConfig{},
+	}
+	rpc := service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard)
+
+	get := func(path string, status int, data interface{}) {
+		w := httptest.NewRecorder()
+		rpc.Handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != status {
+			t.Fatalf("GET %s: expected %d, got %d %s", path, status, w.Code, w.Body.String())
+		}
+		resp := struct {
+			Data interface{} `json:"data"`
+		}{data}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get("/blocks/latest", 404, nil)
+
+	body := types.MintBody{Nonce: 0, Amount: "0xde0b6b3a7640000", Address: address.String()}
+	digestHash, err := body.DigestHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := crypto.Sign(digestHash, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintTx, _ := json.Marshal(types.Tx{Ty: types.Mint, Signature: common.Bytes2Hex(signature), Body: body})
+	blobTx := func(data string) []byte {
+		tx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: data, Address: address.String(), Namespace: "rollup"}})
+		return tx
+	}
+
+	alice, bob := []byte{0xaa}, []byte{0xbb}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	execute := func(height int64, proposer []byte, votes []tdTypes.VoteInfo, txs ...[]byte) {
+		abci.BeginBlock(tdTypes.RequestBeginBlock{
+			Hash:           []byte{byte(height)},
+			Header:         tmTypes.Header{Height: height, ChainID: "side", Time: start.Add(time.Duration(height) * time.Second), ProposerAddress: proposer},
+			LastCommitInfo: tdTypes.LastCommitInfo{Votes: votes},
+		})
+		for _, tx := range txs {
+			abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: tx})
+		}
+		abci.EndBlock(tdTypes.RequestEndBlock{Height: height})
+	}
+	vote := func(address []byte, power int64, signed bool) tdTypes.VoteInfo {
+		return tdTypes.VoteInfo{Validator: tdTypes.Validator{Address: address, Power: power}, SignedLastBlock: signed}
+	}
+
+	execute(1, alice, nil, mintTx)
+	abci.Commit()
+	// the last tx is not a tx and fails
+	execute(2, bob, []tdTypes.VoteInfo{vote(alice, 10, true), vote(bob, 5, false)}, blobTx("0x0102"), blobTx("0x010203"), []byte("{}"))
+	// executed again after a crash before the commit
+	execute(2, bob, []tdTypes.VoteInfo{vote(alice, 10, true), vote(bob, 5, false)}, blobTx("0x0102"), blobTx("0x010203"), []byte("{}"))
+	abci.Commit()
+	execute(3, alice, []tdTypes.VoteInfo{vote(alice, 10, true), vote(bob, 5, true)})
+
+	var block struct {
+		Block *types.BlockRecord `json:"block"`
+	}
+	get("/blocks/latest", 200, &block)
+	if block.Block.Height != 2 || block.Block.Proposer != "BB" || block.Block.Hash != "02" || block.Block.ChainId != "side" {
+		t.Fatalf("unexpected latest block %+v", block.Block)
+	}
+	b := block.Block
+	if b.TxCount != 3 || b.FailedTxs != 1 || b.Blobs != 2 || b.BlobBytes != 5 || b.Fees != "0x8c" || len(b.Txs) != 3 {
+		t.Fatalf("unexpected aggregates %+v", b)
+	}
+	if b.Txs[0].Type != types.Blob || b.Txs[2].Type != types.UnKnown || b.Txs[2].Code == 0 {
+		t.Fatalf("unexpected txs %+v %+v", b.Txs[0], b.Txs[2])
+	}
+
+	get("/blocks/1", 200, &block)
+	if block.Block.TxCount != 1 || block.Block.Txs[0].Type != types.Mint || block.Block.Fees != "0x0" {
+		t.Fatalf("unexpected block %+v", block.Block)
+	}
+	// executed but not committed
+	get("/blocks/3", 404, nil)
+	get("/blocks/0", 400, nil)
+
+	var validators struct {
+		Height     int64                    `json:"height"`
+		Validators []*types.ValidatorRecord `json:"validators"`
+	}
+	get("/validators", 200, &validators)
+	if validators.Height != 3 || len(validators.Validators) != 2 {
+		t.Fatalf("unexpected validators %+v", validators)
+	}
+	a, o := validators.Validators[0], validators.Validators[1]
+	if a.Address != "AA" || a.Power != 10 || a.Proposed != 2 || a.Signed != 2 || a.LastSignedHeight != 2 {
+		t.Fatalf("unexpected validator %+v", a)
+	}
+	if o.Address != "BB" || o.Proposed != 1 || o.Signed != 1 || o.Missed != 1 || o.LastSeenHeight != 3 {
+		t.Fatalf("unexpected validator %+v", o)
+	}
+
+	var stats struct {
+		Stats  *types.ChainStats   `json:"stats"`
+		Points []*types.ChainStats `json:"points"`
+	}
+	get("/stats?interval=1&points=5", 200, &stats)
+	s := stats.Stats
+	if s.Height != 2 || s.FromHeight != 1 || s.Blocks != 2 || s.Txs != 4 || s.FailedTxs != 1 || s.Blobs != 2 || s.BlobBytes != 5 || s.FeesBurned != "0x8c" {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if len(stats.Points) != 2 || stats.Points[0].Height != 1 || stats.Points[0].Txs != 1 || !stats.Points[1].Time.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("unexpected points %+v", stats.Points)
+	}
+	get("/stats?points=101", 400, nil)
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcTypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestDashboard checks that the dashboard is only served when enabled, without credentials like the other
+// public routes, and that the status it shows is read from tendermint.
+func TestDashboard(t *testing.T) {
+
+	td := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcTypes.RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "status":
+			result = &coreTypes.ResultStatus{
+				NodeInfo: p2p.DefaultNodeInfo{Moniker: "node0", Network: "side-chain"},
+				SyncInfo: coreTypes.SyncInfo{LatestBlockHeight: 42, CatchingUp: true},
+			}
+		case "net_info":
+			result = &coreTypes.ResultNetInfo{NPeers: 1, Peers: []coreTypes.Peer{{NodeInfo: p2p.DefaultNodeInfo{Moniker: "node1"}, IsOutbound: true, RemoteIP: "10.0.0.2"}}}
+		case "num_unconfirmed_txs":
+			result = &coreTypes.ResultUnconfirmedTxs{Count: 3, Total: 3, TotalBytes: 512}
+		default:
+			t.Errorf("unexpected call %s", req.Method)
+		}
+
+		resp := rpcTypes.NewRPCSuccessResponse(req.ID, result)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer td.Close()
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+
+	newRpc := func(dashboard bool) *service.Rpc {
+		config := &types.Config{
+			Rpc:  &types.RpcConfig{TdRpc: td.URL, Dashboard: dashboard},
+			Eth:  &types.EthConfig{},
+			Auth: &types.AuthConfig{Mode: types.AuthModeApiKey, Keys: []*types.ApiKey{{Name: "alice", Key: "secret"}}},
+		}
+		return service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard)
+	}
+
+	if w := serve(newRpc(false), "GET", "/dashboard", "", ""); w.Code != 404 {
+		t.Fatalf("disabled dashboard served %d", w.Code)
+	}
+
+	rpc := newRpc(true)
+	w := serve(rpc, "GET", "/dashboard", "", "")
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") || !strings.Contains(w.Body.String(), "side-chain node") {
+		t.Fatalf("unexpected dashboard %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if policy := w.Header().Get("Content-Security-Policy"); !strings.Contains(policy, "default-src 'none'") || !strings.Contains(policy, "connect-src 'self'") {
+		t.Fatalf("unexpected policy %q", policy)
+	}
+	// no external assets
+	for _, external := range []string{"http://", "https://", "//cdn"} {
+		if strings.Contains(w.Body.String(), external) {
+			t.Fatalf("dashboard references %s", external)
+		}
+	}
+
+	if w := serve(rpc, "GET", "/status", "", ""); w.Code != 401 {
+		t.Fatalf("status served without credentials %d", w.Code)
+	}
+	// the routes documented without security are public
+	for _, path := range []string{"/openapi.json", "/ready"} {
+		if w := serve(rpc, "GET", path, "", ""); w.Code != 200 {
+			t.Fatalf("%s not public: %d", path, w.Code)
+		}
+	}
+	w = serve(rpc, "GET", "/status", "secret", "")
+	var resp struct {
+		Data struct {
+			Moniker      string                     `json:"moniker"`
+			Height       int64                      `json:"latest_block_height"`
+			CatchingUp   bool                       `json:"catching_up"`
+			NPeers       int                        `json:"n_peers"`
+			Peers        []struct{ Moniker string } `json:"peers"`
+			MempoolTxs   int                        `json:"mempool_txs"`
+			MempoolBytes int64                      `json:"mempool_bytes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != 200 {
+		t.Fatalf("unexpected status %d %s", w.Code, w.Body.String())
+	}
+	status := resp.Data
+	if status.Moniker != "node0" || status.Height != 42 || !status.CatchingUp || status.NPeers != 1 || status.Peers[0].Moniker != "node1" ||
+		status.MempoolTxs != 3 || status.MempoolBytes != 512 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
@@ -33,7 +33,7 @@ func TestDbBalance(t *testing.T) {
 	}
 
 	// get
-	balance, err := db.GetAccountBalance(address)
+	balance, err := db.GetAccountBalance(address, types.LatestHeight)
 	if err != nil {
 		panic(err)
 	}
@@ -63,7 +63,7 @@ func TestDbNonce(t *testing.T) {
 	}
 
 	// get
-	nonce, err := db.GetAccountNonce(address)
+	nonce, err := db.GetAccountNonce(address, types.LatestHeight)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"os"
+	"testing"
+)
+
+// TestErrorCodes checks that rejected txs carry the registered code and the codespace.
+func TestErrorCodes(t *testing.T) {
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	config := types.DbConfig{
+		Path: t.TempDir(),
+	}
+
+	db := service.NewDbService(&config, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+
+	body := types.MintBody{
+		Nonce:   1,
+		Amount:  "0xde0b6b3a7640000",
+		Address: address.String(),
+	}
+	digestHash, err := body.DigestHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := crypto.Sign(digestHash, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintTx, _ := json.Marshal(types.Tx{Ty: types.Mint, Signature: common.Bytes2Hex(signature), Body: body})
+	blobTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String(), Codec: types.CodecNone}})
+	// blobs without codec are gzip compressed
+	corruptTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String()}})
+	codecTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String(), Codec: "lz4"}})
+
+	cases := []struct {
+		tx   []byte
+		code uint32
+		log  string
+	}{
+		{[]byte("{"), types.CodeDecodeTx, types.ErrDecodeTx},
+		{mintTx, types.CodeInvalidNonce, types.ErrNonceNotMatch},
+		{blobTx, types.CodeInsufficientBalance, types.ErrInsufficientBalance},
+		{corruptTx, types.CodeDecodeTx, types.ErrDecompressBlob},
+		{codecTx, types.CodeDecodeTx, types.ErrUnknownCodec},
+	}
+
+	for _, c := range cases {
+		check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: c.tx})
+		if check.Code != c.code || check.Codespace != types.Codespace || check.Log != c.log {
+			t.Fatalf("expected code %d %s, got %d %s %s", c.code, c.log, check.Code, check.Codespace, check.Log)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"io"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestHistory checks that balances and nonces are read at the heights they were committed at, and that only the
+// KeepRecent heights below the last one are kept.
+func TestHistory(t *testing.T) {
+
+	path := t.TempDir()
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: path, KeepRecent: 5}, logger)
+
+	alice := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	bob := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+
+	// alice receives 10 at every height, bob once at height 3
+	for height := int64(1); height <= 12; height++ {
+		if err := db.AddAccountBalance(alice, big.NewInt(10)); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateAccountNonce(alice); err != nil {
+			t.Fatal(err)
+		}
+		if height == 3 {
+			if err := db.AddAccountBalance(bob, big.NewInt(7)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.SetLastBlock(height, []byte{byte(height)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// written after the last commit, only visible at the latest height
+	if err := db.AddAccountBalance(alice, big.NewInt(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		address common.Address
+		height  int64
+		balance int64
+		nonce   int64
+	}{
+		{alice, 8, 80, 8},
+		{alice, 12, 120, 12},
+		{alice, types.LatestHeight, 1120, 12},
+		// bob was not written since height 3, the version of height 3 still holds
+		{bob, 8, 7, 0},
+		{bob, 12, 7, 0},
+	} {
+		balance, err := db.GetAccountBalance(c.address, c.height)
+		if err != nil || balance.Int64() != c.balance {
+			t.Fatalf("balance of %s at %d: expected %d, got %d %v", c.address, c.height, c.balance, balance, err)
+		}
+		nonce, err := db.GetAccountNonce(c.address, c.height)
+		if err != nil || nonce.Int64() != c.nonce {
+			t.Fatalf("nonce of %s at %d: expected %d, got %d %v", c.address, c.height, c.nonce, nonce, err)
+		}
+	}
+
+	// heights 8 to 12 are kept
+	for _, height := range []int64{7, 13} {
+		var notAvailable *types.StateNotAvailableError
+		if _, err := db.GetAccountBalance(alice, height); !errors.As(err, &notAvailable) || notAvailable.Oldest != 8 {
+			t.Fatalf("expected state at %d not available, got %v", height, err)
+		}
+	}
+
+	// keeping more heights later does not bring pruned ones back
+	_ = db.Close()
+	db = service.NewDbService(&types.DbConfig{Path: path}, logger)
+	defer db.Close()
+	if _, err := db.GetAccountBalance(alice, 7); err == nil {
+		t.Fatal("read a pruned height")
+	}
+	if balance, err := db.GetAccountBalance(alice, 8); err != nil || balance.Int64() != 80 {
+		t.Fatalf("expected 80 at the oldest height, got %d %v", balance, err)
+	}
+}
+
+// TestHistoryInit checks that the state of a database written before balances were versioned is readable from
+// its last committed height on.
+func TestHistoryInit(t *testing.T) {
+
+	path := t.TempDir()
+	alice := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+	legacy, err := badger.Open(badger.DefaultOptions(path).WithLoggingLevel(badger.ERROR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = legacy.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(types.BalanceKey(alice), big.NewInt(500).Bytes()); err != nil {
+			return err
+		}
+		if err := txn.Set(types.NonceKey(alice), big.NewInt(2).Bytes()); err != nil {
+			return err
+		}
+		if err := txn.Set(types.LastBlockAppHashKey, []byte{1}); err != nil {
+			return err
+		}
+		return txn.Set(types.LastBlockHeightKey, big.NewInt(40).Bytes())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = legacy.Close()
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: path}, logger)
+	defer db.Close()
+
+	if balance, err := db.GetAccountBalance(alice, 40); err != nil || balance.Int64() != 500 {
+		t.Fatalf("expected 500 at the last height, got %d %v", balance, err)
+	}
+	if nonce, err := db.GetAccountNonce(alice, 40); err != nil || nonce.Int64() != 2 {
+		t.Fatalf("expected nonce 2 at the last height, got %d %v", nonce, err)
+	}
+	var notAvailable *types.StateNotAvailableError
+	if _, err := db.GetAccountBalance(alice, 39); !errors.As(err, &notAvailable) {
+		t.Fatalf("expected the state before versioning not available, got %v", err)
+	}
+}
+
+// TestHistoryPrune checks that the versions of an account which is no longer written are pruned as well.
+func TestHistoryPrune(t *testing.T) {
+
+	path := t.TempDir()
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: path, KeepRecent: 5}, logger)
+
+	// carol receives 10 at heights 1 to 4 and is not written after
+	carol := common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC")
+	for height := int64(1); height <= 12; height++ {
+		if height <= 4 {
+			if err := db.AddAccountBalance(carol, big.NewInt(10)); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.UpdateAccountNonce(carol); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.SetLastBlock(height, []byte{byte(height)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if balance, err := db.GetAccountBalance(carol, 8); err != nil || balance.Int64() != 40 {
+		t.Fatalf("expected 40 at the oldest height, got %d %v", balance, err)
+	}
+	if nonce, err := db.GetAccountNonce(carol, 12); err != nil || nonce.Int64() != 4 {
+		t.Fatalf("expected nonce 4 at the last height, got %d %v", nonce, err)
+	}
+	_ = db.Close()
+
+	// only the version of height 4 is left
+	raw, err := badger.Open(badger.DefaultOptions(path).WithLoggingLevel(badger.ERROR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, historyKey := range []func(common.Address, int64) []byte{types.BalanceHistoryKey, types.NonceHistoryKey} {
+		var heights []int64
+		prefix := historyKey(carol, 0)
+		prefix = prefix[:len(prefix)-8]
+		_ = raw.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+			defer it.Close()
+			for it.Rewind(); it.Valid(); it.Next() {
+				key := it.Item().Key()
+				heights = append(heights, int64(binary.BigEndian.Uint64(key[len(key)-8:])))
+			}
+			return nil
+		})
+		if len(heights) != 1 || heights[0] != 4 {
+			t.Fatalf("expected the version of height 4, got %v", heights)
+		}
+	}
+}
+
+// TestHistoryQuery checks that queries without a height read the last committed state, not the block being
+// executed, and that the earliest block tag reads the oldest kept height.
+func TestHistoryQuery(t *testing.T) {
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir(), KeepRecent: 5}, logger)
+	defer db.Close()
+
+	alice := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	for height := int64(1); height <= 8; height++ {
+		if err := db.AddAccountBalance(alice, big.NewInt(10)); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateAccountNonce(alice); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetLastBlock(height, []byte{byte(height)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the block at height 9 is being executed
+	if err := db.AddAccountBalance(alice, big.NewInt(1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateAccountNonce(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &types.Config{Rpc: &types.RpcConfig{TdRpc: "http://127.0.0.1:1"}, Eth: &types.EthConfig{ChainId: types.DefaultEthChainId}}
+	handler := service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard).Handler()
+	get := func(path string, data interface{}) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if err := json.Unmarshal(w.Body.Bytes(), &struct {
+			Data interface{} `json:"data"`
+		}{data}); err != nil || w.Code != 200 {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	var balance struct {
+		Balance string `json:"balance"`
+		Height  int64  `json:"height"`
+	}
+	get("/balance/"+alice.String(), &balance)
+	if balance.Balance != "0x50" || balance.Height != 8 {
+		t.Fatalf("expected 0x50 at 8, got %+v", balance)
+	}
+	var nonce struct {
+		Nonce  uint64 `json:"nonce"`
+		Height int64  `json:"height"`
+	}
+	get("/nonce/"+alice.String(), &nonce)
+	if nonce.Nonce != 8 || nonce.Height != 8 {
+		t.Fatalf("expected nonce 8 at 8, got %+v", nonce)
+	}
+
+	// heights 4 to 8 are kept
+	for tag, expected := range map[string]string{"latest": "0x50", "earliest": "0x28", "0x6": "0x3c"} {
+		w := httptest.NewRecorder()
+		body := `{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["` + alice.String() + `","` + tag + `"]}`
+		req := httptest.NewRequest("POST", "/eth", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(w, req)
+		var resp types.EthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || string(resp.Result) != `"`+expected+`"` {
+			t.Fatalf("%s: expected %s, got %s", tag, expected, w.Body.String())
+		}
+	}
+}
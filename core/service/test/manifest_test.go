@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcTypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestManifest checks that a manifest is only accepted for successful blobs of its address and that the
+// object is reassembled from its parts.
+func TestManifest(t *testing.T) {
+
+	privateKey, _ := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	otherKey, _ := crypto.HexToECDSA("59c6995e998f97a5a0044966f0945389dc9c86dae88c7a8412f4603b6b78690d")
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+
+	for _, key := range []string{address.String(), crypto.PubkeyToAddress(otherKey.PublicKey).String()} {
+		if err := db.AddAccountBalance(common.HexToAddress(key), big.NewInt(1000000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	object := bytes.Repeat([]byte("model checkpoint "), 3000)
+	chunks := [][]byte{object[:20000], object[20000:40000], object[40000:]}
+
+	// the parts, and a blob of another address
+	delivered := map[string][]byte{}
+	var parts []string
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmProto.Header{Height: 1}})
+	for i, chunk := range chunks {
+		tx, err := client.NewBlobTx(privateKey, uint64(i), chunk, "models", types.CodecZstd, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, deliver(t, abci, tx, delivered))
+	}
+	otherTx, _ := client.NewBlobTx(otherKey, 0, chunks[0], "models", types.CodecZstd, "")
+	other := deliver(t, abci, otherTx, delivered)
+	abci.Commit()
+
+	digest := sha256.Sum256(object)
+	manifest := func(parts []string, digest []byte) []byte {
+		tx, err := client.NewManifestTx(privateKey, 3, "models", parts, int64(len(object)), digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, _ := json.Marshal(tx)
+		return j
+	}
+
+	missing := strings.Repeat("AB", 32)
+	cases := []struct {
+		tx  []byte
+		log string
+	}{
+		{manifest(nil, digest[:]), types.ErrInvalidManifest},
+		{manifest([]string{parts[0], parts[0]}, digest[:]), types.ErrInvalidManifest},
+		{manifest(parts, digest[:4]), types.ErrInvalidManifest},
+		{manifest(append(parts[:2:2], missing), digest[:]), types.ErrManifestPartMissing},
+		{manifest(append(parts[:2:2], other), digest[:]), types.ErrInvalidManifestPart},
+	}
+	for _, c := range cases {
+		if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: c.tx}); check.Log != c.log {
+			t.Fatalf("expected %s, got %d %s", c.log, check.Code, check.Log)
+		}
+	}
+
+	manifestTx := manifest(parts, digest[:])
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: manifestTx}); check.Code != 0 {
+		t.Fatalf("check failed: %s %s", check.Log, check.Info)
+	}
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmProto.Header{Height: 2}})
+	if deliver := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: manifestTx}); deliver.Code != 0 {
+		t.Fatalf("deliver failed: %s", deliver.Log)
+	}
+	abci.Commit()
+	id := types.TxHash(manifestTx)
+	delivered[id] = manifestTx
+
+	record, _ := db.GetTx(id)
+	if record == nil || record.Type != types.Manifest || record.Namespace != "models" {
+		t.Fatalf("manifest not indexed: %+v", record)
+	}
+	if nonce, _ := db.GetAccountNonce(address, types.LatestHeight); nonce.Uint64() != 4 {
+		t.Fatalf("expected nonce 4, got %d", nonce)
+	}
+
+	tendermint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcTypes.RPCRequest
+		var params struct {
+			Hash []byte `json:"hash"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.Unmarshal(req.Params, &params)
+		tx, ok := delivered[fmt.Sprintf("%X", params.Hash)]
+		if req.Method != "tx" || !ok {
+			_ = json.NewEncoder(w).Encode(rpcTypes.RPCInternalError(req.ID, io.EOF))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rpcTypes.NewRPCSuccessResponse(req.ID, &coreTypes.ResultTx{Hash: params.Hash, Height: 1, Tx: tmTypes.Tx(tx)}))
+	}))
+	defer tendermint.Close()
+
+	config := &types.Config{Rpc: &types.RpcConfig{TdRpc: tendermint.URL}, Eth: &types.EthConfig{}}
+	server := httptest.NewServer(service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard).Handler())
+	defer server.Close()
+	c := client.NewClient(server.URL)
+
+	var out bytes.Buffer
+	if err := c.Object(context.Background(), id, &out); err != nil || !bytes.Equal(out.Bytes(), object) {
+		t.Fatalf("object not reassembled: %v", err)
+	}
+	if err := c.Object(context.Background(), parts[0], &out); !client.IsNotFound(err) {
+		t.Fatalf("expected a blob not to be an object, got %v", err)
+	}
+
+	// the chain does not check the size, the object is streamed in full and the client rejects it
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmProto.Header{Height: 3}})
+	wrongTx, err := client.NewManifestTx(privateKey, 4, "models", parts, int64(len(object))+1, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := deliver(t, abci, wrongTx, delivered)
+	abci.Commit()
+	out.Reset()
+	if err := c.Object(context.Background(), wrong, &out); err == nil || !bytes.Equal(out.Bytes(), object) {
+		t.Fatalf("expected a size mismatch after the full object, got %v", err)
+	}
+
+	// a part the node can not read cuts the object short
+	delete(delivered, parts[1])
+	if err := c.Object(context.Background(), id, io.Discard); err == nil {
+		t.Fatal("expected error for a truncated object")
+	}
+}
+
+// deliver delivers tx in the current block and records its bytes by hash.
+func deliver(t *testing.T, abci *service.Abci, tx *types.Tx, delivered map[string][]byte) string {
+	j, _ := json.Marshal(tx)
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: j}); check.Code != 0 {
+		t.Fatalf("check failed: %s %s", check.Log, check.Info)
+	}
+	if deliver := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: j}); deliver.Code != 0 {
+		t.Fatalf("deliver failed: %s", deliver.Log)
+	}
+	hash := types.TxHash(j)
+	delivered[hash] = j
+	return hash
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/client"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// TestMultisig checks that a tx of a 2 of 3 multisig account is accepted with the signatures of any two signers,
+// collected offline and combined, and rejected with fewer or foreign signatures.
+func TestMultisig(t *testing.T) {
+
+	keys := make([]*ecdsa.PrivateKey, 4)
+	signers := make([]common.Address, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		if i < 3 {
+			signers[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		}
+	}
+
+	multisig, err := types.NewMultisig(2, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, _ := multisig.Address()
+	// the address depends on the set of signers, not their order
+	if reversed, _ := types.MultisigAddress(2, []common.Address{signers[2], signers[1], signers[0]}); reversed != address {
+		t.Fatal("address depends on the order of the signers")
+	}
+	if other, _ := types.MultisigAddress(1, signers); other == address {
+		t.Fatal("address does not depend on the threshold")
+	}
+	if _, err := types.NewMultisig(4, signers); err == nil {
+		t.Fatal("expected error for a threshold above the signers")
+	}
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+	if err := db.AddAccountBalance(address, big.NewInt(1000000)); err != nil {
+		t.Fatal(err)
+	}
+
+	to := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	unsigned := client.UnsignedTransferTx(address, 0, to, big.NewInt(1000))
+	unsigned.Multisig = multisig
+	unsignedJson, _ := json.Marshal(unsigned)
+
+	// every signer signs a copy of the tx file
+	sign := func(key *ecdsa.PrivateKey) *types.Tx {
+		tx, err := client.DecodeTx(unsignedJson)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.SignMultisigTx(context.Background(), tx, client.NewLocalSigner(key)); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	alice, carol := sign(keys[0]), sign(keys[2])
+	if err := client.SignMultisigTx(context.Background(), unsigned, client.NewLocalSigner(keys[3])); err == nil {
+		t.Fatal("signed by a key that is not a signer")
+	}
+
+	combined, err := client.CombineMultisigTxs(alice, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.VerifyTx(combined); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CombineMultisigTxs(alice, sign(keys[1]), client.UnsignedTransferTx(address, 1, to, big.NewInt(1000))); err == nil {
+		t.Fatal("combined another tx")
+	}
+
+	encode := func(tx *types.Tx) []byte {
+		j, _ := json.Marshal(tx)
+		return j
+	}
+	foreign := *combined
+	foreignMultisig := *combined.Multisig
+	foreignMultisig.Signatures = append([]string{}, combined.Multisig.Signatures...)
+	foreignSignature, _ := crypto.Sign(make([]byte, 32), keys[3])
+	foreignMultisig.Signatures[1] = common.Bytes2Hex(foreignSignature)
+	foreign.Multisig = &foreignMultisig
+	withSignature := *combined
+	withSignature.Signature = alice.Multisig.Signatures[multisig.Index(signers[0])]
+
+	for _, tx := range [][]byte{encode(alice), encode(&foreign), encode(&withSignature), unsignedJson} {
+		if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: tx}); check.Log != types.ErrVerifySignature {
+			t.Fatalf("expected %s, got %d %s", types.ErrVerifySignature, check.Code, check.Log)
+		}
+	}
+
+	txBytes := encode(combined)
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: 1}})
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: txBytes}); check.Code != 0 {
+		t.Fatalf("check failed: %s %s", check.Log, check.Info)
+	}
+	if deliver := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: txBytes}); deliver.Code != 0 {
+		t.Fatalf("deliver failed: %s", deliver.Log)
+	}
+	abci.Commit()
+
+	if balance, _ := db.GetAccountBalance(to, types.LatestHeight); balance.Int64() != 1000 {
+		t.Fatalf("expected 1000 transferred, got %d", balance)
+	}
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: txBytes}); check.Log != types.ErrNonceNotMatch {
+		t.Fatalf("replayed multisig tx: %d %s", check.Code, check.Log)
+	}
+}
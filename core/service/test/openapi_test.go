@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestOpenApi checks that every documented operation is routed and that requests violating the spec
+// are rejected before they reach the handlers.
+func TestOpenApi(t *testing.T) {
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+
+	config := &types.Config{
+		Rpc: &types.RpcConfig{TdRpc: "http://127.0.0.1:1", MaxBlobBytes: 4},
+		Eth: &types.EthConfig{},
+	}
+	rpc := service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard)
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(service.OpenApiSpec, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	routed := make(map[string]bool)
+	for _, route := range rpc.Routes() {
+		routed[strings.ToLower(route.Method)+" "+service.OpenApiPath(route.Path)] = true
+	}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if !routed[method+" "+path] {
+				t.Fatalf("%s %s documented but not routed", method, path)
+			}
+		}
+	}
+
+	address := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   uint32
+	}{
+		{"GET", "/balance/" + address, "", 200, types.CodeOK},
+		{"GET", "/balance/0x1234", "", 400, types.CodeInvalidAddress},
+		{"GET", "/balance/" + address + "?height=0", "", 200, types.CodeOK},
+		{"GET", "/balance/" + address + "?height=-1", "", 400, types.CodeInvalidRequest},
+		{"GET", "/nonce/" + address + "?height=5", "", 404, types.CodeNotFound},
+		{"GET", "/txs?address=" + address + "&limit=1000", "", 400, types.CodeInvalidRequest},
+		{"GET", "/txs?limit=10", "", 400, types.CodeInvalidAddress},
+		{"GET", "/tx/nothex", "", 400, types.CodeInvalidRequest},
+		{"GET", "/blocks/latest", "", 404, types.CodeNotFound},
+		{"GET", "/blocks/first", "", 400, types.CodeInvalidRequest},
+		{"GET", "/stats?interval=0", "", 400, types.CodeInvalidRequest},
+		{"GET", "/validators", "", 200, types.CodeOK},
+		{"GET", "/status", "", 503, types.CodeUnavailable},
+		{"GET", "/dashboard", "", 404, types.CodeNotFound},
+		{"POST", "/blob", `{"data":"0x010","address":"` + address + `"}`, 400, types.CodeInvalidRequest},
+		{"POST", "/blob", `{"data":"0xzz","address":"` + address + `"}`, 400, types.CodeInvalidRequest},
+		{"POST", "/blob", `{"data":"0x0102030405","address":"` + address + `"}`, 413, types.CodeTooLarge},
+		{"POST", "/blob", `{"data":"0x01","address":"0x0000000000000000000000000000000000000000"}`, 400, types.CodeInvalidAddress},
+		{"POST", "/blob", `{"data":"0x01","address":"` + address + `","namespace":"a b"}`, 400, types.CodeInvalidRequest},
+		{"POST", "/tx", `{"type":"blob","body":{}}`, 400, types.CodeInvalidSignature},
+		{"POST", "/tx", `{"type":"upgrade","body":{}}`, 400, types.CodeUnknownTxType},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		rpc.Handler().ServeHTTP(w, req)
+
+		var resp struct {
+			Error *types.RpcError `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: %v", c.method, c.path, err)
+		}
+
+		code := types.CodeOK
+		if resp.Error != nil {
+			code = resp.Error.Code
+		}
+		if w.Code != c.status || code != c.code {
+			t.Fatalf("%s %s %s: expected %d code %d, got %d %s", c.method, c.path, c.body, c.status, c.code, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	rpc.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != len(service.OpenApiSpec) {
+		t.Fatalf("unexpected spec response %d", w.Code)
+	}
+}
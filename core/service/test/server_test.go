@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newServerRpc(t *testing.T, rpcConfig *types.RpcConfig) *service.Rpc {
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	t.Cleanup(func() { db.Close() })
+
+	rpcConfig.Host = "127.0.0.1"
+	rpcConfig.TdRpc = "http://127.0.0.1:1"
+	config := &types.Config{Rpc: rpcConfig, Eth: &types.EthConfig{}}
+	return service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard)
+}
+
+// TestRpcLifecycle checks that listen errors are returned, and that Stop lets an in-flight upload complete
+// while refusing new requests.
+func TestRpcLifecycle(t *testing.T) {
+
+	rpc := newServerRpc(t, &types.RpcConfig{})
+	if err := rpc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + rpc.Addr()
+
+	_, port, _ := net.SplitHostPort(rpc.Addr())
+	p, _ := strconv.Atoi(port)
+	if err := newServerRpc(t, &types.RpcConfig{Port: p}).Start(); err == nil {
+		t.Fatal("expected listen error on a used port")
+	}
+
+	resp, err := http.Get(url + "/ready")
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("not ready: %v", err)
+	}
+	resp.Body.Close()
+
+	// an upload whose body is still being sent when the shutdown starts
+	body, writer := io.Pipe()
+	done := make(chan int)
+	go func() {
+		resp, err := http.Post(url+"/blob", "application/json", body)
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	_, _ = writer.Write([]byte(`{"address":"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",`))
+	time.Sleep(100 * time.Millisecond)
+
+	stopped := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- rpc.Stop(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := http.Get(url + "/ready"); err == nil {
+		t.Fatal("new connection accepted while draining")
+	}
+
+	_, _ = writer.Write([]byte(`"data":"0x01"}`))
+	_ = writer.Close()
+
+	// the tendermint rpc is unreachable, the upload fails in the handler but is answered
+	if status := <-done; status != 500 {
+		t.Fatalf("in-flight upload not answered: %d", status)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRpcTls checks that the service is served over https when a certificate is configured.
+func TestRpcTls(t *testing.T) {
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	if err := newServerRpc(t, &types.RpcConfig{TlsCert: certFile, TlsKey: filepath.Join(dir, "missing.pem")}).Start(); err == nil {
+		t.Fatal("expected error for a missing key")
+	}
+
+	rpc := newServerRpc(t, &types.RpcConfig{TlsCert: certFile, TlsKey: keyFile})
+	if err := rpc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer rpc.Stop(context.Background())
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	resp, err := client.Get("https://" + rpc.Addr() + "/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+}
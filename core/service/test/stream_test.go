@@ -0,0 +1,89 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/libs/log"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcTypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStreamLimits checks that a connection holds at most types.MaxStreamSubscriptions subscriptions and
+// that replays further back than types.MaxStreamReplay heights are refused.
+func TestStreamLimits(t *testing.T) {
+
+	td := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcTypes.RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		result := &coreTypes.ResultStatus{SyncInfo: coreTypes.SyncInfo{LatestBlockHeight: types.MaxStreamReplay + 10}}
+		_ = json.NewEncoder(w).Encode(rpcTypes.NewRPCSuccessResponse(req.ID, result))
+	}))
+	defer td.Close()
+
+	eventBus := tmTypes.NewEventBus()
+	if err := eventBus.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = eventBus.Stop() }()
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+
+	config := &types.Config{
+		Rpc: &types.RpcConfig{TdRpc: td.URL},
+		Eth: &types.EthConfig{},
+	}
+	server := httptest.NewServer(service.NewRpc(config, db, service.NewPendingTxs(), eventBus, logger, io.Discard).Handler())
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		return conn
+	}
+	read := func(conn *websocket.Conn) *types.StreamMessage {
+		var msg types.StreamMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return &msg
+	}
+
+	conn := dial()
+	defer conn.Close()
+	if err := conn.WriteJSON(&types.StreamRequest{Id: "old", Topic: types.TopicBlocks, FromHeight: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := read(conn); msg.Id != "old" || msg.Error != types.ErrReplayRange || msg.Height != 10 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	conn = dial()
+	defer conn.Close()
+	for i := int32(0); i <= types.MaxStreamSubscriptions; i++ {
+		if err := conn.WriteJSON(&types.StreamRequest{Id: fmt.Sprint(i), Topic: types.TopicBlocks}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if msg := read(conn); msg.Id != fmt.Sprint(types.MaxStreamSubscriptions) || msg.Error != types.ErrTooManySubscriptions {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"io"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestEthTransfer maps a signed EIP-1559 value transfer to a native transfer and delivers it.
+func TestEthTransfer(t *testing.T) {
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	to := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	config := types.DbConfig{
+		Path: t.TempDir(),
+	}
+
+	db := service.NewDbService(&config, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{Eth: &types.EthConfig{ChainId: types.DefaultEthChainId}}, logger)
+
+	if err := db.AddAccountBalance(from, big.NewInt(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	chainId := new(big.Int).SetUint64(types.DefaultEthChainId)
+	ethTx, err := ethTypes.SignNewTx(privateKey, ethTypes.LatestSignerForChainID(chainId), &ethTypes.DynamicFeeTx{
+		ChainID: chainId,
+		Nonce:   0,
+		To:      &to,
+		Value:   big.NewInt(400),
+		Gas:     21000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ethTx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// signed for another chain
+	if _, _, err := types.NewEthTransferTx(hexutil.Encode(raw), 1); err == nil {
+		t.Fatal("ethereum tx of another chain accepted")
+	}
+
+	tx, _, err := types.NewEthTransferTx(hexutil.Encode(raw), types.DefaultEthChainId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, _ := json.Marshal(tx)
+
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: txBytes}); check.Code != 0 {
+		t.Fatalf("check failed: %s %s", check.Log, check.Info)
+	}
+
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: 1}})
+	if deliver := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: txBytes}); deliver.Code != 0 {
+		t.Fatalf("deliver failed: %s", deliver.Log)
+	}
+	abci.Commit()
+
+	fromBalance, _ := db.GetAccountBalance(from, types.LatestHeight)
+	toBalance, _ := db.GetAccountBalance(to, types.LatestHeight)
+	nonce, _ := db.GetAccountNonce(from, types.LatestHeight)
+	if fromBalance.Int64() != 600 || toBalance.Int64() != 400 || nonce.Int64() != 1 {
+		t.Fatalf("unexpected state from %s to %s nonce %s", fromBalance, toBalance, nonce)
+	}
+
+	record, err := db.GetTx(types.NormalizeTxHash(ethTx.Hash().Hex()))
+	if err != nil || record == nil || record.Recipient != to.String() {
+		t.Fatalf("transfer not found by ethereum hash: %v %+v", err, record)
+	}
+
+	// replaying the same ethereum tx fails on the nonce
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: txBytes}); check.Log != types.ErrNonceNotMatch {
+		t.Fatalf("replayed transfer not rejected: %s", check.Log)
+	}
+}
+
+// TestEthLimits checks that oversize requests and batches are rejected before any call is served.
+func TestEthLimits(t *testing.T) {
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	config := &types.Config{Rpc: &types.RpcConfig{TdRpc: "http://127.0.0.1:1"}, Eth: &types.EthConfig{ChainId: types.DefaultEthChainId}}
+	handler := service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard).Handler()
+	call := func(body string) (int, json.RawMessage) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/eth", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+	request := `{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`
+
+	batch := strings.TrimSuffix(strings.Repeat(request+",", types.MaxEthBatch), ",")
+	var resps []types.EthResponse
+	if code, body := call("[" + batch + "]"); code != 200 || json.Unmarshal(body, &resps) != nil || len(resps) != types.MaxEthBatch {
+		t.Fatalf("batch of %d not served: %d", types.MaxEthBatch, code)
+	}
+
+	var resp types.EthResponse
+	if code, body := call("[" + batch + "," + request + "]"); code != 200 || json.Unmarshal(body, &resp) != nil || resp.Error == nil || resp.Error.Code != types.EthErrInvalidRequest {
+		t.Fatalf("expected an invalid request for an oversize batch, got %d %s", code, body)
+	}
+	padding := strings.Repeat(" ", int(types.MaxEthRequestBytes))
+	if code, body := call(request + padding); code != 413 || json.Unmarshal(body, &resp) != nil || resp.Error.Code != types.EthErrInvalidRequest {
+		t.Fatalf("expected 413 for an oversize request, got %d %s", code, body)
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTxIndex delivers a mint and a blob tx and checks the emitted events and the address index.
+func TestTxIndex(t *testing.T) {
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	config := types.DbConfig{
+		Path: t.TempDir(),
+	}
+
+	db := service.NewDbService(&config, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+
+	body := types.MintBody{
+		Nonce:   0,
+		Amount:  "0xde0b6b3a7640000",
+		Address: address.String(),
+	}
+	digestHash, err := body.DigestHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := crypto.Sign(digestHash, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintTx, _ := json.Marshal(types.Tx{Ty: types.Mint, Signature: common.Bytes2Hex(signature), Body: body})
+	blobTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String(), Namespace: "rollup"}})
+
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: 1}})
+	mint := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: mintTx})
+	abci.Commit()
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: 2}})
+	blob := abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: blobTx})
+	abci.Commit()
+
+	if mint.Code != 0 || blob.Code != 0 {
+		t.Fatalf("deliver failed: %s %s", mint.Log, blob.Log)
+	}
+	if len(blob.Events) != 1 || blob.Events[0].Type != types.TxEventType {
+		t.Fatalf("unexpected events %v", blob.Events)
+	}
+
+	txs, total, err := db.GetTxsByAddress(address, 0, 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || txs[0].Type != types.Mint || txs[1].Type != types.Blob {
+		t.Fatalf("unexpected txs %d %v", total, txs)
+	}
+	if txs[1].BlobSize != 2 || txs[1].Namespace != "rollup" || txs[1].Fee != "0x3c" {
+		t.Fatalf("unexpected blob record %+v", txs[1])
+	}
+
+	txs, total, err = db.GetTxsByAddress(address, types.Blob, 2, 0, 10)
+	if err != nil || total != 1 || txs[0].Height != 2 {
+		t.Fatalf("unexpected filtered txs %d %v %v", total, txs, err)
+	}
+}
+
+// TestTxReceipt checks that a tx accepted by CheckTx is pending until it is delivered, then found by hash.
+func TestTxReceipt(t *testing.T) {
+
+	address := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	config := types.DbConfig{
+		Path: t.TempDir(),
+	}
+
+	db := service.NewDbService(&config, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+
+	blobTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String(), Codec: types.CodecNone}})
+	hash := types.TxHash(blobTx)
+
+	// fund exactly the fee of the blob
+	if err := db.AddAccountBalance(address, big.NewInt(60)); err != nil {
+		t.Fatal(err)
+	}
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: blobTx}); check.Code != 0 {
+		t.Fatalf("check failed: %s", check.Log)
+	}
+	if !abci.Pending.Has(hash) {
+		t.Fatal("tx not pending after CheckTx")
+	}
+
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: 1}})
+	abci.DeliverTx(tdTypes.RequestDeliverTx{Tx: blobTx})
+	abci.Commit()
+
+	if abci.Pending.Has(hash) {
+		t.Fatal("tx still pending after DeliverTx")
+	}
+
+	record, err := db.GetTx(types.NormalizeTxHash("0x" + strings.ToLower(hash)))
+	if err != nil || record == nil {
+		t.Fatalf("tx not found: %v", err)
+	}
+	if record.Status() != types.TxStatusIncluded || record.Height != 1 || record.GasUsed != 60 {
+		t.Fatalf("unexpected receipt %+v", record)
+	}
+}
+
+// TestPendingExpiry checks that a pending tx which is neither rechecked nor delivered expires, and that a recheck
+// keeps it pending.
+func TestPendingExpiry(t *testing.T) {
+
+	address := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	abci := service.NewAbci(db, &types.Config{}, logger)
+	abci.Pending = service.NewPendingTxsWithTTL(200 * time.Millisecond)
+
+	if err := db.AddAccountBalance(address, big.NewInt(60)); err != nil {
+		t.Fatal(err)
+	}
+	blobTx, _ := json.Marshal(types.Tx{Ty: types.Blob, Body: types.BlobBody{Data: "0x0102", Address: address.String(), Codec: types.CodecNone}})
+	hash := types.TxHash(blobTx)
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: blobTx}); check.Code != 0 {
+		t.Fatalf("check failed: %s", check.Log)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if check := abci.CheckTx(tdTypes.RequestCheckTx{Tx: blobTx, Type: tdTypes.CheckTxType_Recheck}); check.Code != 0 {
+		t.Fatalf("recheck failed: %s", check.Log)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !abci.Pending.Has(hash) {
+		t.Fatal("rechecked tx expired")
+	}
+
+	// evicted from the mempool, the tx is no longer rechecked
+	time.Sleep(250 * time.Millisecond)
+	if abci.Pending.Has(hash) {
+		t.Fatal("tx pending after the ttl")
+	}
+}
+
+// TestNamespaceIndex checks that blobs are listed by namespace and height range, and served on /blobs.
+func TestNamespaceIndex(t *testing.T) {
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	config := types.DbConfig{
+		Path: t.TempDir(),
+	}
+	db := service.NewDbService(&config, logger)
+
+	sender := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	for height := int64(1); height <= 5; height++ {
+		for i, namespace := range []string{"rollup", "rollup2", ""} {
+			record := &types.TxRecord{Hash: fmt.Sprintf("%064X", height*10+int64(i)), Height: height, Index: uint32(i), Type: types.Blob, Sender: sender, Namespace: namespace}
+			if err := db.IndexTx(record); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// other txs are not indexed by namespace
+		if err := db.IndexTx(&types.TxRecord{Hash: "B", Height: height, Index: 3, Type: types.Mint, Sender: sender}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blobs, total, err := db.GetBlobsByNamespace("rollup", 2, 4, 1, 10)
+	if err != nil || total != 3 || len(blobs) != 2 || blobs[0].Height != 3 || blobs[1].Namespace != "rollup" {
+		t.Fatalf("unexpected blobs %d %v %v", total, blobs, err)
+	}
+	if _, total, _ := db.GetBlobsByNamespace("", 0, 0, 0, 10); total != 5 {
+		t.Fatalf("expected 5 blobs without namespace, got %d", total)
+	}
+
+	// reopening does not index twice
+	db.Close()
+	db = service.NewDbService(&config, logger)
+	defer db.Close()
+	if _, total, _ := db.GetBlobsByNamespace("rollup", 0, 0, 0, 10); total != 5 {
+		t.Fatalf("expected 5 blobs after reopening, got %d", total)
+	}
+
+	rpc := service.NewRpc(&types.Config{Rpc: &types.RpcConfig{TdRpc: "http://127.0.0.1:1"}, Eth: &types.EthConfig{}}, db, service.NewPendingTxs(), nil, logger, io.Discard)
+	w := serve(rpc, "GET", "/blobs?namespace=rollup2&from_height=4", "", "")
+	var resp struct {
+		Data struct {
+			Txs   []*types.TxRecord `json:"txs"`
+			Total int               `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != 200 || resp.Data.Total != 2 || resp.Data.Txs[0].Height != 4 {
+		t.Fatalf("unexpected /blobs response %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(rpc, "GET", "/blobs?from_height=4&to_height=2", "", ""); w.Code != 400 {
+		t.Fatalf("expected 400 for an empty range, got %d", w.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"github.com/dgraph-io/badger/v3"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	tdTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// TestUpgradeHalt commits blocks up to a scheduled upgrade height and checks that the node halts,
+// that the old binary refuses to continue, and that the registered migration runs on restart.
+func TestUpgradeHalt(t *testing.T) {
+
+	address := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	config := types.DbConfig{
+		Path: t.TempDir(),
+	}
+	plan := &types.UpgradeConfig{Name: "test-upgrade", Height: 2}
+
+	db := service.NewDbService(&config, logger)
+	defer db.Close()
+
+	abci := service.NewAbci(db, &types.Config{Upgrade: plan}, logger)
+	for height := int64(1); height <= plan.Height; height++ {
+		abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: height}})
+		abci.Commit()
+	}
+
+	select {
+	case <-abci.Halted():
+	default:
+		t.Fatal("node did not halt at the upgrade height")
+	}
+
+	info := service.NewAbci(db, &types.Config{Upgrade: plan}, logger).Info(tdTypes.RequestInfo{})
+	if info.LastBlockHeight != plan.Height {
+		t.Fatalf("last block height %d, expected %d", info.LastBlockHeight, plan.Height)
+	}
+
+	// the old binary does not know the migration
+	if err := db.ApplyPendingUpgrade(); err == nil {
+		t.Fatal("pending upgrade applied without a registered migration")
+	}
+
+	service.RegisterMigration(plan.Name, func(db *badger.DB) error {
+		return db.Update(func(txn *badger.Txn) error {
+			return txn.Set(types.BalanceKey(address), big.NewInt(1).Bytes())
+		})
+	})
+
+	if err := db.ApplyPendingUpgrade(); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := db.IsUpgradeApplied(plan.Name)
+	if err != nil || !applied {
+		t.Fatalf("upgrade not applied: %v", err)
+	}
+
+	balance, err := db.GetAccountBalance(address, types.LatestHeight)
+	if err != nil || balance.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("migration not run, balance %s: %v", balance, err)
+	}
+
+	// the upgraded binary continues past the upgrade height
+	abci = service.NewAbci(db, &types.Config{Upgrade: plan}, logger)
+	abci.BeginBlock(tdTypes.RequestBeginBlock{Header: tmTypes.Header{Height: plan.Height + 1}})
+	abci.Commit()
+}
@@ -0,0 +1,285 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nbnet/side-chain/core/service"
+	"github.com/nbnet/side-chain/core/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	coreTypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcTypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const uploadAddress = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+
+// newTendermintMock answers the tendermint rpc calls of an upload and records the broadcast txs, which
+// checkTx checks if set.
+func newTendermintMock(t *testing.T, maxBlockBytes int64, checkTx func([]byte) abci.ResponseCheckTx) (*httptest.Server, *[][]byte) {
+	var txs [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcTypes.RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "consensus_params":
+			result = &coreTypes.ResultConsensusParams{
+				BlockHeight:     1,
+				ConsensusParams: tmProto.ConsensusParams{Block: tmProto.BlockParams{MaxBytes: maxBlockBytes, MaxGas: -1}},
+			}
+		case "abci_query":
+			// every dry run wants the fee of a 2 byte blob
+			result = &coreTypes.ResultABCIQuery{Response: abci.ResponseQuery{Value: []byte("60")}}
+		case "validators":
+			result = &coreTypes.ResultValidators{BlockHeight: 1, Count: 1, Total: 1}
+		case "broadcast_tx_sync":
+			var params struct {
+				Tx []byte `json:"tx"`
+			}
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				t.Error(err)
+			}
+			txs = append(txs, params.Tx)
+			result = &coreTypes.ResultBroadcastTx{Hash: make([]byte, 32)}
+			if checkTx != nil {
+				check := checkTx(params.Tx)
+				result = &coreTypes.ResultBroadcastTx{Code: check.Code, Log: check.Log, Hash: tmTypes.Tx(params.Tx).Hash()}
+			}
+		default:
+			t.Errorf("unexpected call %s", req.Method)
+		}
+
+		_ = json.NewEncoder(w).Encode(rpcTypes.NewRPCSuccessResponse(req.ID, result))
+	}))
+	t.Cleanup(server.Close)
+	return server, &txs
+}
+
+func newUploadRpc(t *testing.T, maxBlobBytes int, maxBlockBytes int64) (*service.Rpc, *[][]byte) {
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	t.Cleanup(func() { db.Close() })
+
+	tendermint, txs := newTendermintMock(t, maxBlockBytes, nil)
+	config := &types.Config{Rpc: &types.RpcConfig{TdRpc: tendermint.URL, MaxBlobBytes: maxBlobBytes}, Eth: &types.EthConfig{}}
+	return service.NewRpc(config, db, service.NewPendingTxs(), nil, logger, io.Discard), txs
+}
+
+func upload(rpc *service.Rpc, query, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/blob/upload"+query, body)
+	req.Header.Set("Content-Type", contentType)
+	rpc.Handler().ServeHTTP(w, req)
+	return w
+}
+
+// decodeBlobTx returns the decompressed data of a broadcast blob tx.
+func decodeBlobTx(t *testing.T, j []byte) (types.BlobBody, []byte) {
+	var tx struct {
+		Ty   types.TxType   `json:"type"`
+		Body types.BlobBody `json:"body"`
+	}
+	if err := json.Unmarshal(j, &tx); err != nil || tx.Ty != types.Blob {
+		t.Fatalf("not a blob tx: %v %s", err, j)
+	}
+
+	var data bytes.Buffer
+	if _, err := tx.Body.Decompress(&data); err != nil {
+		t.Fatal(err)
+	}
+	return tx.Body, data.Bytes()
+}
+
+// TestUploadBlob checks raw and multipart uploads and the early rejection of oversize blobs.
+func TestUploadBlob(t *testing.T) {
+
+	rpc, txs := newUploadRpc(t, 1<<20, 64<<10)
+
+	data := bytes.Repeat([]byte("rollup batch "), 50000)
+	if w := upload(rpc, "?address="+uploadAddress+"&namespace=rollup", "application/octet-stream", bytes.NewReader(data)); w.Code != 200 {
+		t.Fatalf("raw upload: %d %s", w.Code, w.Body.String())
+	}
+	body, decoded := decodeBlobTx(t, (*txs)[0])
+	if !bytes.Equal(decoded, data) || body.Namespace != "rollup" || body.Address != uploadAddress {
+		t.Fatal("raw upload does not round trip")
+	}
+
+	// the encoder gives the tx of the json endpoint
+	tx := types.Tx{}
+	if err := tx.GenCompressBlobTx(types.BlobBody{Data: hex.EncodeToString(data), Address: uploadAddress, Namespace: "rollup"}, types.CodecGzip); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := json.Marshal(tx); !bytes.Equal(j, (*txs)[0]) {
+		t.Fatal("streamed tx differs from the json endpoint tx")
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	_ = writer.WriteField("address", uploadAddress)
+	for _, chunk := range []string{"first chunk,", "second chunk"} {
+		part, _ := writer.CreateFormFile("data", "chunk")
+		_, _ = part.Write([]byte(chunk))
+	}
+	_ = writer.Close()
+	if w := upload(rpc, "", writer.FormDataContentType(), &form); w.Code != 200 {
+		t.Fatalf("multipart upload: %d %s", w.Code, w.Body.String())
+	}
+	if _, decoded := decodeBlobTx(t, (*txs)[1]); string(decoded) != "first chunk,second chunk" {
+		t.Fatalf("chunks not concatenated: %s", decoded)
+	}
+
+	// random data does not compress, the tx outgrows the block before the upload is read
+	random := make([]byte, 512<<10)
+	_, _ = rand.Read(random)
+	reader := bytes.NewReader(random)
+	w := upload(rpc, "?address="+uploadAddress, "application/octet-stream", io.MultiReader(reader))
+	if w.Code != 413 || !strings.Contains(w.Body.String(), types.ErrBlobTooLarge) {
+		t.Fatalf("expected 413 for an incompressible blob, got %d", w.Code)
+	}
+	if reader.Len() == 0 {
+		t.Fatal("oversize upload read to the end")
+	}
+
+	tooLarge := bytes.NewReader(make([]byte, 1<<20+1))
+	if w := upload(rpc, "?address="+uploadAddress, "application/octet-stream", tooLarge); w.Code != 413 || tooLarge.Len() != 1<<20+1 {
+		t.Fatalf("expected 413 before reading, got %d", w.Code)
+	}
+
+	if w := upload(rpc, "", "application/octet-stream", bytes.NewReader(data)); w.Code != 400 {
+		t.Fatalf("expected 400 without address, got %d", w.Code)
+	}
+	if w := upload(rpc, "?address="+uploadAddress, "text/plain", bytes.NewReader(data)); w.Code != 415 {
+		t.Fatalf("expected 415, got %d", w.Code)
+	}
+	// already compressed data is stored as is under the auto codec
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(data)
+	_ = gz.Close()
+	if w := upload(rpc, "?address="+uploadAddress+"&codec=auto", "application/octet-stream", bytes.NewReader(compressed.Bytes())); w.Code != 200 {
+		t.Fatalf("auto codec upload: %d %s", w.Code, w.Body.String())
+	}
+	if body, decoded := decodeBlobTx(t, (*txs)[2]); body.Codec != types.CodecNone || !bytes.Equal(decoded, compressed.Bytes()) {
+		t.Fatalf("compressed data stored with %s", body.Codec)
+	}
+	if w := upload(rpc, "?address="+uploadAddress+"&codec=brotli", "application/octet-stream", bytes.NewReader(data)); w.Code != 400 {
+		t.Fatalf("expected 400 for an unknown codec, got %d", w.Code)
+	}
+	if w := upload(rpc, "?address="+uploadAddress+"&wait=commit&timeout=10m", "application/octet-stream", bytes.NewReader(data)); w.Code != 400 || !strings.Contains(w.Body.String(), types.ErrInvalidQuery) {
+		t.Fatalf("expected 400 for a timeout above the maximum, got %d", w.Code)
+	}
+
+	if len(*txs) != 3 {
+		t.Fatalf("rejected uploads broadcast: %d", len(*txs))
+	}
+}
+
+// TestBlobKey checks that the node signs the blobs of the blob key account with the next nonce, without waiting
+// for the previous blob to be committed, and broadcasts the blobs of other accounts unsigned.
+func TestBlobKey(t *testing.T) {
+
+	keyFile := filepath.Join(t.TempDir(), "blob_key")
+	if err := os.WriteFile(keyFile, []byte("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	other := "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+
+	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	db := service.NewDbService(&types.DbConfig{Path: t.TempDir()}, logger)
+	defer db.Close()
+	app := service.NewAbci(db, &types.Config{}, logger)
+	for _, address := range []string{uploadAddress, other} {
+		if err := db.AddAccountBalance(common.HexToAddress(address), big.NewInt(1<<40)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tendermint, txs := newTendermintMock(t, 64<<10, func(tx []byte) abci.ResponseCheckTx {
+		check := app.CheckTx(abci.RequestCheckTx{Tx: tx})
+		if check.Code != 0 {
+			t.Errorf("check failed: %s %s", check.Log, check.Info)
+		}
+		return check
+	})
+	config := &types.Config{Rpc: &types.RpcConfig{TdRpc: tendermint.URL, BlobKey: keyFile}, Eth: &types.EthConfig{}}
+	rpc := service.NewRpc(config, db, app.Pending, nil, logger, io.Discard)
+
+	// signed returns the nonce of a broadcast blob tx, which has to be signed by its address if signed is set
+	signed := func(j []byte, signed bool) uint64 {
+		var tx struct {
+			types.Tx
+			Body types.BlobBody `json:"body"`
+		}
+		if err := json.Unmarshal(j, &tx); err != nil {
+			t.Fatal(err)
+		}
+		if !signed {
+			if tx.Signed() || tx.Body.Nonce != 0 {
+				t.Fatalf("blob of %s signed", tx.Body.Address)
+			}
+			return 0
+		}
+		if digestHash, err := tx.Body.DigestHash(); err != nil || tx.VerifySignature(common.HexToAddress(tx.Body.Address), digestHash) != nil {
+			t.Fatalf("blob not signed by %s", tx.Body.Address)
+		}
+		return tx.Body.Nonce
+	}
+
+	data := bytes.Repeat([]byte("rollup batch "), 1000)
+	if w := post(rpc, "/blob", `{"data":"0x0102","address":"`+uploadAddress+`"}`); w.Code != 200 {
+		t.Fatalf("blob: %d %s", w.Code, w.Body.String())
+	}
+	if w := upload(rpc, "?address="+uploadAddress, "application/octet-stream", bytes.NewReader(data)); w.Code != 200 {
+		t.Fatalf("raw upload: %d %s", w.Code, w.Body.String())
+	}
+	if w := upload(rpc, "?address="+other, "application/octet-stream", bytes.NewReader(data)); w.Code != 200 {
+		t.Fatalf("upload of another address: %d %s", w.Code, w.Body.String())
+	}
+	if nonces := []uint64{signed((*txs)[0], true), signed((*txs)[1], true), signed((*txs)[2], false)}; nonces[0] != 0 || nonces[1] != 1 {
+		t.Fatalf("blobs signed with nonces %v", nonces)
+	}
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: tmProto.Header{Height: 1}})
+	for _, tx := range (*txs)[:2] {
+		if deliver := app.DeliverTx(abci.RequestDeliverTx{Tx: tx}); deliver.Code != 0 {
+			t.Fatalf("deliver failed: %s", deliver.Log)
+		}
+	}
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	// the delivered blobs are counted by the state
+	if w := upload(rpc, "?address="+uploadAddress, "application/octet-stream", bytes.NewReader(data)); w.Code != 200 {
+		t.Fatalf("raw upload: %d %s", w.Code, w.Body.String())
+	}
+	if nonce := signed((*txs)[3], true); nonce != 2 {
+		t.Fatalf("blob signed with nonce %d after the commit", nonce)
+	}
+}
+
+func post(rpc *service.Rpc, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rpc.Handler().ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nbnet/side-chain/core/types"
+)
+
+// checkTransfer verifies the signature, nonce and balance of a transfer. Transfers decoded from an ethereum tx
+// are verified against the ethereum signature, native transfers against the signature of the tx.
+func (s *Abci) checkTransfer(tx *types.Tx) internalResult {
+	var body types.TransferBody
+	err := mapstructure.Decode(tx.Body, &body)
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrDecodeTransferBody, err)
+		return internalResult{
+			code: types.ErrorCode(types.ErrDecodeTransferBody),
+			log:  types.ErrDecodeTransferBody,
+			info: err.Error(),
+		}
+	}
+
+	if !common.IsHexAddress(body.From) || !common.IsHexAddress(body.To) {
+		return internalResult{
+			code: types.ErrorCode(types.ErrInvalidAddress),
+			log:  types.ErrInvalidAddress,
+		}
+	}
+	address := common.HexToAddress(body.From)
+
+	amount, ok := body.AmountInt()
+	if !ok {
+		return internalResult{
+			code: types.ErrorCode(types.ErrDecodeAmount),
+			log:  types.ErrDecodeAmount,
+		}
+	}
+
+	if len(body.EthTx) != 0 {
+		if s.eth == nil || s.eth.ChainId == 0 {
+			return internalResult{
+				code: types.ErrorCode(types.ErrEthDisabled),
+				log:  types.ErrEthDisabled,
+			}
+		}
+
+		if err := body.VerifyEthTx(s.eth.ChainId); err != nil {
+			s.log.Debug(types.ProcessTxTitle, types.ErrVerifyEthTx, err)
+			return internalResult{
+				code: types.ErrorCode(types.ErrVerifyEthTx),
+				log:  types.ErrVerifyEthTx,
+				info: err.Error(),
+			}
+		}
+	} else {
+		digestHash, err := body.DigestHash()
+		if err != nil {
+			s.log.Error(types.ProcessTxTitle, types.ErrCalculateDigestHash, err)
+			return internalResult{
+				code: types.ErrorCode(types.ErrCalculateDigestHash),
+				log:  types.ErrCalculateDigestHash,
+				info: err.Error(),
+			}
+		}
+
+		if err := tx.VerifySignature(address, digestHash); err != nil {
+			s.log.Debug(types.ProcessTxTitle, types.ErrVerifySignature, err)
+			return internalResult{
+				code: types.ErrorCode(types.ErrVerifySignature),
+				log:  types.ErrVerifySignature,
+				info: err.Error(),
+			}
+		}
+	}
+
+	checked := s.checkNonce(address, body.Nonce)
+	if checked.code != 0 {
+		return checked
+	}
+
+	balance, err := s.Db.GetAccountBalance(address, types.LatestHeight)
+	if err != nil {
+		return internalResult{
+			code: types.ErrorCode(types.ErrGetBalance),
+			log:  types.ErrGetBalance,
+			info: err.Error(),
+		}
+	}
+
+	if balance.Cmp(amount) < 0 {
+		return internalResult{
+			code: types.ErrorCode(types.ErrInsufficientBalance),
+			log:  types.ErrInsufficientBalance,
+		}
+	}
+
+	return checked
+}
+
+// deliverTransfer bumps the nonce of the sender and moves the amount, the balance is checked again
+// because earlier txs of the block may have spent it.
+func (s *Abci) deliverTransfer(tx *types.Tx) internalResult {
+	var body types.TransferBody
+	// Success by default, only successful in checkTx will reach here
+	_ = mapstructure.Decode(tx.Body, &body)
+	amount, _ := body.AmountInt()
+	from := common.HexToAddress(body.From)
+	to := common.HexToAddress(body.To)
+
+	result := internalResult{
+		address:   from,
+		recipient: to,
+		ty:        tx.Ty,
+		amount:    amount,
+	}
+
+	if len(body.EthTx) != 0 {
+		if ethTx, err := types.DecodeEthTx(body.EthTx); err == nil {
+			result.ethHash = ethTx.Hash().Hex()
+		}
+	}
+
+	if err := s.Db.UpdateAccountNonce(from); err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrUpdateNonce, err)
+		result.code = types.ErrorCode(types.ErrUpdateNonce)
+		result.log = types.ErrUpdateNonce
+		result.info = err.Error()
+		return result
+	}
+
+	balance, err := s.Db.GetAccountBalance(from, types.LatestHeight)
+	if err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrGetBalance, err)
+		result.code = types.ErrorCode(types.ErrGetBalance)
+		result.log = types.ErrGetBalance
+		result.info = err.Error()
+		return result
+	}
+
+	if balance.Cmp(amount) < 0 {
+		result.code = types.ErrorCode(types.ErrInsufficientBalance)
+		result.log = types.ErrInsufficientBalance
+		return result
+	}
+
+	if err := s.Db.SubAccountBalance(from, amount); err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrUpdateBalance, err)
+		result.code = types.ErrorCode(types.ErrUpdateBalance)
+		result.log = types.ErrUpdateBalance
+		result.info = err.Error()
+		return result
+	}
+
+	if err := s.Db.AddAccountBalance(to, amount); err != nil {
+		s.log.Error(types.ProcessTxTitle, types.ErrUpdateBalance, err)
+		result.code = types.ErrorCode(types.ErrUpdateBalance)
+		result.log = types.ErrUpdateBalance
+		result.info = err.Error()
+		return result
+	}
+
+	return result
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/nbnet/side-chain/core/types"
+	"math/big"
+)
+
+// Migration rewrites the badger state from the layout of the previous binary into the layout of this one.
+type Migration func(db *badger.DB) error
+
+// migrations holds the state migrations known to this binary, keyed by upgrade plan name.
+var migrations = map[string]Migration{}
+
+// RegisterMigration makes a migration available under the given upgrade plan name.
+// It panics if a migration has already been registered under that name.
+func RegisterMigration(name string, migration Migration) {
+	if _, ok := migrations[name]; ok {
+		panic(fmt.Sprintf("migration %s already registered", name))
+	}
+	migrations[name] = migration
+}
+
+// ApplyPendingUpgrade runs the migration of the upgrade plan the node halted on, if any.
+// It must be called before the node starts. An error is returned if this binary does not
+// know the pending upgrade, which means the old binary was restarted instead of the new one.
+func (d *DbService) ApplyPendingUpgrade() error {
+	plan, err := d.GetPendingUpgrade()
+	if err != nil {
+		return err
+	}
+
+	if plan == nil {
+		return nil
+	}
+
+	migration, ok := migrations[plan.Name]
+	if !ok {
+		d.log.Error(types.UpgradeTitle, types.ErrUnknownUpgrade, plan.Name, "height", plan.Height)
+		return fmt.Errorf("upgrade %s needed at height %d, no migration registered in this binary", plan.Name, plan.Height)
+	}
+
+	d.log.Info(types.UpgradeTitle, "applying", plan.Name, "height", plan.Height)
+	if err := migration(d.db); err != nil {
+		d.log.Error(types.UpgradeTitle, types.ErrApplyUpgrade, err)
+		return err
+	}
+
+	result := d.db.Update(func(txn *badger.Txn) error {
+		err := txn.Set(types.UpgradeKey(plan.Name), big.NewInt(plan.Height).Bytes())
+		if err != nil {
+			d.log.Error(types.UpgradeTitle, types.ErrSetUpgrade, err)
+			return err
+		}
+
+		err = txn.Delete(types.PendingUpgradeKey)
+		if err != nil {
+			d.log.Error(types.UpgradeTitle, types.ErrSetUpgrade, err)
+			return err
+		}
+		return nil
+	})
+
+	return result
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nbnet/side-chain/core/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"io"
+)
+
+// uploadBlobHandler submits a blob streamed as a raw application/octet-stream body, or as the data parts
+// of a multipart/form-data body which are concatenated in order. The data is compressed while it is
+// received, an upload is rejected as soon as its tx outgrows a block of the current consensus params. A blob of
+// the account of the blob key is signed with it.
+func (rpc *Rpc) uploadBlobHandler(c *gin.Context) {
+	maxBytes := rpc.maxBlobBytes()
+	contentType := c.ContentType()
+	if contentType != "application/octet-stream" && contentType != "multipart/form-data" {
+		rpc.uploadError(c, 415, types.ErrUnsupportedMediaType, fmt.Errorf("content type %s", contentType))
+		return
+	}
+	if contentType == "application/octet-stream" && c.Request.ContentLength > int64(maxBytes) {
+		rpc.uploadError(c, 413, types.ErrBlobTooLarge, fmt.Errorf("blob of %d bytes, at most %d", c.Request.ContentLength, maxBytes))
+		return
+	}
+
+	maxDataBytes, err := rpc.maxDataBytes(c.Request.Context())
+	if err != nil {
+		rpc.uploadError(c, 503, types.ErrConsensusParams, err)
+		return
+	}
+
+	meta := types.BlobBody{Address: c.Query("address"), Namespace: c.Query("namespace"), Codec: c.Query("codec")}
+	if !types.ValidCodec(meta.Codec) {
+		rpc.uploadError(c, 400, types.ErrUnknownCodec, fmt.Errorf("unknown codec %s", meta.Codec))
+		return
+	}
+	encoder, err := types.NewBlobTxEncoder(rpc.blobCodec(meta.Codec))
+	if err != nil {
+		rpc.uploadError(c, 500, types.ErrCompressBlobTx, err)
+		return
+	}
+
+	if contentType == "application/octet-stream" {
+		if errName := meta.ValidateMeta(); len(errName) != 0 {
+			rpc.uploadError(c, 400, errName, nil)
+			return
+		}
+		if status, errName, err := copyBlob(encoder, c.Request.Body, maxBytes, maxDataBytes); err != nil {
+			rpc.uploadError(c, status, errName, err)
+			return
+		}
+	} else {
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			rpc.uploadError(c, 400, types.ErrReadBlobBody, err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				rpc.uploadError(c, 400, types.ErrReadBlobBody, err)
+				return
+			}
+
+			switch part.FormName() {
+			case "address", "namespace":
+				value, err := io.ReadAll(io.LimitReader(part, int64(types.DefaultMaxNamespaceLength+1)))
+				if err != nil {
+					rpc.uploadError(c, 400, types.ErrReadBlobBody, err)
+					return
+				}
+				if part.FormName() == "address" {
+					meta.Address = string(value)
+				} else {
+					meta.Namespace = string(value)
+				}
+			case "data":
+				if status, errName, err := copyBlob(encoder, part, maxBytes, maxDataBytes); err != nil {
+					rpc.uploadError(c, status, errName, err)
+					return
+				}
+			default:
+				rpc.uploadError(c, 400, types.ErrReadBlobBody, fmt.Errorf("unknown part %s", part.FormName()))
+				return
+			}
+		}
+
+		if errName := meta.ValidateMeta(); len(errName) != 0 {
+			rpc.uploadError(c, 400, errName, nil)
+			return
+		}
+	}
+
+	if encoder.Size() == 0 {
+		rpc.uploadError(c, 400, types.ErrInvalidBlobData, errors.New("empty blob"))
+		return
+	}
+
+	if err := encoder.CloseBody(meta.Address, meta.Namespace); err != nil {
+		rpc.uploadError(c, 500, types.ErrCompressBlobTx, err)
+		return
+	}
+	// counted as ComputeProtoSizeForTxs does, with the field tag and the length of the tx
+	size := int64(encoder.MaxLen())
+	size += int64(1 + len(binary.AppendUvarint(nil, uint64(size))))
+	if size > maxDataBytes {
+		rpc.uploadError(c, 413, types.ErrBlobTooLarge, fmt.Errorf("tx of %d bytes, a block holds %d", size, maxDataBytes))
+		return
+	}
+
+	if rpc.blobKey.signs(meta.Address) {
+		rpc.broadcastSigned(c, types.UploadBlobHandlerTitle, func(nonce uint64, sign func([]byte) (string, error)) ([]byte, error) {
+			digestHash, err := encoder.DigestHash(nonce)
+			if err != nil {
+				return nil, err
+			}
+			signature, err := sign(digestHash)
+			if err != nil {
+				return nil, err
+			}
+			return encoder.Tx(nonce, signature), nil
+		})
+		return
+	}
+
+	rpc.broadcast(c, types.UploadBlobHandlerTitle, encoder.Tx(0, ""))
+}
+
+func (rpc *Rpc) uploadError(c *gin.Context, status int, errName string, err error) {
+	rpc.log.Error(types.UploadBlobHandlerTitle, errName, err)
+	c.JSON(status, types.NewRpcResp(types.NewRpcError(errName, err), types.NewRpcBlobData(1, nil)))
+}
+
+// copyBlob compresses the data read from r into encoder. It fails once more than maxBytes are read or the
+// encoded tx exceeds maxDataBytes, without reading the rest of the upload.
+func copyBlob(encoder *types.BlobTxEncoder, r io.Reader, maxBytes int, maxDataBytes int64) (int, string, error) {
+	buf := make([]byte, types.DefaultUploadChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if encoder.Size()+n > maxBytes {
+				return 413, types.ErrBlobTooLarge, fmt.Errorf("blob larger than %d bytes", maxBytes)
+			}
+			if _, err := encoder.Write(buf[:n]); err != nil {
+				return 500, types.ErrCompressBlobTx, err
+			}
+			if int64(encoder.Len()) > maxDataBytes {
+				return 413, types.ErrBlobTooLarge, fmt.Errorf("compressed tx larger than the %d bytes a block holds", maxDataBytes)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return 0, "", nil
+		}
+		if err != nil {
+			return 400, types.ErrReadBlobBody, err
+		}
+	}
+}
+
+// maxDataBytes returns how many tx bytes a block can hold under the current consensus params.
+func (rpc *Rpc) maxDataBytes(ctx context.Context) (int64, error) {
+	params, err := rpc.tdClient.ConsensusParams(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	perPage := 1
+	validators, err := rpc.tdClient.Validators(ctx, nil, nil, &perPage)
+	if err != nil {
+		return 0, err
+	}
+
+	maxBytes := params.ConsensusParams.Block.MaxBytes
+	if maxBytes <= 0 || maxBytes > tmTypes.MaxBlockSizeBytes {
+		maxBytes = tmTypes.MaxBlockSizeBytes
+	}
+	return tmTypes.MaxDataBytesNoEvidence(maxBytes, validators.Total), nil
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"time"
+)
+
+// BlockRecord describes an executed block. It is aggregated in EndBlock from the delivered txs and stored in
+// the app-side block index, hashes are upper case hex like tendermint prints them.
+type BlockRecord struct {
+	Height        int64       `json:"height"`
+	Hash          string      `json:"hash"`
+	Time          time.Time   `json:"time"`
+	ChainId       string      `json:"chain_id"`
+	Proposer      string      `json:"proposer"`
+	LastBlockHash string      `json:"last_block_hash"`
+	DataHash      string      `json:"data_hash"`
+	AppHash       string      `json:"app_hash"`
+	TxCount       int         `json:"tx_count"`
+	FailedTxs     int         `json:"failed_txs"`
+	Blobs         int         `json:"blobs"`
+	BlobBytes     int64       `json:"blob_bytes"`
+	Fees          string      `json:"fees"`
+	Txs           []*TxRecord `json:"txs"`
+}
+
+// Aggregate sums the txs of the block. Only successful txs store blobs and pay fees.
+func (b *BlockRecord) Aggregate() {
+	fees := big.NewInt(0)
+	b.TxCount, b.FailedTxs, b.Blobs, b.BlobBytes = len(b.Txs), 0, 0, 0
+	for _, tx := range b.Txs {
+		if tx.Code != 0 {
+			b.FailedTxs++
+			continue
+		}
+		if tx.Type == Blob {
+			b.Blobs++
+			b.BlobBytes += int64(tx.BlobSize)
+		}
+		if fee, err := hexutil.DecodeBig(tx.Fee); err == nil {
+			fees.Add(fees, fee)
+		}
+	}
+	b.Fees = hexutil.EncodeBig(fees)
+}
+
+// ChainStats are the totals of the blocks from FromHeight to Height, the first block the node aggregated and
+// the block they were taken at. Fees are burned, no account is credited with them.
+type ChainStats struct {
+	Height     int64     `json:"height"`
+	Time       time.Time `json:"time"`
+	FromHeight int64     `json:"from_height"`
+	Blocks     int64     `json:"blocks"`
+	Txs        int64     `json:"txs"`
+	FailedTxs  int64     `json:"failed_txs"`
+	Blobs      int64     `json:"blobs"`
+	BlobBytes  int64     `json:"blob_bytes"`
+	FeesBurned string    `json:"fees_burned"`
+}
+
+// Add returns the totals including block, stats is nil before the first block.
+func (s *ChainStats) Add(block *BlockRecord) *ChainStats {
+	next := &ChainStats{FromHeight: block.Height, FeesBurned: "0x0"}
+	if s != nil {
+		*next = *s
+	}
+
+	next.Height = block.Height
+	next.Time = block.Time
+	next.Blocks++
+	next.Txs += int64(block.TxCount)
+	next.FailedTxs += int64(block.FailedTxs)
+	next.Blobs += int64(block.Blobs)
+	next.BlobBytes += block.BlobBytes
+
+	burned, err := hexutil.DecodeBig(next.FeesBurned)
+	if err != nil {
+		burned = big.NewInt(0)
+	}
+	if fees, err := hexutil.DecodeBig(block.Fees); err == nil {
+		burned.Add(burned, fees)
+	}
+	next.FeesBurned = hexutil.EncodeBig(burned)
+	return next
+}
+
+// ValidatorRecord tracks a validator over the blocks the node executed: the blocks it proposed and whether it
+// signed the commits of their previous blocks. Address is the tendermint validator address.
+type ValidatorRecord struct {
+	Address            string `json:"address"`
+	Power              int64  `json:"power"`
+	Proposed           int64  `json:"proposed"`
+	Signed             int64  `json:"signed"`
+	Missed             int64  `json:"missed"`
+	LastSeenHeight     int64  `json:"last_seen_height"`
+	LastProposedHeight int64  `json:"last_proposed_height"`
+	LastSignedHeight   int64  `json:"last_signed_height"`
+}
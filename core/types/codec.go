@@ -0,0 +1,104 @@
+package types
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+const (
+	CodecNone   = "none"
+	CodecGzip   = "gzip"
+	CodecZstd   = "zstd"
+	CodecSnappy = "snappy"
+	// CodecAuto is a policy rather than a codec: data that is already compressed is stored with CodecNone,
+	// other data with CodecZstd.
+	CodecAuto = "auto"
+)
+
+// compressedMagics are the leading bytes of formats that do not compress further.
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b},                         // gzip
+	{0x28, 0xb5, 0x2f, 0xfd},             // zstd
+	{0xff, 0x06, 0x00, 0x00, 0x73, 0x4e}, // snappy framed
+	{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}, // xz
+	{0x42, 0x5a, 0x68},                   // bzip2
+	{0x04, 0x22, 0x4d, 0x18},             // lz4
+	{0x50, 0x4b, 0x03, 0x04},             // zip
+	{0x89, 0x50, 0x4e, 0x47},             // png
+	{0xff, 0xd8, 0xff},                   // jpeg
+}
+
+// codecSniffBytes is how many leading bytes ResolveCodec needs to recognize compressed data.
+const codecSniffBytes = 6
+
+// ValidCodec reports whether codec can be requested for a blob, the empty codec is the node default.
+func ValidCodec(codec string) bool {
+	switch codec {
+	case "", CodecNone, CodecGzip, CodecZstd, CodecSnappy, CodecAuto:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResolveCodec returns the codec data starting with prefix is stored with under the requested codec.
+func ResolveCodec(codec string, prefix []byte) string {
+	if codec != CodecAuto {
+		return codec
+	}
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(prefix, magic) {
+			return CodecNone
+		}
+	}
+	return CodecZstd
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewCodecWriter returns a writer compressing into w, Close flushes the compressed data but does not close w.
+func NewCodecWriter(codec string, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case CodecNone:
+		return nopWriteCloser{w}, nil
+	case CodecGzip:
+		return gzip.NewWriter(w), nil
+	case CodecZstd:
+		return zstd.NewWriter(w)
+	case CodecSnappy:
+		return snappy.NewBufferedWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %s", codec)
+	}
+}
+
+// NewCodecReader returns a reader decompressing r. Blobs stored before codecs were introduced have no codec,
+// they are gzip compressed.
+func NewCodecReader(codec string, r io.Reader) (io.ReadCloser, error) {
+	switch codec {
+	case CodecNone:
+		return io.NopCloser(r), nil
+	case "", CodecGzip:
+		return gzip.NewReader(r)
+	case CodecZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(MaxBlobDecompressedBytes)))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case CodecSnappy:
+		return io.NopCloser(snappy.NewReader(r)), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %s", codec)
+	}
+}
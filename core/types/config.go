@@ -6,27 +6,106 @@ import (
 )
 
 type Config struct {
-	Db  *DbConfig  `json:"db"`
-	Rpc *RpcConfig `json:"rpc"`
+	Db        *DbConfig        `json:"db"`
+	Rpc       *RpcConfig       `json:"rpc"`
+	Upgrade   *UpgradeConfig   `json:"upgrade"`
+	Eth       *EthConfig       `json:"eth"`
+	Auth      *AuthConfig      `json:"auth"`
+	RateLimit *RateLimitConfig `json:"rate_limit" mapstructure:"rate_limit"`
 }
 
+// DbConfig locates the state. KeepRecent is how many heights of past balances and nonces are kept for
+// queries by height, 0 keeps all of them.
 type DbConfig struct {
-	Path string `json:"path"`
+	Path       string `json:"path"`
+	KeepRecent int64  `json:"keep_recent" mapstructure:"keep_recent"`
 }
 
 type RpcConfig struct {
 	Host  string `json:"host"`
 	Port  int    `json:"port"`
 	TdRpc string `json:"td_rpc" mapstructure:"td_rpc"`
+	// MaxBlobBytes limits the size of a submitted blob before compression
+	MaxBlobBytes int `json:"max_blob_bytes" mapstructure:"max_blob_bytes"`
+	// BlobCodec compresses blobs submitted without a codec: none, gzip, zstd, snappy or auto
+	BlobCodec string `json:"blob_codec" mapstructure:"blob_codec"`
+	// TlsCert and TlsKey are PEM files, the service is served over https when set
+	TlsCert string `json:"tls_cert" mapstructure:"tls_cert"`
+	TlsKey  string `json:"tls_key" mapstructure:"tls_key"`
+	// ShutdownTimeout is how many seconds in-flight requests are waited for on shutdown
+	ShutdownTimeout int64 `json:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+	// Dashboard serves the web dashboard of the node at /dashboard
+	Dashboard bool `json:"dashboard"`
+	// BlobKey is a file holding the hex private key /blob and /blob/upload sign the blobs of its account with,
+	// blobs of other accounts are broadcast unsigned.
+	BlobKey string `json:"blob_key" mapstructure:"blob_key"`
+}
+
+// UpgradeConfig schedules a coordinated software upgrade. The running binary commits blocks up to and
+// including Height, then halts; the binary that knows the migration called Name resumes from Height+1.
+type UpgradeConfig struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height"`
+}
+
+// Scheduled reports whether an upgrade plan has been configured.
+func (u *UpgradeConfig) Scheduled() bool {
+	return u != nil && len(u.Name) != 0 && u.Height > 0
+}
+
+// EthConfig configures the ethereum compatibility layer. ChainId is the EIP-155 chain id ethereum txs
+// must be signed for, it has to be the same on every node.
+type EthConfig struct {
+	ChainId uint64 `json:"chain_id" mapstructure:"chain_id"`
+}
+
+const (
+	AuthModeApiKey = "apikey"
+	AuthModeJwt    = "jwt"
+)
+
+// AuthConfig enables authentication of the RPC service. Mode is empty to disable it, "apikey" to accept
+// the keys listed in Keys or "jwt" to accept HS256 tokens signed with JwtSecret. Credentials are sent as
+// "Authorization: Bearer ...". With PublicReads, GET requests are served without credentials.
+type AuthConfig struct {
+	Mode        string    `json:"mode"`
+	Keys        []*ApiKey `json:"keys"`
+	JwtSecret   string    `json:"jwt_secret" mapstructure:"jwt_secret"`
+	PublicReads bool      `json:"public_reads" mapstructure:"public_reads"`
+}
+
+// ApiKey is a named key, rate limits are accounted per name.
+type ApiKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// RateLimitConfig limits the requests and request body bytes per Window seconds of every authenticated
+// key and, for anonymous requests, of every client ip. Zero disables a limit. Client ips are taken from
+// X-Forwarded-For only behind TrustedProxies.
+type RateLimitConfig struct {
+	Window         int64    `json:"window"`
+	KeyRequests    int64    `json:"key_requests" mapstructure:"key_requests"`
+	KeyBytes       int64    `json:"key_bytes" mapstructure:"key_bytes"`
+	IpRequests     int64    `json:"ip_requests" mapstructure:"ip_requests"`
+	IpBytes        int64    `json:"ip_bytes" mapstructure:"ip_bytes"`
+	TrustedProxies []string `json:"trusted_proxies" mapstructure:"trusted_proxies"`
 }
 
 func DefaultConfig(idx, port, tdPort int) *Config {
 	return &Config{
-		Db: &DbConfig{Path: fmt.Sprintf("%s/.side-chain/%d/node_db", os.Getenv("HOME"), idx)},
+		Db: &DbConfig{Path: fmt.Sprintf("%s/.side-chain/%d/node_db", os.Getenv("HOME"), idx), KeepRecent: DefaultKeepRecent},
 		Rpc: &RpcConfig{
-			Host:  "0.0.0.0",
-			Port:  port,
-			TdRpc: fmt.Sprintf("http://127.0.0.0:%d", tdPort),
+			Host:            "0.0.0.0",
+			Port:            port,
+			TdRpc:           fmt.Sprintf("http://127.0.0.0:%d", tdPort),
+			MaxBlobBytes:    DefaultMaxBlobBytes,
+			BlobCodec:       DefaultBlobCodec,
+			ShutdownTimeout: DefaultShutdownTimeout,
 		},
+		Upgrade:   &UpgradeConfig{},
+		Eth:       &EthConfig{ChainId: DefaultEthChainId},
+		Auth:      &AuthConfig{},
+		RateLimit: &RateLimitConfig{Window: DefaultRateLimitWindow},
 	}
 }
@@ -1,19 +1,96 @@
 package types
 
 import (
+	"encoding/binary"
 	"github.com/ethereum/go-ethereum/common"
 	"math/big"
+	"time"
 )
 
 var (
 	BalanceKeyPrefix = []byte("balance")
 	NonceKeyPrefix   = []byte("nonce")
 
+	LastBlockHeightKey  = []byte("lastblockheight")
+	LastBlockAppHashKey = []byte("lastblockapphash")
+	PendingUpgradeKey   = []byte("pendingupgrade")
+	UpgradeKeyPrefix    = []byte("upgrade")
+	TxIndexKeyPrefix    = []byte("txindex")
+	TxHashKeyPrefix     = []byte("txhash")
+	NamespaceKeyPrefix  = []byte("nsindex")
+	NamespaceIndexedKey = []byte("nsindexed")
+
+	// balances and nonces are versioned by the height they were committed at
+	BalanceHistoryKeyPrefix = []byte("hbalance")
+	NonceHistoryKeyPrefix   = []byte("hnonce")
+	HistoryBaseKey          = []byte("historybase")
+
+	// the block index of EndBlock
+	BlockKeyPrefix     = []byte("block")
+	StatsKeyPrefix     = []byte("stats")
+	ValidatorKeyPrefix = []byte("validator")
+
+	// CheckTxQueryPath is the abci query path running CheckTx without adding the tx to the mempool
+	CheckTxQueryPath = "/check_tx"
+
 	// wei
 	DefaultPerByteFee  = new(big.Int).SetUint64(10)
 	DefaultAddress     = common.HexToAddress("0x0000000000000000000000000000000000000000")
 	DefaultHash        = common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000")
 	DefaultAddressSize = 40
+
+	DefaultEthChainId = uint64(70740)
+
+	DefaultPageLimit = 30
+	MaxPageLimit     = 100
+
+	DefaultWaitCommitTimeout  = 30 * time.Second
+	MaxWaitCommitTimeout      = 5 * time.Minute
+	DefaultWaitCommitInterval = 500 * time.Millisecond
+	// bound the ethereum JSON-RPC requests, a batch entry may read the state or broadcast a tx
+	MaxEthRequestBytes = int64(1 << 20)
+	MaxEthBatch        = 100
+	// a pending tx neither rechecked nor delivered for this long has left the mempool
+	PendingTxTTL = 10 * time.Minute
+
+	DefaultStreamBuffer       = 1000
+	DefaultStreamPingInterval = 30 * time.Second
+	MaxStreamSubscriptions    = int32(16)
+	MaxStreamReplay           = int64(10000)
+
+	// the hex encoded tx of an incompressible blob has to fit the 100MB max tx bytes of a block
+	DefaultMaxBlobBytes       = 48 << 20
+	DefaultMaxNamespaceLength = 64
+	DefaultUploadChunkSize    = 64 << 10
+	DefaultBlobCodec          = CodecGzip
+
+	// a manifest assembles at most this many blobs, 48GB of data with parts of DefaultMaxBlobBytes
+	MaxManifestParts = 1000
+
+	// every signature of a multisig account is recovered in CheckTx
+	MaxMultisigSigners = 16
+
+	// CheckTx rejects blobs expanding beyond these bounds, every node has to use the same values
+	MaxBlobExpansion         = int64(4096)
+	MaxBlobDecompressedBytes = int64(128 << 20)
+
+	// /stats samples the totals every DefaultStatsInterval blocks
+	DefaultStatsInterval = int64(100)
+
+	DefaultRateLimitWindow = int64(60) // seconds
+
+	// about a week of blocks, see DbConfig.KeepRecent
+	DefaultKeepRecent = int64(362880)
+
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultShutdownTimeout   = int64(30) // seconds
+
+	DefaultClientTimeout    = 10 * time.Second
+	DefaultClientRetries    = 3
+	DefaultClientRetryDelay = 500 * time.Millisecond
+
+	DefaultSignerDialTimeout = 3 * time.Second
+	DefaultSignerRetryWait   = time.Second
 )
 
 var (
@@ -28,28 +105,103 @@ var (
 	BalanceHandlerTitle       = "BalanceHandler"
 	NonceHandlerTitle         = "NonceHandler"
 	BlobHandlerTitle          = "BlobHandler"
+	InfoTitle                 = "Info"
+	UpgradeTitle              = "Upgrade"
+	IndexTxTitle              = "IndexTx"
+	TxsHandlerTitle           = "TxsHandler"
+	TxHandlerTitle            = "TxHandler"
+	StreamHandlerTitle        = "StreamHandler"
+	EthHandlerTitle           = "EthHandler"
+	BroadcastTxHandlerTitle   = "BroadcastTxHandler"
+	GetBlobHandlerTitle       = "GetBlobHandler"
+	UploadBlobHandlerTitle    = "UploadBlobHandler"
+	BlobsHandlerTitle         = "BlobsHandler"
+	ObjectHandlerTitle        = "ObjectHandler"
+	IndexNamespacesTitle      = "IndexNamespaces"
+	ValidateTitle             = "Validate"
+	AuthTitle                 = "Auth"
+	RateLimitTitle            = "RateLimit"
+	IndexBlockTitle           = "IndexBlock"
+	BlocksHandlerTitle        = "BlocksHandler"
+	ValidatorsHandlerTitle    = "ValidatorsHandler"
+	StatsHandlerTitle         = "StatsHandler"
+	StatusHandlerTitle        = "StatusHandler"
 )
 
 var (
-	ErrDecodeTx              = "DecodeTxError"
-	ErrEncodeTx              = "EncodeTxError"
-	ErrDecodeMintBody        = "DecodeMintBodyError"
-	ErrDecodeBlobBody        = "DecodeBlobBodyError"
-	ErrCalculateDigestHash   = "CalculateDigestHashError"
-	ErrVerifySignature       = "VerifySignatureError"
-	ErrUpdateNonce           = "UpdateNonceError"
-	ErrUpdateBalance         = "UpdateBalanceError"
-	ErrDecodeAmount          = "DecodeAmountError"
-	ErrUnknownTxBody         = "UnknownTxBody"
-	ErrGetBalance            = "GetBalanceError"
-	ErrGetNonce              = "GetNonceError"
-	ErrInsufficientBalance   = "InsufficientBalance"
-	ErrNonceNotMatch         = "NonceNotMatch"
-	ErrInvalidAddress        = "InvalidAddress"
-	ErrGenGzipCompressBlobTx = "GenGzipCompressBlobTxError"
-	ErrBroadcastTxSync       = "BroadcastTxSyncError"
-	ErrProcessCommit         = "ProcessCommitError"
-	ErrTxToBytes             = "TxToBytesErr"
+	ErrDecodeTx             = "DecodeTxError"
+	ErrEncodeTx             = "EncodeTxError"
+	ErrDecodeMintBody       = "DecodeMintBodyError"
+	ErrDecodeBlobBody       = "DecodeBlobBodyError"
+	ErrCalculateDigestHash  = "CalculateDigestHashError"
+	ErrVerifySignature      = "VerifySignatureError"
+	ErrUpdateNonce          = "UpdateNonceError"
+	ErrUpdateBalance        = "UpdateBalanceError"
+	ErrDecodeAmount         = "DecodeAmountError"
+	ErrUnknownTxBody        = "UnknownTxBody"
+	ErrGetBalance           = "GetBalanceError"
+	ErrGetNonce             = "GetNonceError"
+	ErrInsufficientBalance  = "InsufficientBalance"
+	ErrNonceNotMatch        = "NonceNotMatch"
+	ErrInvalidAddress       = "InvalidAddress"
+	ErrCompressBlobTx       = "CompressBlobTxError"
+	ErrBroadcastTxSync      = "BroadcastTxSyncError"
+	ErrProcessCommit        = "ProcessCommitError"
+	ErrTxToBytes            = "TxToBytesErr"
+	ErrGetLastBlock         = "GetLastBlockError"
+	ErrSetLastBlock         = "SetLastBlockError"
+	ErrGetUpgrade           = "GetUpgradeError"
+	ErrSetUpgrade           = "SetUpgradeError"
+	ErrUnknownUpgrade       = "UnknownUpgrade"
+	ErrApplyUpgrade         = "ApplyUpgradeError"
+	ErrIndexTx              = "IndexTxError"
+	ErrGetTxs               = "GetTxsError"
+	ErrInvalidQuery         = "InvalidQuery"
+	ErrGetTx                = "GetTxError"
+	ErrTxNotFound           = "TxNotFound"
+	ErrWaitCommitTimeout    = "WaitCommitTimeout"
+	ErrUnknownTopic         = "UnknownTopic"
+	ErrSubscribe            = "SubscribeError"
+	ErrReplay               = "ReplayError"
+	ErrStreamClosed         = "StreamClosed"
+	ErrTooManySubscriptions = "TooManySubscriptions"
+	ErrReplayRange          = "ReplayRangeTooLarge"
+	ErrEventBusUnavailable  = "EventBusUnavailable"
+	ErrDecodeTransferBody   = "DecodeTransferBodyError"
+	ErrVerifyEthTx          = "VerifyEthTxError"
+	ErrEthDisabled          = "EthDisabled"
+	ErrInvalidBlobData      = "InvalidBlobData"
+	ErrInvalidNamespace     = "InvalidNamespace"
+	ErrBlobTooLarge         = "BlobTooLarge"
+	ErrUnauthorized         = "Unauthorized"
+	ErrRateLimited          = "RateLimited"
+	ErrShuttingDown         = "ShuttingDown"
+	ErrReadBlobBody         = "ReadBlobBodyError"
+	ErrUnsupportedMediaType = "UnsupportedMediaType"
+	ErrConsensusParams      = "ConsensusParamsError"
+	ErrUnknownCodec         = "UnknownCodec"
+	ErrDecompressBlob       = "DecompressBlobError"
+	ErrBlobExpansion        = "BlobExpansion"
+	ErrGetBlobs             = "GetBlobsError"
+	ErrGetBlockHeader       = "GetBlockHeaderError"
+	ErrInvalidBlobLink      = "InvalidBlobLink"
+	ErrDryRunTx             = "DryRunTxError"
+	ErrDecodeManifestBody   = "DecodeManifestBodyError"
+	ErrInvalidManifest      = "InvalidManifest"
+	ErrManifestPartMissing  = "ManifestPartMissing"
+	ErrInvalidManifestPart  = "InvalidManifestPart"
+	ErrObjectNotFound       = "ObjectNotFound"
+	ErrReadObject           = "ReadObjectError"
+	ErrStateNotAvailable    = "StateNotAvailable"
+	ErrIndexBlock           = "IndexBlockError"
+	ErrPruneHistory         = "PruneHistoryError"
+	ErrGetBlock             = "GetBlockError"
+	ErrBlockNotFound        = "BlockNotFound"
+	ErrGetValidators        = "GetValidatorsError"
+	ErrGetStats             = "GetStatsError"
+	ErrNodeStatus           = "NodeStatusError"
+	ErrSignBlob             = "SignBlobError"
+	ErrDashboardDisabled    = "DashboardDisabled"
 )
 
 func BalanceKey(address common.Address) []byte {
@@ -59,3 +211,75 @@ func BalanceKey(address common.Address) []byte {
 func NonceKey(address common.Address) []byte {
 	return append(NonceKeyPrefix, address.Bytes()...)
 }
+
+// BalanceHistoryKey orders the balances of an address by the height they were committed at.
+func BalanceHistoryKey(address common.Address, height int64) []byte {
+	return historyKey(BalanceHistoryKeyPrefix, address, height)
+}
+
+// NonceHistoryKey orders the nonces of an address by the height they were committed at.
+func NonceHistoryKey(address common.Address, height int64) []byte {
+	return historyKey(NonceHistoryKeyPrefix, address, height)
+}
+
+func historyKey(prefix []byte, address common.Address, height int64) []byte {
+	key := append(append(append([]byte{}, prefix...), address.Bytes()...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], uint64(height))
+	return key
+}
+
+// BlockKey orders the blocks by height.
+func BlockKey(height int64) []byte {
+	return heightKey(BlockKeyPrefix, height)
+}
+
+// StatsKey orders the totals taken at every block by height.
+func StatsKey(height int64) []byte {
+	return heightKey(StatsKeyPrefix, height)
+}
+
+func heightKey(prefix []byte, height int64) []byte {
+	key := append(append([]byte{}, prefix...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], uint64(height))
+	return key
+}
+
+// ValidatorKey takes the tendermint address of a validator.
+func ValidatorKey(address []byte) []byte {
+	return append(append([]byte{}, ValidatorKeyPrefix...), address...)
+}
+
+func UpgradeKey(name string) []byte {
+	return append(UpgradeKeyPrefix, []byte(name)...)
+}
+
+// TxIndexKey orders the index entries of an address by height, then by position in the block.
+func TxIndexKey(address common.Address, height int64, index uint32) []byte {
+	key := append(TxIndexAddressKey(address), make([]byte, 12)...)
+	binary.BigEndian.PutUint64(key[len(key)-12:], uint64(height))
+	binary.BigEndian.PutUint32(key[len(key)-4:], index)
+	return key
+}
+
+func TxIndexAddressKey(address common.Address) []byte {
+	return append(TxIndexKeyPrefix, address.Bytes()...)
+}
+
+// NamespaceKey orders the blobs of a namespace by height, then by position in the block.
+func NamespaceKey(namespace string, height int64, index uint32) []byte {
+	key := append(NamespacePrefixKey(namespace), make([]byte, 12)...)
+	binary.BigEndian.PutUint64(key[len(key)-12:], uint64(height))
+	binary.BigEndian.PutUint32(key[len(key)-4:], index)
+	return key
+}
+
+// NamespacePrefixKey ends the namespace with a zero byte, which namespaces can not contain, so a namespace
+// is not a prefix of another.
+func NamespacePrefixKey(namespace string) []byte {
+	key := append([]byte{}, NamespaceKeyPrefix...)
+	return append(append(key, []byte(namespace)...), 0)
+}
+
+func TxHashKey(hash string) []byte {
+	return append(TxHashKeyPrefix, []byte(hash)...)
+}
@@ -1,14 +1,46 @@
 package types
 
 import (
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"math/big"
 )
 
+// LatestHeight reads the latest state, including the block being executed. Only CheckTx reads it, queries read
+// the state of a committed height.
+const LatestHeight = int64(0)
+
+// StateNotAvailableError is returned for a read at a height whose state is not kept, Oldest to Latest are.
+type StateNotAvailableError struct {
+	Height int64
+	Oldest int64
+	Latest int64
+}
+
+func (e *StateNotAvailableError) Error() string {
+	return fmt.Sprintf("state at height %d not available, kept from %d to %d", e.Height, e.Oldest, e.Latest)
+}
+
+// Db is the state of the chain. GetAccountBalance and GetAccountNonce read the state committed at height, or
+// the latest state at LatestHeight.
 type Db interface {
 	AddAccountBalance(address common.Address, amount *big.Int) error
 	SubAccountBalance(address common.Address, amount *big.Int) error
 	UpdateAccountNonce(address common.Address) error
-	GetAccountBalance(address common.Address) (*big.Int, error)
-	GetAccountNonce(address common.Address) (*big.Int, error)
+	GetAccountBalance(address common.Address, height int64) (*big.Int, error)
+	GetAccountNonce(address common.Address, height int64) (*big.Int, error)
+	GetLastBlock() (int64, []byte, error)
+	GetHistoryRange() (int64, int64, error)
+	SetLastBlock(height int64, appHash []byte) error
+	GetPendingUpgrade() (*UpgradeConfig, error)
+	SetPendingUpgrade(plan *UpgradeConfig) error
+	IsUpgradeApplied(name string) (bool, error)
+	IndexTx(record *TxRecord) error
+	GetTx(hash string) (*TxRecord, error)
+	GetTxsByAddress(address common.Address, ty TxType, fromHeight int64, offset, limit int) ([]*TxRecord, int, error)
+	GetBlobsByNamespace(namespace string, fromHeight, toHeight int64, offset, limit int) ([]*TxRecord, int, error)
+	IndexBlock(block *BlockRecord, stats *ChainStats, validators []*ValidatorRecord) error
+	GetBlock(height int64) (*BlockRecord, error)
+	GetStats(height int64) (*ChainStats, error)
+	GetValidators() ([]*ValidatorRecord, error)
 }
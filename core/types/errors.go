@@ -0,0 +1,108 @@
+package types
+
+// Codespace qualifies the error codes of the side chain in CheckTx and DeliverTx responses.
+const Codespace = "sidechain"
+
+// Error codes returned in CheckTx and DeliverTx and in the error of RPC responses. DeliverTx codes are part
+// of the block results hash, never renumber them.
+const (
+	CodeOK uint32 = iota
+	CodeInternal
+	CodeDecodeTx
+	CodeInvalidSignature
+	CodeInvalidNonce
+	CodeInsufficientBalance
+	CodeInvalidAddress
+	CodeInvalidAmount
+	CodeUnknownTxType
+	CodeInvalidRequest
+	CodeNotFound
+	CodeTimeout
+	CodeUnavailable
+	CodeTooLarge
+	CodeUnauthorized
+	CodeRateLimited
+)
+
+// errorCodes registers the code of every error name a client may want to branch on.
+// Names not listed are internal errors.
+var errorCodes = map[string]uint32{
+	ErrDecodeTx:             CodeDecodeTx,
+	ErrDecodeMintBody:       CodeDecodeTx,
+	ErrDecodeBlobBody:       CodeDecodeTx,
+	ErrDecodeTransferBody:   CodeDecodeTx,
+	ErrCalculateDigestHash:  CodeInvalidSignature,
+	ErrVerifySignature:      CodeInvalidSignature,
+	ErrVerifyEthTx:          CodeInvalidSignature,
+	ErrNonceNotMatch:        CodeInvalidNonce,
+	ErrInsufficientBalance:  CodeInsufficientBalance,
+	ErrInvalidAddress:       CodeInvalidAddress,
+	ErrDecodeAmount:         CodeInvalidAmount,
+	ErrUnknownTxBody:        CodeUnknownTxType,
+	ErrInvalidQuery:         CodeInvalidRequest,
+	ErrTxNotFound:           CodeNotFound,
+	ErrWaitCommitTimeout:    CodeTimeout,
+	ErrEventBusUnavailable:  CodeUnavailable,
+	ErrEthDisabled:          CodeUnavailable,
+	ErrShuttingDown:         CodeUnavailable,
+	ErrInvalidBlobData:      CodeInvalidRequest,
+	ErrInvalidNamespace:     CodeInvalidRequest,
+	ErrBlobTooLarge:         CodeTooLarge,
+	ErrReadBlobBody:         CodeInvalidRequest,
+	ErrUnsupportedMediaType: CodeInvalidRequest,
+	ErrConsensusParams:      CodeUnavailable,
+	ErrUnknownCodec:         CodeDecodeTx,
+	ErrDecompressBlob:       CodeDecodeTx,
+	ErrBlobExpansion:        CodeTooLarge,
+	ErrGetBlockHeader:       CodeUnavailable,
+	ErrInvalidBlobLink:      CodeInvalidRequest,
+	ErrDecodeManifestBody:   CodeDecodeTx,
+	ErrInvalidManifest:      CodeInvalidRequest,
+	ErrManifestPartMissing:  CodeInvalidRequest,
+	ErrInvalidManifestPart:  CodeInvalidRequest,
+	ErrObjectNotFound:       CodeNotFound,
+	ErrStateNotAvailable:    CodeNotFound,
+	ErrBlockNotFound:        CodeNotFound,
+	ErrNodeStatus:           CodeUnavailable,
+	ErrDashboardDisabled:    CodeNotFound,
+	ErrUnauthorized:         CodeUnauthorized,
+	ErrRateLimited:          CodeRateLimited,
+}
+
+// ErrorCode returns the code registered for an error name like ErrNonceNotMatch.
+func ErrorCode(name string) uint32 {
+	if code, ok := errorCodes[name]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// RpcError is the error of an RPC response. Message is the error name, like NonceNotMatch, Details the
+// underlying error if any.
+type RpcError struct {
+	Code      uint32 `json:"code"`
+	Codespace string `json:"codespace"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+}
+
+func NewRpcError(name string, err error) *RpcError {
+	rpcErr := &RpcError{
+		Code:      ErrorCode(name),
+		Codespace: Codespace,
+		Message:   name,
+	}
+	if err != nil {
+		rpcErr.Details = err.Error()
+	}
+	return rpcErr
+}
+
+// NewRpcTxError wraps the result of a tx rejected by CheckTx.
+func NewRpcTxError(code uint32, codespace, log string) *RpcError {
+	return &RpcError{
+		Code:      code,
+		Codespace: codespace,
+		Message:   log,
+	}
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// JSON-RPC 2.0 error codes used by the ethereum compatibility layer
+const (
+	EthErrParse          = -32700
+	EthErrInvalidRequest = -32600
+	EthErrMethodNotFound = -32601
+	EthErrInvalidParams  = -32602
+	EthErrInternal       = -32603
+	EthErrTxRejected     = -32000
+	EthErrNotFound       = -32001
+)
+
+type EthRequest struct {
+	Jsonrpc string            `json:"jsonrpc"`
+	Id      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type EthResponse struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *EthError       `json:"error,omitempty"`
+}
+
+type EthError struct {
+	Code    int       `json:"code"`
+	Message string    `json:"message"`
+	Data    *RpcError `json:"data,omitempty"`
+}
+
+func NewEthResult(id json.RawMessage, result interface{}) *EthResponse {
+	j, err := json.Marshal(result)
+	if err != nil {
+		return NewEthError(id, EthErrInternal, err.Error())
+	}
+
+	return &EthResponse{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Result:  j,
+	}
+}
+
+func NewEthError(id json.RawMessage, code int, message string) *EthResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return &EthResponse{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Error: &EthError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
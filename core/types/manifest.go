@@ -0,0 +1,72 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/json-iterator/go"
+	"math/big"
+	"strings"
+)
+
+// ManifestBody assembles an object from blobs delivered earlier by Address. The object is the decompressed
+// data of Parts, the tx hashes of the blobs, in order. The chain checks that every part is a successful blob of
+// Address, Size and Digest, the hex sha256 of the object, let readers verify the reassembled object.
+type ManifestBody struct {
+	Nonce     uint64   `json:"nonce" mapstructure:"nonce"`
+	Address   string   `json:"address" mapstructure:"address"`
+	Namespace string   `json:"namespace,omitempty" mapstructure:"namespace"`
+	Parts     []string `json:"parts" mapstructure:"parts"`
+	Size      int64    `json:"size" mapstructure:"size"`
+	Digest    string   `json:"digest" mapstructure:"digest"`
+}
+
+func (m *ManifestBody) DigestHash() ([]byte, error) {
+
+	jsonType := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	result, err := jsonType.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	digestHash := sha256.Sum256(result)
+	return digestHash[:], nil
+}
+
+// Validate checks the form of a manifest: a non zero address, a short printable namespace, between 1 and
+// MaxManifestParts distinct part hashes as TxHash prints them, a non negative size and a sha256 digest.
+func (m *ManifestBody) Validate() string {
+	if !common.IsHexAddress(m.Address) || common.HexToAddress(m.Address) == DefaultAddress {
+		return ErrInvalidAddress
+	}
+
+	blob := BlobBody{Address: m.Address, Namespace: m.Namespace}
+	if errName := blob.ValidateMeta(); len(errName) != 0 {
+		return errName
+	}
+
+	if len(m.Parts) == 0 || len(m.Parts) > MaxManifestParts {
+		return ErrInvalidManifest
+	}
+	parts := make(map[string]struct{}, len(m.Parts))
+	for _, part := range m.Parts {
+		if _, ok := parts[part]; ok || !ValidTxHash(part) {
+			return ErrInvalidManifest
+		}
+		parts[part] = struct{}{}
+	}
+
+	if digest, err := hex.DecodeString(m.Digest); m.Size < 0 || err != nil || len(digest) != sha256.Size ||
+		m.Digest != strings.ToLower(m.Digest) {
+		return ErrInvalidManifest
+	}
+
+	return ""
+}
+
+// Gas charges the part hashes like blob data.
+func (m *ManifestBody) Gas() *big.Int {
+	bytesLen := big.NewInt(int64(len(m.Parts) * 2 * sha256.Size))
+	return bytesLen.Mul(bytesLen, DefaultPerByteFee)
+}
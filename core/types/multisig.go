@@ -0,0 +1,121 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"sort"
+)
+
+// multisigDomain separates multisig addresses from the addresses of keys.
+var multisigDomain = []byte("side-chain/multisig")
+
+// Multisig carries the signatures of a tx sent by a multisig account. The address of the account is derived from
+// Threshold and Signers, see MultisigAddress, so the chain keeps no state for it and every tx carries both.
+type Multisig struct {
+	Threshold int `json:"threshold" mapstructure:"threshold"`
+	// Signers are the signer addresses in ascending order
+	Signers []string `json:"signers" mapstructure:"signers"`
+	// Signatures are in the order of Signers, empty for signers that did not sign
+	Signatures []string `json:"signatures" mapstructure:"signatures"`
+}
+
+// NewMultisig returns the multisig of threshold out of signers without signatures.
+func NewMultisig(threshold int, signers []common.Address) (*Multisig, error) {
+	sorted := SortAddresses(signers)
+	m := &Multisig{Threshold: threshold, Signers: make([]string, len(sorted)), Signatures: make([]string, len(sorted))}
+	for i, signer := range sorted {
+		m.Signers[i] = signer.String()
+	}
+	if _, err := m.Address(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SortAddresses returns a sorted copy of addresses.
+func SortAddresses(addresses []common.Address) []common.Address {
+	sorted := append([]common.Address{}, addresses...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].Bytes(), sorted[j].Bytes()) < 0 })
+	return sorted
+}
+
+// MultisigAddress returns the address of the account of threshold out of signers, the last 20 bytes of
+// keccak256("side-chain/multisig" || uint8(threshold) || signers in ascending order).
+func MultisigAddress(threshold int, signers []common.Address) (common.Address, error) {
+	if len(signers) == 0 || len(signers) > MaxMultisigSigners {
+		return common.Address{}, fmt.Errorf("multisig of %d signers, at most %d", len(signers), MaxMultisigSigners)
+	}
+	if threshold < 1 || threshold > len(signers) {
+		return common.Address{}, fmt.Errorf("threshold %d out of 1 to %d", threshold, len(signers))
+	}
+
+	sorted := SortAddresses(signers)
+	data := [][]byte{multisigDomain, {uint8(threshold)}}
+	for i, signer := range sorted {
+		if i > 0 && signer == sorted[i-1] {
+			return common.Address{}, fmt.Errorf("duplicate signer %s", signer)
+		}
+		data = append(data, signer.Bytes())
+	}
+	return common.BytesToAddress(crypto.Keccak256(data...)[12:]), nil
+}
+
+// Address returns the address of the multisig account. The signers have to be in ascending order, so a tx has
+// a single encoding.
+func (m *Multisig) Address() (common.Address, error) {
+	signers := make([]common.Address, len(m.Signers))
+	for i, signer := range m.Signers {
+		if !common.IsHexAddress(signer) {
+			return common.Address{}, fmt.Errorf("invalid signer %q", signer)
+		}
+		signers[i] = common.HexToAddress(signer)
+		if i > 0 && bytes.Compare(signers[i-1].Bytes(), signers[i].Bytes()) >= 0 {
+			return common.Address{}, errors.New("signers not in ascending order")
+		}
+	}
+	return MultisigAddress(m.Threshold, signers)
+}
+
+// Index returns the position of signer in Signers, -1 if it is not a signer.
+func (m *Multisig) Index(signer common.Address) int {
+	for i, s := range m.Signers {
+		if common.HexToAddress(s) == signer {
+			return i
+		}
+	}
+	return -1
+}
+
+// Verify checks that the multisig is the account address and that at least Threshold signers signed
+// digestHash. Every signature present has to be valid.
+func (m *Multisig) Verify(address common.Address, digestHash []byte) error {
+	multisigAddress, err := m.Address()
+	if err != nil {
+		return err
+	}
+	if multisigAddress != address {
+		return fmt.Errorf("multisig address %s not equal to address %s", multisigAddress, address)
+	}
+	if len(m.Signatures) != len(m.Signers) {
+		return fmt.Errorf("%d signatures for %d signers", len(m.Signatures), len(m.Signers))
+	}
+
+	signed := 0
+	for i, signature := range m.Signatures {
+		if len(signature) == 0 {
+			continue
+		}
+		if err := verifySignature(common.Hex2Bytes(signature), common.HexToAddress(m.Signers[i]), digestHash); err != nil {
+			return fmt.Errorf("signer %d: %w", i, err)
+		}
+		signed++
+	}
+
+	if signed < m.Threshold {
+		return fmt.Errorf("%d of %d signatures", signed, m.Threshold)
+	}
+	return nil
+}
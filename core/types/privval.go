@@ -0,0 +1,57 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmlog "github.com/tendermint/tendermint/libs/log"
+	tmnet "github.com/tendermint/tendermint/libs/net"
+	"github.com/tendermint/tendermint/privval"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// SignerConfig is the signer.toml of a signer dir. The signer dials NodeAddr, the priv_validator_laddr of the
+// node in config.toml, and signs the votes and proposals of ChainId with the key and state files, which are
+// relative to the signer dir.
+type SignerConfig struct {
+	ChainId            string `json:"chain_id" mapstructure:"chain_id"`
+	NodeAddr           string `json:"node_addr" mapstructure:"node_addr"`
+	PrivValidatorKey   string `json:"priv_validator_key" mapstructure:"priv_validator_key"`
+	PrivValidatorState string `json:"priv_validator_state" mapstructure:"priv_validator_state"`
+}
+
+// NewSigner returns the signer of the file validator key in dir. The last signed height, round and step are
+// kept in the state file, a vote or proposal conflicting with them is never signed. The signer redials the
+// node whenever the connection is lost.
+func NewSigner(config *SignerConfig, dir string, logger tmlog.Logger) (*privval.SignerServer, error) {
+	if len(config.ChainId) == 0 {
+		return nil, errors.New("signer without chain id")
+	}
+
+	var dialer privval.SocketDialer
+	protocol, address := tmnet.ProtocolAndAddress(config.NodeAddr)
+	switch protocol {
+	case "unix":
+		dialer = privval.DialUnixFn(address)
+	case "tcp":
+		dialer = privval.DialTCPFn(address, DefaultSignerDialTimeout, ed25519.GenPrivKey())
+	default:
+		return nil, fmt.Errorf("node address %q is neither tcp nor unix", config.NodeAddr)
+	}
+
+	// LoadFilePV exits on missing files, a missing state file would also lose the double sign protection
+	keyFile, stateFile := filepath.Join(dir, config.PrivValidatorKey), filepath.Join(dir, config.PrivValidatorState)
+	for _, file := range []string{keyFile, stateFile} {
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+	}
+	pv := privval.LoadFilePV(keyFile, stateFile)
+
+	endpoint := privval.NewSignerDialerEndpoint(logger.With("module", "privval"), dialer,
+		privval.SignerDialerEndpointConnRetries(math.MaxInt),
+		privval.SignerDialerEndpointRetryWaitInterval(DefaultSignerRetryWait))
+	return privval.NewSignerServer(endpoint, config.ChainId, pv), nil
+}
@@ -1,13 +1,16 @@
 package types
 
 import (
-	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
+	abci "github.com/tendermint/tendermint/abci/types"
 	tmTypes "github.com/tendermint/tendermint/rpc/core/types"
 	"math/big"
+	"strconv"
 )
 
-func NewRpcResp(err error, data gin.H) gin.H {
+// NewRpcResp builds the envelope of every RPC response, error is null on success.
+func NewRpcResp(err *RpcError, data gin.H) gin.H {
 	return gin.H{
 		"jsonrpc": "2.0",
 		"id":      0,
@@ -24,7 +27,7 @@ func NewRpcBalanceData(balance *big.Int, code int) gin.H {
 
 	return gin.H{
 		"code":    code,
-		"balance": fmt.Sprintf("0x%x", balance.Bytes()),
+		"balance": hexutil.EncodeBig(balance),
 	}
 }
 
@@ -58,3 +61,190 @@ func NewRpcBlobData(code int, data *tmTypes.ResultBroadcastTx) gin.H {
 
 	return result
 }
+
+// NewRpcDryRunData wraps the CheckTx result of a dry run, the fee is the gas the tx wants in wei.
+func NewRpcDryRunData(response *abci.ResponseQuery, hash string) gin.H {
+
+	result := gin.H{
+		"code":       1,
+		"log":        "",
+		"hash":       hash,
+		"gas_wanted": 0,
+		"fee":        "0x0",
+	}
+
+	if response != nil {
+		gas, _ := strconv.ParseInt(string(response.Value), 10, 64)
+		result["code"] = response.Code
+		result["log"] = response.Log
+		result["gas_wanted"] = gas
+		result["fee"] = hexutil.EncodeBig(big.NewInt(gas))
+	}
+
+	return result
+}
+
+func NewRpcTxsData(txs []*TxRecord, total, page, limit, code int) gin.H {
+
+	if txs == nil {
+		txs = make([]*TxRecord, 0)
+	}
+
+	return gin.H{
+		"code":  code,
+		"txs":   txs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}
+}
+
+func NewRpcTxData(record *TxRecord, status string, code int) gin.H {
+
+	result := gin.H{
+		"code":     code,
+		"status":   status,
+		"hash":     "",
+		"height":   0,
+		"index":    0,
+		"type":     "",
+		"gas_used": 0,
+		"fee":      "0x0",
+		"tx_code":  0,
+		"log":      "",
+	}
+
+	if record != nil {
+		result["hash"] = record.Hash
+		result["height"] = record.Height
+		result["index"] = record.Index
+		result["type"] = record.Type
+		result["gas_used"] = record.GasUsed
+		result["fee"] = record.Fee
+		result["tx_code"] = record.Code
+		result["log"] = record.Log
+	}
+
+	return result
+}
+
+func NewRpcGetBlobData(body *BlobBody, height int64, txCode uint32, code int) gin.H {
+
+	result := gin.H{
+		"code":      code,
+		"hash":      "",
+		"height":    height,
+		"tx_code":   txCode,
+		"address":   "",
+		"namespace": "",
+		"data":      "",
+		"codec":     "",
+	}
+
+	if body != nil {
+		result["address"] = body.Address
+		result["namespace"] = body.Namespace
+		result["data"] = body.Data
+		result["codec"] = body.Codec
+		if len(body.Codec) == 0 {
+			result["codec"] = CodecGzip
+		}
+	}
+
+	return result
+}
+
+func NewRpcReadyData(ready bool, code int) gin.H {
+	return gin.H{
+		"code":  code,
+		"ready": ready,
+	}
+}
+
+func NewRpcBlockData(block *BlockRecord, code int) gin.H {
+	return gin.H{
+		"code":  code,
+		"block": block,
+	}
+}
+
+func NewRpcValidatorsData(validators []*ValidatorRecord, height int64, code int) gin.H {
+
+	if validators == nil {
+		validators = make([]*ValidatorRecord, 0)
+	}
+
+	return gin.H{
+		"code":       code,
+		"height":     height,
+		"validators": validators,
+		"total":      len(validators),
+	}
+}
+
+// NewRpcStatsData wraps the totals at the last committed block and the samples of them, stats is null before
+// the first aggregated block.
+func NewRpcStatsData(stats *ChainStats, points []*ChainStats, code int) gin.H {
+
+	if points == nil {
+		points = make([]*ChainStats, 0)
+	}
+
+	return gin.H{
+		"code":   code,
+		"stats":  stats,
+		"points": points,
+	}
+}
+
+// NewRpcStatusData reports the sync status of the node, its peers and its mempool.
+func NewRpcStatusData(status *tmTypes.ResultStatus, netInfo *tmTypes.ResultNetInfo, mempool *tmTypes.ResultUnconfirmedTxs, code int) gin.H {
+
+	result := gin.H{
+		"code":                code,
+		"node_id":             "",
+		"moniker":             "",
+		"network":             "",
+		"version":             "",
+		"latest_block_height": 0,
+		"latest_block_hash":   "",
+		"latest_block_time":   "",
+		"catching_up":         false,
+		"n_peers":             0,
+		"peers":               make([]gin.H, 0),
+		"mempool_txs":         0,
+		"mempool_bytes":       0,
+	}
+
+	if status != nil {
+		result["node_id"] = status.NodeInfo.DefaultNodeID
+		result["moniker"] = status.NodeInfo.Moniker
+		result["network"] = status.NodeInfo.Network
+		result["version"] = status.NodeInfo.Version
+		result["latest_block_height"] = status.SyncInfo.LatestBlockHeight
+		result["latest_block_hash"] = status.SyncInfo.LatestBlockHash.String()
+		result["latest_block_time"] = status.SyncInfo.LatestBlockTime
+		result["catching_up"] = status.SyncInfo.CatchingUp
+	}
+
+	if netInfo != nil {
+		peers := make([]gin.H, 0, len(netInfo.Peers))
+		for _, peer := range netInfo.Peers {
+			peers = append(peers, gin.H{
+				"id":        peer.NodeInfo.DefaultNodeID,
+				"moniker":   peer.NodeInfo.Moniker,
+				"remote_ip": peer.RemoteIP,
+				"outbound":  peer.IsOutbound,
+			})
+		}
+		result["n_peers"] = netInfo.NPeers
+		result["peers"] = peers
+	}
+
+	if mempool != nil {
+		result["mempool_txs"] = mempool.Total
+		result["mempool_bytes"] = mempool.TotalBytes
+	}
+
+	return result
+}
@@ -0,0 +1,139 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"time"
+)
+
+const (
+	TopicBlocks   = "blocks"
+	TopicBlobs    = "blobs"
+	TopicBalances = "balances"
+)
+
+// StreamRequest subscribes a websocket connection to a topic. When FromHeight is set, messages from that
+// height on are replayed before live messages, so a client resumes after a disconnect by passing the height
+// of the last message it received plus one.
+type StreamRequest struct {
+	Id         string   `json:"id"`
+	Topic      string   `json:"topic"`
+	FromHeight int64    `json:"from_height"`
+	Sender     string   `json:"sender"`
+	Namespace  string   `json:"namespace"`
+	Addresses  []string `json:"addresses"`
+}
+
+// Validate checks the topic and its filters.
+func (r *StreamRequest) Validate() string {
+	if r.FromHeight < 0 {
+		return ErrInvalidQuery
+	}
+
+	switch r.Topic {
+	case TopicBlocks:
+	case TopicBlobs:
+		if len(r.Sender) != 0 && !common.IsHexAddress(r.Sender) {
+			return ErrInvalidAddress
+		}
+	case TopicBalances:
+		if len(r.Addresses) == 0 {
+			return ErrInvalidAddress
+		}
+		for _, address := range r.Addresses {
+			if !common.IsHexAddress(address) {
+				return ErrInvalidAddress
+			}
+		}
+	default:
+		return ErrUnknownTopic
+	}
+
+	return ""
+}
+
+// MatchBlob reports whether a delivered tx is a blob passing the sender and namespace filters.
+func (r *StreamRequest) MatchBlob(record *TxRecord) bool {
+	if record.Type != Blob {
+		return false
+	}
+	if len(r.Sender) != 0 && common.HexToAddress(r.Sender) != common.HexToAddress(record.Sender) {
+		return false
+	}
+	if len(r.Namespace) != 0 && r.Namespace != record.Namespace {
+		return false
+	}
+	return true
+}
+
+// BalanceChanges returns the balance changes a delivered tx caused to the watched addresses.
+func (r *StreamRequest) BalanceChanges(record *TxRecord) []*StreamBalance {
+	changes := make([]*StreamBalance, 0)
+	if record.Code != 0 {
+		return changes
+	}
+
+	for _, address := range r.Addresses {
+		address = common.HexToAddress(address).String()
+		if address == record.Sender || address == record.Recipient {
+			changes = append(changes, NewStreamBalance(record, address))
+		}
+	}
+	return changes
+}
+
+type StreamMessage struct {
+	Id     string      `json:"id"`
+	Topic  string      `json:"topic"`
+	Height int64       `json:"height"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type StreamBlock struct {
+	Hash     string    `json:"hash"`
+	Time     time.Time `json:"time"`
+	Proposer string    `json:"proposer"`
+	NumTxs   int       `json:"num_txs"`
+}
+
+// StreamBalance is a balance change caused by a delivered tx. Balance is the balance after the block,
+// it is left empty for replayed changes.
+type StreamBalance struct {
+	Address string `json:"address"`
+	TxHash  string `json:"tx_hash"`
+	Change  string `json:"change"`
+	Balance string `json:"balance,omitempty"`
+}
+
+// NewStreamBalance derives the balance change of address from a delivered tx: mints credit the amount,
+// blobs debit the fee and transfers move the amount from the sender to the recipient.
+func NewStreamBalance(record *TxRecord, address string) *StreamBalance {
+	change := new(big.Int)
+	switch record.Type {
+	case Mint:
+		change, _ = hexutil.DecodeBig(record.Amount)
+	case Blob:
+		fee, _ := hexutil.DecodeBig(record.Fee)
+		if fee != nil {
+			change.Neg(fee)
+		}
+	case Transfer:
+		amount, _ := hexutil.DecodeBig(record.Amount)
+		if amount != nil && address != record.Recipient {
+			change.Neg(amount)
+		} else if amount != nil && address != record.Sender {
+			change.Set(amount)
+		}
+	}
+	if change == nil {
+		change = new(big.Int)
+	}
+
+	return &StreamBalance{
+		Address: address,
+		TxHash:  record.Hash,
+		Change:  hexutil.EncodeBig(change),
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/privval"
 	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/types"
 )
 
 func NewTd(app abci.Application, config *cfg.Config, logger tmlog.Logger) *node.Node {
@@ -16,11 +17,14 @@ func NewTd(app abci.Application, config *cfg.Config, logger tmlog.Logger) *node.
 		panic(err)
 	}
 
-	// read private validator
-	pv := privval.LoadFilePV(
-		config.PrivValidatorKeyFile(),
-		config.PrivValidatorStateFile(),
-	)
+	// read private validator, unless the node listens for a signer holding the key, see NewSigner
+	var pv types.PrivValidator
+	if len(config.PrivValidatorListenAddr) == 0 {
+		pv = privval.LoadFilePV(
+			config.PrivValidatorKeyFile(),
+			config.PrivValidatorStateFile(),
+		)
+	}
 
 	// read node key
 	nodeKey, err := p2p.LoadNodeKey(config.NodeKeyFile())
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nbnet/side-chain/core/types"
+	"testing"
+)
+
+// TestCodec checks that blobs round trip with every codec, that the streaming encoder gives the same tx and
+// that the auto codec leaves compressed data alone.
+func TestCodec(t *testing.T) {
+
+	address := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	data := bytes.Repeat([]byte("rollup batch "), 1000)
+
+	for _, codec := range []string{types.CodecNone, types.CodecGzip, types.CodecZstd, types.CodecSnappy, types.CodecAuto} {
+		tx := types.Tx{}
+		if err := tx.GenCompressBlobTx(types.BlobBody{Data: "0x" + hex.EncodeToString(data), Address: address}, codec); err != nil {
+			t.Fatal(err)
+		}
+		body := tx.Body.(types.BlobBody)
+
+		var decoded bytes.Buffer
+		if errName, err := body.Decompress(&decoded); err != nil || !bytes.Equal(decoded.Bytes(), data) {
+			t.Fatalf("%s does not round trip: %s %v", codec, errName, err)
+		}
+
+		// the encoder is written to in small chunks, auto has to wait for enough data to pick the codec
+		encoder, err := types.NewBlobTxEncoder(codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(data); i += 3 {
+			_, _ = encoder.Write(data[i:min(i+3, len(data))])
+		}
+		j, err := encoder.Close(address, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, _ := json.Marshal(tx); !bytes.Equal(j, expected) {
+			t.Fatalf("%s encoder differs:\n%s\n%s", codec, j[:80], expected[:80])
+		}
+	}
+
+	compressed := types.Tx{}
+	_ = compressed.GenCompressBlobTx(types.BlobBody{Data: hex.EncodeToString(data), Address: address}, types.CodecGzip)
+	gzipped, _ := hex.DecodeString(compressed.Body.(types.BlobBody).Data)
+	if codec := types.ResolveCodec(types.CodecAuto, gzipped); codec != types.CodecNone {
+		t.Fatalf("compressed data resolved to %s", codec)
+	}
+	if codec := types.ResolveCodec(types.CodecAuto, data); codec != types.CodecZstd {
+		t.Fatalf("plain data resolved to %s", codec)
+	}
+}
+
+// TestSignedBlobTxEncoder checks that the encoder signs the hash of the body with its nonce and gives the tx json
+// gives for the signed body.
+func TestSignedBlobTxEncoder(t *testing.T) {
+
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	data := bytes.Repeat([]byte("rollup batch "), 1000)
+
+	for _, nonce := range []uint64{0, 7} {
+		encoder, err := types.NewBlobTxEncoder(types.CodecZstd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = encoder.Write(data)
+		if err := encoder.CloseBody(address.String(), "rollup"); err != nil {
+			t.Fatal(err)
+		}
+		digestHash, err := encoder.DigestHash(nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := crypto.Sign(digestHash, privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxLen := encoder.MaxLen()
+		j := encoder.Tx(nonce, common.Bytes2Hex(signature))
+		if len(j) > maxLen {
+			t.Fatalf("tx of %d bytes, at most %d expected", len(j), maxLen)
+		}
+
+		tx := types.Tx{}
+		if err := tx.GenCompressBlobTx(types.BlobBody{Data: hex.EncodeToString(data), Address: address.String(), Namespace: "rollup"}, types.CodecZstd); err != nil {
+			t.Fatal(err)
+		}
+		body := tx.Body.(types.BlobBody)
+		body.Nonce = nonce
+		tx.Body = body
+		tx.Signature = common.Bytes2Hex(signature)
+		if expected, _ := json.Marshal(tx); !bytes.Equal(j, expected) {
+			t.Fatalf("nonce %d: encoder differs:\n%s\n%s", nonce, j[:80], expected[:80])
+		}
+		if err := tx.VerifySignature(address, digestHash); err != nil {
+			t.Fatal(err)
+		}
+		if expected, _ := body.DigestHash(); !bytes.Equal(digestHash, expected) {
+			t.Fatalf("nonce %d: digest hash differs", nonce)
+		}
+	}
+}
+
+// TestDecompressBound checks that blobs expanding beyond the bounds are rejected.
+func TestDecompressBound(t *testing.T) {
+
+	// a few kilobytes of zstd expanding to 64MB
+	bomb := types.Tx{}
+	if err := bomb.GenCompressBlobTx(types.BlobBody{Data: hex.EncodeToString(make([]byte, 64<<20))}, types.CodecZstd); err != nil {
+		t.Fatal(err)
+	}
+	body := bomb.Body.(types.BlobBody)
+	if errName, _ := body.Decompress(&bytes.Buffer{}); errName != types.ErrBlobExpansion {
+		t.Fatalf("expected %s, got %q for %d compressed bytes", types.ErrBlobExpansion, errName, body.Size())
+	}
+
+	body.Codec = "brotli"
+	if errName, _ := body.Decompress(&bytes.Buffer{}); errName != types.ErrUnknownCodec {
+		t.Fatalf("expected %s, got %q", types.ErrUnknownCodec, errName)
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"github.com/nbnet/side-chain/core/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/privval"
+	tmProto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func prevote(height int64, block string) *tmProto.Vote {
+	return &tmProto.Vote{
+		Type:      tmProto.PrevoteType,
+		Height:    height,
+		BlockID:   tmProto.BlockID{Hash: tmhash.Sum([]byte(block)), PartSetHeader: tmProto.PartSetHeader{Total: 1, Hash: tmhash.Sum([]byte(block))}},
+		Timestamp: time.Now(),
+	}
+}
+
+// TestSigner checks that a signer dir signs for a node listening on priv_validator_laddr and never signs a vote
+// conflicting with one it signed before.
+func TestSigner(t *testing.T) {
+
+	dir := t.TempDir()
+	for _, sub := range []string{"config", "data"} {
+		_ = os.MkdirAll(filepath.Join(dir, sub), 0700)
+	}
+	keyFile, stateFile := filepath.Join(dir, "config/priv_validator_key.json"), filepath.Join(dir, "data/priv_validator_state.json")
+	pv := privval.GenFilePV(keyFile, stateFile)
+	pv.Save()
+
+	addr := privval.GetFreeLocalhostAddrPort()
+	config := &types.SignerConfig{
+		ChainId:            "side-chain-test",
+		NodeAddr:           "tcp://" + addr,
+		PrivValidatorKey:   "config/priv_validator_key.json",
+		PrivValidatorState: "data/priv_validator_state.json",
+	}
+
+	// the node side of the connection
+	listener, err := privval.NewSignerListener("tcp://"+addr, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := privval.NewSignerClient(listener, config.ChainId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	signer, err := types.NewSigner(config, dir, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Stop()
+
+	if err := client.WaitForConnection(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if pubKey, err := client.GetPubKey(); err != nil || !pubKey.Equals(pv.Key.PubKey) {
+		t.Fatalf("unexpected pubkey %v %v", pubKey, err)
+	}
+
+	vote := prevote(5, "block")
+	if err := client.SignVote(config.ChainId, vote); err != nil {
+		t.Fatal(err)
+	}
+	if !pv.Key.PubKey.VerifySignature(tmTypes.VoteSignBytes(config.ChainId, vote), vote.Signature) {
+		t.Fatal("vote not signed with the validator key")
+	}
+
+	if err := client.SignVote(config.ChainId, prevote(5, "other block")); err == nil {
+		t.Fatal("signed a conflicting vote")
+	}
+	if err := client.SignVote(config.ChainId, prevote(4, "block")); err == nil {
+		t.Fatal("signed a vote below the last signed height")
+	}
+
+	// the last signed vote survives a restart of the signer
+	if state := privval.LoadFilePV(keyFile, stateFile).LastSignState; state.Height != 5 {
+		t.Fatalf("last signed height %d not saved", state.Height)
+	}
+
+	missing := *config
+	missing.PrivValidatorState = "data/missing.json"
+	if _, err := types.NewSigner(&missing, dir, log.NewNopLogger()); err == nil {
+		t.Fatal("expected error for a missing state file")
+	}
+	missing = *config
+	missing.NodeAddr = "udp://" + addr
+	if _, err := types.NewSigner(&missing, dir, log.NewNopLogger()); err == nil {
+		t.Fatal("expected error for an udp node address")
+	}
+}
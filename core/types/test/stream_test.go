@@ -0,0 +1,45 @@
+package test
+
+import (
+	"github.com/nbnet/side-chain/core/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"testing"
+)
+
+// TestStreamRecord rebuilds a tx record from its DeliverTx events and derives the balance change streamed for it.
+func TestStreamRecord(t *testing.T) {
+	txBytes := []byte(`{"type":"blob"}`)
+	record := &types.TxRecord{
+		Hash:      types.TxHash(txBytes),
+		Height:    7,
+		Index:     1,
+		Type:      types.Blob,
+		Sender:    "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		Amount:    "0x0",
+		Fee:       "0x3c",
+		GasUsed:   60,
+		BlobSize:  2,
+		Namespace: "rollup",
+	}
+
+	result := &abci.ResponseDeliverTx{GasUsed: 60, Events: record.Events()}
+	rebuilt := types.NewTxRecord(txBytes, 7, 1, result)
+	if *rebuilt != *record {
+		t.Fatalf("rebuilt record %+v, expected %+v", rebuilt, record)
+	}
+
+	req := types.StreamRequest{Topic: types.TopicBlobs, Namespace: "rollup", Sender: "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266"}
+	if msg := req.Validate(); len(msg) != 0 || !req.MatchBlob(rebuilt) {
+		t.Fatalf("blob not matched: %s", msg)
+	}
+
+	req = types.StreamRequest{Topic: types.TopicBalances, Addresses: []string{record.Sender}}
+	changes := req.BalanceChanges(rebuilt)
+	if msg := req.Validate(); len(msg) != 0 || len(changes) != 1 {
+		t.Fatalf("balance not matched: %s", msg)
+	}
+
+	if changes[0].Change != "-0x3c" {
+		t.Fatalf("unexpected balance change %s", changes[0].Change)
+	}
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/json-iterator/go"
+	"github.com/nbnet/side-chain/core/utils"
+	"math/big"
+)
+
+// TransferBody moves Amount from From to To. It is either signed natively over its digest hash like a mint,
+// or carries the raw signed ethereum tx it was decoded from in EthTx, in which case the ethereum signature
+// is verified instead and Signature of the enclosing Tx is left empty.
+type TransferBody struct {
+	Nonce  uint64 `json:"nonce" mapstructure:"nonce"`
+	From   string `json:"from" mapstructure:"from"`
+	To     string `json:"to" mapstructure:"to"`
+	Amount string `json:"amount" mapstructure:"amount"`
+	EthTx  string `json:"eth_tx,omitempty" mapstructure:"eth_tx"`
+}
+
+func (b *TransferBody) DigestHash() ([]byte, error) {
+
+	jsonType := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	result, err := jsonType.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	digestHash := sha256.Sum256(result)
+	return digestHash[:], nil
+}
+
+// AmountInt parses the hex amount, with or without 0x prefix.
+func (b *TransferBody) AmountInt() (*big.Int, bool) {
+	amount, ok := new(big.Int).SetString(utils.RemoveHexPrefix(b.Amount), 16)
+	if !ok || amount.Sign() < 0 {
+		return nil, false
+	}
+	return amount, true
+}
+
+// VerifyEthTx checks that EthTx is a value transfer signed for chainId by From, and that it matches the body.
+func (b *TransferBody) VerifyEthTx(chainId uint64) error {
+	ethTx, err := DecodeEthTx(b.EthTx)
+	if err != nil {
+		return err
+	}
+
+	from, err := EthTxSender(ethTx, chainId)
+	if err != nil {
+		return err
+	}
+
+	amount, ok := b.AmountInt()
+	if !ok {
+		return fmt.Errorf("invalid amount %s", b.Amount)
+	}
+
+	if from != common.HexToAddress(b.From) || *ethTx.To() != common.HexToAddress(b.To) ||
+		ethTx.Nonce() != b.Nonce || ethTx.Value().Cmp(amount) != 0 {
+		return fmt.Errorf("ethereum tx %s does not match transfer body", ethTx.Hash())
+	}
+
+	return nil
+}
+
+// DecodeEthTx decodes a raw signed ethereum tx. Only legacy and dynamic fee value transfers are accepted.
+func DecodeEthTx(raw string) (*ethTypes.Transaction, error) {
+	data, err := hexutil.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	ethTx := new(ethTypes.Transaction)
+	if err := ethTx.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	if ethTx.Type() != ethTypes.LegacyTxType && ethTx.Type() != ethTypes.DynamicFeeTxType {
+		return nil, fmt.Errorf("unsupported ethereum tx type %d", ethTx.Type())
+	}
+
+	if ethTx.To() == nil || len(ethTx.Data()) != 0 {
+		return nil, fmt.Errorf("ethereum tx %s is not a value transfer", ethTx.Hash())
+	}
+
+	return ethTx, nil
+}
+
+// EthTxSender recovers the signer of an EIP-155 protected ethereum tx signed for chainId.
+func EthTxSender(ethTx *ethTypes.Transaction, chainId uint64) (common.Address, error) {
+	if !ethTx.Protected() {
+		return common.Address{}, fmt.Errorf("ethereum tx %s is not replay protected", ethTx.Hash())
+	}
+
+	signer := ethTypes.LatestSignerForChainID(new(big.Int).SetUint64(chainId))
+	return ethTypes.Sender(signer, ethTx)
+}
+
+// NewEthTransferTx maps a raw signed ethereum value transfer to a native transfer tx.
+func NewEthTransferTx(raw string, chainId uint64) (*Tx, *ethTypes.Transaction, error) {
+	ethTx, err := DecodeEthTx(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from, err := EthTxSender(ethTx, chainId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx := &Tx{
+		Ty: Transfer,
+		Body: TransferBody{
+			Nonce:  ethTx.Nonce(),
+			From:   from.String(),
+			To:     ethTx.To().String(),
+			Amount: hexutil.EncodeBig(ethTx.Value()),
+			EthTx:  raw,
+		},
+	}
+
+	return tx, ethTx, nil
+}
@@ -2,16 +2,22 @@ package types
 
 import (
 	"bytes"
-	"compress/gzip"
 	"crypto/sha256"
+	"encoding"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/json-iterator/go"
 	"github.com/nbnet/side-chain/core/utils"
+	"hash"
+	"io"
+	"math"
 	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -21,6 +27,8 @@ const (
 	UnKnown TxType = iota + 1
 	Mint
 	Blob
+	Transfer
+	Manifest
 )
 
 func (t TxType) String() string {
@@ -29,6 +37,10 @@ func (t TxType) String() string {
 		return "mint"
 	case Blob:
 		return "blob"
+	case Transfer:
+		return "transfer"
+	case Manifest:
+		return "manifest"
 	default:
 		return "unknown"
 	}
@@ -44,66 +56,265 @@ func (t *TxType) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	*t = ParseTxType(s)
+
+	return nil
+}
+
+// ParseTxType returns the tx type named by s, or UnKnown.
+func ParseTxType(s string) TxType {
 	switch strings.ToLower(s) {
 	case "mint":
-		*t = Mint
+		return Mint
 	case "blob":
-		*t = Blob
+		return Blob
+	case "transfer":
+		return Transfer
+	case "manifest":
+		return Manifest
 	default:
-		*t = UnKnown
+		return UnKnown
 	}
-
-	return nil
 }
 
 type Tx struct {
 	Ty        TxType      `json:"type" mapstructure:"type"`
 	Signature string      `json:"signature" mapstructure:"signature"`
+	Multisig  *Multisig   `json:"multisig,omitempty" mapstructure:"multisig"`
 	Body      interface{} `json:"body" mapstructure:"body"`
 }
 
+// VerifySignature checks that the signature was made by address over digestHash, or over the EIP-191 text hash
+// of digestHash as signed by remote signers such as Clef and Web3Signer, which do not sign raw hashes. A tx of a
+// multisig account carries the signatures in Multisig instead, see Multisig.Verify.
 func (t *Tx) VerifySignature(address common.Address, digestHash []byte) error {
+	if t.Multisig != nil {
+		if len(t.Signature) != 0 {
+			return errors.New("tx with a signature and a multisig")
+		}
+		return t.Multisig.Verify(address, digestHash)
+	}
+	return verifySignature(common.Hex2Bytes(t.Signature), address, digestHash)
+}
 
-	pubkey, err := crypto.SigToPub(digestHash, common.Hex2Bytes(t.Signature))
+// Signed reports whether the tx carries a signature or the signatures of a multisig account.
+func (t *Tx) Signed() bool {
+	return len(t.Signature) != 0 || t.Multisig != nil
+}
+
+func verifySignature(signature []byte, address common.Address, digestHash []byte) error {
+
+	pubkey, err := crypto.SigToPub(digestHash, signature)
 	if err != nil {
 		return err
 	}
 
 	recoverAddress := crypto.PubkeyToAddress(*pubkey)
+	if address == recoverAddress {
+		return nil
+	}
 
-	if address != recoverAddress {
-		return fmt.Errorf("recover address %s not equal to address %s", recoverAddress, address)
+	if pubkey, err := crypto.SigToPub(accounts.TextHash(digestHash), signature); err == nil && crypto.PubkeyToAddress(*pubkey) == address {
+		return nil
 	}
 
-	return nil
+	return fmt.Errorf("recover address %s not equal to address %s", recoverAddress, address)
 }
 
-func (t *Tx) GenGzipCompressBlobTx(body BlobBody) error {
-
-	var buf bytes.Buffer
-	writer := gzip.NewWriter(&buf)
+// GenCompressBlobTx compresses the hex data of body with codec, CodecAuto picks the codec from the data.
+func (t *Tx) GenCompressBlobTx(body BlobBody, codec string) error {
 
-	utils.RemoveHexPrefix(body.Data)
-	dataBytes, err := hex.DecodeString(body.Data)
+	dataBytes, err := hex.DecodeString(utils.RemoveHexPrefix(body.Data))
 	if err != nil {
 		return err
 	}
 
+	codec = ResolveCodec(codec, dataBytes)
+	var buf bytes.Buffer
+	writer, err := NewCodecWriter(codec, &buf)
+	if err != nil {
+		return err
+	}
 	if _, err := writer.Write(dataBytes); err != nil {
 		return err
 	}
-	writer.Close()
+	if err := writer.Close(); err != nil {
+		return err
+	}
 
 	compressData := hex.EncodeToString(buf.Bytes())
 	t.Ty = Blob
 	t.Body = BlobBody{
-		Data:    compressData,
-		Address: body.Address,
+		Data:      compressData,
+		Address:   body.Address,
+		Namespace: body.Namespace,
+		Codec:     codec,
+		Nonce:     body.Nonce,
+		Prev:      body.Prev,
 	}
 
 	return nil
 }
 
+// BlobTxEncoder encodes a blob tx while its data is written. The data is compressed and hex encoded on the
+// fly, so only the encoded tx is held in memory. The tx is the json GenCompressBlobTx gives, signed or not.
+// The body is encoded after room for the tx header, which is written in front of it once the signature is known.
+type BlobTxEncoder struct {
+	buf     bytes.Buffer
+	codec   string
+	writer  io.WriteCloser
+	pending []byte
+	size    int
+	// body hashes the body up to its nonce, once the body is closed
+	body hash.Hash
+}
+
+// blobTxHeaderSpace is the length of the header of a signed blob tx, the header of an unsigned one is shorter.
+var blobTxHeaderSpace = len(blobTxHeader(strings.Repeat("0", 2*crypto.SignatureLength)))
+
+func blobTxHeader(signature string) string {
+	return `{"type":"blob","signature":"` + signature + `","body":`
+}
+
+// blobBodyEnd completes the body with the nonce, omitted when zero like json does.
+func blobBodyEnd(nonce uint64) string {
+	if nonce == 0 {
+		return "}"
+	}
+	return `,"nonce":` + strconv.FormatUint(nonce, 10) + "}"
+}
+
+func NewBlobTxEncoder(codec string) (*BlobTxEncoder, error) {
+	if len(codec) == 0 || !ValidCodec(codec) {
+		return nil, fmt.Errorf("unknown codec %s", codec)
+	}
+
+	e := &BlobTxEncoder{codec: codec}
+	e.buf.Write(make([]byte, blobTxHeaderSpace))
+	e.buf.WriteString(`{"data":"`)
+	return e, nil
+}
+
+// open resolves the codec from the first bytes of the data and starts compressing.
+func (e *BlobTxEncoder) open(prefix []byte) error {
+	e.codec = ResolveCodec(e.codec, prefix)
+	writer, err := NewCodecWriter(e.codec, hex.NewEncoder(&e.buf))
+	if err != nil {
+		return err
+	}
+	e.writer = writer
+
+	pending := e.pending
+	e.pending = nil
+	_, err = e.writer.Write(pending)
+	return err
+}
+
+func (e *BlobTxEncoder) Write(p []byte) (int, error) {
+	if e.writer == nil {
+		// CodecAuto waits for enough data to recognize compressed data
+		if e.codec == CodecAuto && len(e.pending)+len(p) < codecSniffBytes {
+			e.pending = append(e.pending, p...)
+			e.size += len(p)
+			return len(p), nil
+		}
+		if err := e.open(append(e.pending, p...)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := e.writer.Write(p)
+	e.size += n
+	return n, err
+}
+
+// Size returns the number of data bytes written.
+func (e *BlobTxEncoder) Size() int {
+	return e.size
+}
+
+// Len returns the length of the signed tx encoded so far, it grows as compressed data is flushed.
+func (e *BlobTxEncoder) Len() int {
+	return e.buf.Len()
+}
+
+// MaxLen returns the length the tx can have once the closed body is completed with any nonce and signed.
+func (e *BlobTxEncoder) MaxLen() int {
+	return e.buf.Len() + len(blobBodyEnd(math.MaxUint64)+"}")
+}
+
+// Codec returns the codec the data is compressed with, it is resolved once data has been written.
+func (e *BlobTxEncoder) Codec() string {
+	return e.codec
+}
+
+// CloseBody flushes the compressed data and completes the body with the address paying its fee and the
+// namespace. The nonce is left to DigestHash and Tx.
+func (e *BlobTxEncoder) CloseBody(address, namespace string) error {
+	if e.writer == nil {
+		if err := e.open(e.pending); err != nil {
+			return err
+		}
+	}
+	if err := e.writer.Close(); err != nil {
+		return err
+	}
+
+	addressJson, err := json.Marshal(address)
+	if err != nil {
+		return err
+	}
+	e.buf.WriteString(`","address":`)
+	e.buf.Write(addressJson)
+
+	if len(namespace) != 0 {
+		namespaceJson, err := json.Marshal(namespace)
+		if err != nil {
+			return err
+		}
+		e.buf.WriteString(`,"namespace":`)
+		e.buf.Write(namespaceJson)
+	}
+	e.buf.WriteString(`,"codec":"` + e.codec + `"`)
+
+	e.body = sha256.New()
+	e.body.Write(e.buf.Bytes()[blobTxHeaderSpace:])
+	return nil
+}
+
+// DigestHash returns the hash a signature of the body with nonce signs, as BlobBody.DigestHash does, without
+// hashing the data again.
+func (e *BlobTxEncoder) DigestHash(nonce uint64) ([]byte, error) {
+	state, err := e.body.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.New()
+	if err := digest.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, err
+	}
+	digest.Write([]byte(blobBodyEnd(nonce)))
+	return digest.Sum(nil), nil
+}
+
+// Tx completes the closed body with nonce and returns the tx with signature, empty for an unsigned tx.
+// The tx shares the buffer of the encoder, which can not be used afterwards.
+func (e *BlobTxEncoder) Tx(nonce uint64, signature string) []byte {
+	e.buf.WriteString(blobBodyEnd(nonce) + "}")
+	header := blobTxHeader(signature)
+	j := e.buf.Bytes()[blobTxHeaderSpace-len(header):]
+	copy(j, header)
+	return j
+}
+
+// Close completes the unsigned tx with the address paying its fee and the namespace.
+func (e *BlobTxEncoder) Close(address, namespace string) ([]byte, error) {
+	if err := e.CloseBody(address, namespace); err != nil {
+		return nil, err
+	}
+	return e.Tx(0, ""), nil
+}
+
 func (t *Tx) ToBytes() ([]byte, error) {
 	jsonType := jsoniter.ConfigCompatibleWithStandardLibrary
 
@@ -137,8 +348,111 @@ func (m *MintBody) DigestHash() ([]byte, error) {
 }
 
 type BlobBody struct {
-	Data    string `json:"data" mapstructure:"data"`
-	Address string `json:"address" mapstructure:"address"`
+	Data      string `json:"data" mapstructure:"data"`
+	Address   string `json:"address" mapstructure:"address"`
+	Namespace string `json:"namespace,omitempty" mapstructure:"namespace"`
+	// Codec is the codec Data is compressed with, blobs without codec are gzip compressed. Submitted to
+	// the RPC service, it is the requested codec and may be CodecAuto, empty for the node policy.
+	Codec string `json:"codec,omitempty" mapstructure:"codec"`
+	// Nonce is the account nonce of a signed blob, unsigned blobs leave it zero and do not use up a nonce.
+	Nonce uint64 `json:"nonce,omitempty" mapstructure:"nonce"`
+	// Prev is the tx hash of the previous part of a blob split over several txs.
+	Prev string `json:"prev,omitempty" mapstructure:"prev"`
+}
+
+// DigestHash is the hash a signed blob is signed over, it covers the compressed data.
+func (b *BlobBody) DigestHash() ([]byte, error) {
+
+	jsonType := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	result, err := jsonType.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	digestHash := sha256.Sum256(result)
+	return digestHash[:], nil
+}
+
+// Validate checks a blob submitted to the RPC service before it is compressed: a non zero address,
+// non empty even length hex data of at most maxBytes bytes and a short printable namespace.
+func (b *BlobBody) Validate(maxBytes int) string {
+	if errName := b.ValidateMeta(); len(errName) != 0 {
+		return errName
+	}
+
+	data := utils.RemoveHexPrefix(b.Data)
+	if len(data) == 0 || len(data)%2 != 0 {
+		return ErrInvalidBlobData
+	}
+	if len(data)/2 > maxBytes {
+		return ErrBlobTooLarge
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return ErrInvalidBlobData
+		}
+	}
+
+	return ""
+}
+
+// ValidateMeta checks the address, the namespace, the requested codec and the link of a blob, see Validate.
+func (b *BlobBody) ValidateMeta() string {
+	if !common.IsHexAddress(b.Address) || common.HexToAddress(b.Address) == DefaultAddress {
+		return ErrInvalidAddress
+	}
+
+	if !ValidCodec(b.Codec) {
+		return ErrUnknownCodec
+	}
+
+	if len(b.Prev) != 0 && !ValidTxHash(b.Prev) {
+		return ErrInvalidBlobLink
+	}
+
+	if len(b.Namespace) > DefaultMaxNamespaceLength {
+		return ErrInvalidNamespace
+	}
+	for _, c := range b.Namespace {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || strings.ContainsRune("._-/", c)) {
+			return ErrInvalidNamespace
+		}
+	}
+
+	return ""
+}
+
+// Decompress writes the data of a stored blob to w. The data may expand to MaxBlobExpansion times its
+// compressed size and at most MaxBlobDecompressedBytes, which bounds the work a zip bomb can cause.
+func (b *BlobBody) Decompress(w io.Writer) (string, error) {
+	if b.Codec == CodecAuto || !ValidCodec(b.Codec) {
+		return ErrUnknownCodec, fmt.Errorf("unknown codec %s", b.Codec)
+	}
+
+	data := utils.RemoveHexPrefix(b.Data)
+	reader, err := NewCodecReader(b.Codec, hex.NewDecoder(strings.NewReader(data)))
+	if err != nil {
+		return ErrDecompressBlob, err
+	}
+	defer reader.Close()
+
+	limit := min(int64(len(data)/2)*MaxBlobExpansion, MaxBlobDecompressedBytes)
+	n, err := io.Copy(w, io.LimitReader(reader, limit+1))
+	if err != nil {
+		return ErrDecompressBlob, err
+	}
+	if n > limit {
+		return ErrBlobExpansion, fmt.Errorf("blob of %d bytes expands beyond %d bytes", len(data)/2, limit)
+	}
+
+	return "", nil
+}
+
+// Size returns the number of bytes the blob occupies on chain.
+func (b *BlobBody) Size() int {
+	return len(utils.RemoveHexPrefix(b.Data)) / 2
 }
 
 func (b *BlobBody) Gas() *big.Int {
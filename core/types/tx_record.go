@@ -0,0 +1,141 @@
+package types
+
+import (
+	"fmt"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+	"strconv"
+	"strings"
+)
+
+// TxEventType is the tendermint event type emitted for every delivered tx, queried like tx.sender='0x...'
+const TxEventType = "tx"
+
+const (
+	TxStatusPending  = "pending"
+	TxStatusIncluded = "included"
+	TxStatusFailed   = "failed"
+)
+
+// TxHash returns the tendermint hash of a raw tx, upper case hex without prefix like tendermint prints it.
+func TxHash(txBytes []byte) string {
+	return fmt.Sprintf("%X", tmTypes.Tx(txBytes).Hash())
+}
+
+// ValidTxHash reports whether hash is a tx hash as TxHash prints it.
+func ValidTxHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	for i := 0; i < len(hash); i++ {
+		if c := hash[i]; !('0' <= c && c <= '9' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeTxHash accepts a tx hash in any case, with or without 0x prefix.
+func NormalizeTxHash(hash string) string {
+	return strings.ToUpper(strings.TrimPrefix(strings.TrimPrefix(hash, "0x"), "0X"))
+}
+
+// TxRecord describes a delivered tx. It is emitted as DeliverTx events and stored in the app-side tx index.
+type TxRecord struct {
+	Hash      string `json:"hash"`
+	Height    int64  `json:"height"`
+	Index     uint32 `json:"index"`
+	Type      TxType `json:"type"`
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient,omitempty"`
+	EthHash   string `json:"eth_hash,omitempty"`
+	Amount    string `json:"amount"`
+	Fee       string `json:"fee"`
+	GasUsed   int64  `json:"gas_used"`
+	BlobSize  int    `json:"blob_size"`
+	Namespace string `json:"namespace"`
+	Code      uint32 `json:"code"`
+	Log       string `json:"log"`
+}
+
+// NewTxRecord rebuilds the record of a delivered tx from its DeliverTx result events.
+func NewTxRecord(txBytes []byte, height int64, index uint32, result *abci.ResponseDeliverTx) *TxRecord {
+	record := &TxRecord{
+		Hash:    TxHash(txBytes),
+		Height:  height,
+		Index:   index,
+		Type:    UnKnown,
+		Amount:  "0x0",
+		Fee:     "0x0",
+		GasUsed: result.GasUsed,
+		Code:    result.Code,
+		Log:     result.Log,
+	}
+
+	for _, event := range result.Events {
+		if event.Type != TxEventType {
+			continue
+		}
+		for _, attribute := range event.Attributes {
+			value := string(attribute.Value)
+			switch string(attribute.Key) {
+			case "sender":
+				record.Sender = value
+			case "recipient":
+				record.Recipient = value
+			case "eth_hash":
+				record.EthHash = value
+			case "type":
+				record.Type = ParseTxType(value)
+			case "amount":
+				record.Amount = value
+			case "fee":
+				record.Fee = value
+			case "blob_size":
+				record.BlobSize, _ = strconv.Atoi(value)
+			case "namespace":
+				record.Namespace = value
+			}
+		}
+	}
+
+	return record
+}
+
+// Status reports whether the delivered tx succeeded.
+func (r *TxRecord) Status() string {
+	if r.Code != 0 {
+		return TxStatusFailed
+	}
+	return TxStatusIncluded
+}
+
+// Events returns the typed attributes of the tx, indexed by the tendermint kv indexer so tx_search works.
+func (r *TxRecord) Events() []abci.Event {
+	attributes := []abci.EventAttribute{
+		{Key: []byte("sender"), Value: []byte(r.Sender), Index: true},
+		{Key: []byte("type"), Value: []byte(r.Type.String()), Index: true},
+		{Key: []byte("amount"), Value: []byte(r.Amount), Index: false},
+		{Key: []byte("fee"), Value: []byte(r.Fee), Index: false},
+		{Key: []byte("blob_size"), Value: []byte(fmt.Sprintf("%d", r.BlobSize)), Index: false},
+	}
+
+	if len(r.Recipient) != 0 {
+		attributes = append(attributes, abci.EventAttribute{Key: []byte("recipient"), Value: []byte(r.Recipient), Index: true})
+	}
+
+	if len(r.EthHash) != 0 {
+		attributes = append(attributes, abci.EventAttribute{Key: []byte("eth_hash"), Value: []byte(r.EthHash), Index: true})
+	}
+
+	if len(r.Namespace) != 0 {
+		attributes = append(attributes, abci.EventAttribute{Key: []byte("namespace"), Value: []byte(r.Namespace), Index: true})
+	}
+
+	return []abci.Event{
+		{
+			Type:       TxEventType,
+			Attributes: attributes,
+		},
+	}
+}
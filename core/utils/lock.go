@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/gofrs/flock"
+)
+
+// LockFile takes an exclusive lock on path, held until unlock or the exit of the process. It fails at once
+// if another process holds the lock.
+func LockFile(path string) (unlock func() error, err error) {
+	lock := flock.New(path)
+	locked, err := lock.TryLock()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, fmt.Errorf("%s is locked by another process", path)
+	}
+	return lock.Unlock, nil
+}
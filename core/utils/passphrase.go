@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadPassphrase returns the passphrase in the environment variable env if set. Otherwise it prompts for it on
+// the terminal without echo, asking twice if confirm is set, or reads a line from stdin if it is not a terminal.
+func ReadPassphrase(prompt, env string, confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv(env); ok {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt+": ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat "+strings.ToLower(prompt[:1])+prompt[1:]+": ")
+		repeated, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(repeated) != string(passphrase) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return string(passphrase), nil
+}